@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/sshhades/sshhades/internal/cli"
@@ -14,8 +13,9 @@ var (
 )
 
 func main() {
-	if err := cli.NewRootCommand(version, buildTime, gitCommit).Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	rootCmd := cli.NewRootCommand(version, buildTime, gitCommit)
+	// The command that ran carries the context its error is reported with
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		os.Exit(cli.ReportError(cmd, os.Stderr, err))
 	}
 }
\ No newline at end of file
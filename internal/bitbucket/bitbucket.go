@@ -0,0 +1,200 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+// apiURL is the Bitbucket Cloud REST API root
+const apiURL = "https://api.bitbucket.org/2.0"
+
+// defaultTimeout bounds a single API request, including reading the
+// response, when none is configured
+const defaultTimeout = 30 * time.Second
+
+// BackupDir is the repository directory backups are stored in
+const BackupDir = "ssh-keys"
+
+// Sink stores encrypted backups in a Bitbucket Cloud repository using
+// app-password authentication. It satisfies storage.Sink and storage.Source.
+type Sink struct {
+	// Context, when set, bounds every request in addition to the client
+	// timeout, e.g. to the deadline of the whole command
+	Context context.Context
+
+	baseURL string
+	config  *config.BitbucketConfig
+	client  *http.Client
+	comment string
+}
+
+// NewSink creates a Bitbucket sink from the stored configuration
+func NewSink(cfg *config.Config, comment string) (*Sink, error) {
+	if !cfg.IsBitbucketConfigured() {
+		return nil, fmt.Errorf("Bitbucket is not configured. Set bitbucket.workspace, bitbucket.repo, bitbucket.username and bitbucket.app_password with 'sshhades config set'")
+	}
+	return newSink(apiURL, cfg.Bitbucket, comment), nil
+}
+
+func newSink(baseURL string, cfg *config.BitbucketConfig, comment string) *Sink {
+	timeout := defaultTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	return &Sink{
+		baseURL: baseURL,
+		config:  cfg,
+		client:  &http.Client{Timeout: timeout},
+		comment: comment,
+	}
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string {
+	return "bitbucket"
+}
+
+// Write commits data as ssh-keys/<name> on the main branch
+func (s *Sink) Write(name string, data []byte) error {
+	if _, err := s.mainBranch(); err != nil {
+		return err
+	}
+
+	remotePath := path.Join(BackupDir, name)
+
+	message := fmt.Sprintf("Backup SSH key: %s", name)
+	if s.comment != "" {
+		message = fmt.Sprintf("Backup SSH key: %s - %s", name, s.comment)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("message", message); err != nil {
+		return err
+	}
+	part, err := form.CreateFormFile(remotePath, name)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	resp, err := s.do(http.MethodPost, s.repoURL("src"), form.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Read downloads ssh-keys/<name> from the main branch
+func (s *Sink) Read(name string) ([]byte, error) {
+	branch, err := s.mainBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(http.MethodGet, s.repoURL("src", branch, BackupDir, name), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read one byte past the backup size limit to tell oversized files apart
+	data, err := io.ReadAll(io.LimitReader(resp.Body, storage.MaxEncryptedFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if int64(len(data)) > storage.MaxEncryptedFileSize {
+		return nil, fmt.Errorf("%w to be a valid encrypted file: %s (limit %d bytes)", storage.ErrFileTooLarge, name, storage.MaxEncryptedFileSize)
+	}
+
+	return data, nil
+}
+
+// mainBranch checks that the repository exists and returns its main branch
+func (s *Sink) mainBranch() (string, error) {
+	resp, err := s.do(http.MethodGet, s.repoURL(), "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var repo struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&repo); err != nil {
+		return "", fmt.Errorf("failed to parse repository response: %w", err)
+	}
+
+	if repo.MainBranch.Name == "" {
+		return "", fmt.Errorf("repository %s/%s has no main branch yet; push an initial commit first", s.config.Workspace, s.config.Repo)
+	}
+
+	return repo.MainBranch.Name, nil
+}
+
+// repoURL builds an API URL below the configured repository
+func (s *Sink) repoURL(elems ...string) string {
+	parts := []string{"repositories", s.config.Workspace, s.config.Repo}
+	parts = append(parts, elems...)
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return s.baseURL + "/" + path.Join(parts...)
+}
+
+// do sends an authenticated request and converts error statuses into errors
+func (s *Sink) do(method, target, contentType string, body io.Reader) (*http.Response, error) {
+	ctx := s.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(s.config.Username, s.config.AppPassword)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Bitbucket request failed: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("not found on Bitbucket: %s/%s (check workspace, repo and file name)", s.config.Workspace, s.config.Repo)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, fmt.Errorf("Bitbucket rejected the credentials (status %d); check username and app password permissions", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("Bitbucket API error (status %d): %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package bitbucket
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+func newTestServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repositories/team/keys":
+			io.WriteString(w, `{"mainbranch": {"name": "main"}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/repositories/team/keys/src":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("Invalid upload form: %v", err)
+			}
+			for field, headers := range r.MultipartForm.File {
+				file, _ := headers[0].Open()
+				data, _ := io.ReadAll(file)
+				files[field] = data
+			}
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/repositories/team/keys/src/main/"):
+			data, ok := files[strings.TrimPrefix(r.URL.Path, "/repositories/team/keys/src/main/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestWriteAndRead(t *testing.T) {
+	files := make(map[string][]byte)
+	server := newTestServer(t, files)
+	defer server.Close()
+
+	sink := newSink(server.URL, &config.BitbucketConfig{
+		Workspace: "team", Repo: "keys", Username: "alice", AppPassword: "secret",
+	}, "laptop")
+
+	if err := sink.Write("id_ed25519.enc", []byte("backup")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if string(files["ssh-keys/id_ed25519.enc"]) != "backup" {
+		t.Fatalf("Upload stored %q under %v", files["ssh-keys/id_ed25519.enc"], files)
+	}
+
+	data, err := sink.Read("id_ed25519.enc")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != "backup" {
+		t.Errorf("Read returned %q, want %q", data, "backup")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := newTestServer(t, make(map[string][]byte))
+	defer server.Close()
+
+	missingRepo := newSink(server.URL, &config.BitbucketConfig{
+		Workspace: "team", Repo: "missing", Username: "alice", AppPassword: "secret",
+	}, "")
+	if err := missingRepo.Write("a.enc", []byte("x")); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+
+	badCredentials := newSink(server.URL, &config.BitbucketConfig{
+		Workspace: "team", Repo: "keys", Username: "alice", AppPassword: "wrong",
+	}, "")
+	if _, err := badCredentials.Read("a.enc"); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Expected a credentials error, got %v", err)
+	}
+}
+
+func TestReadTooLarge(t *testing.T) {
+	old := storage.MaxEncryptedFileSize
+	storage.MaxEncryptedFileSize = 4
+	defer func() { storage.MaxEncryptedFileSize = old }()
+
+	server := newTestServer(t, map[string][]byte{"ssh-keys/big.enc": []byte("too large")})
+	defer server.Close()
+
+	sink := newSink(server.URL, &config.BitbucketConfig{
+		Workspace: "team", Repo: "keys", Username: "alice", AppPassword: "secret",
+	}, "")
+	if _, err := sink.Read("big.enc"); !errors.Is(err, storage.ErrFileTooLarge) {
+		t.Errorf("Read() error = %v, want ErrFileTooLarge", err)
+	}
+}
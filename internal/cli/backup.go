@@ -1,17 +1,26 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/user"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/bitbucket"
 	"github.com/sshhades/sshhades/internal/config"
 	"github.com/sshhades/sshhades/internal/crypto"
 	"github.com/sshhades/sshhades/internal/github"
+	"github.com/sshhades/sshhades/internal/manifest"
 	"github.com/sshhades/sshhades/internal/ssh"
 	"github.com/sshhades/sshhades/internal/storage"
 	"github.com/sshhades/sshhades/pkg/format"
@@ -20,27 +29,148 @@ import (
 type backupFlags struct {
 	input        string
 	output       string
+	outputDir    string
 	comment      string
+	hint         string
 	algorithm    string
 	iterations   uint32
 	memory       uint32
 	threads      uint8
+	strength     string
+	hook         postHook
 	passphraseEnv string
-	githubRepo   string
-	githubToken  string
 	fastMode     bool
 	githubUpload bool
+	targets      []string
+	shredSource  bool
+	verifyAfter  bool
+	force        bool
+	noInputCheck bool
+	fromAgent    bool
+	directory    string
+	keepGoing    bool
+	noMetadata   bool
+	askComment   bool
+	tagHost      bool
+	stdinKeyType string
+	base64       bool
+	fastAck      bool
+	encryptMeta  bool
+	includePub   bool
+	bundle       bool
+	files        []string
+	kdfVariant   string
+	noFollow     bool
+	exclude      []string
+	expires      string
+	like         string
+	resume       bool
+	delay        time.Duration
+	outputTemplate string
+	compress     string
+	split        string
+
+	// expiresIn is the parsed --expires duration
+	expiresIn time.Duration
+
+	// splitThreshold and splitTotal are the parsed --split K-of-N
+	splitThreshold int
+	splitTotal     int
+
+	// algorithmSet and variantSet record an explicit --algorithm and
+	// --kdf-variant, which take precedence over --like
+	algorithmSet bool
+	variantSet   bool
+
+	// passphrase, when set, is used instead of reading one; genpass
+	// --use-for-backup passes its generated passphrase this way
+	passphrase []byte
+}
+
+// readBackupPassphrase returns a copy of the preset passphrase, or reads a
+// new one from --passphrase-env or the terminal
+func readBackupPassphrase(flags *backupFlags) ([]byte, error) {
+	if flags.splitTotal > 0 {
+		return crypto.GenerateDataKey()
+	}
+	if flags.passphrase != nil {
+		return append([]byte(nil), flags.passphrase...), nil
+	}
+	return readNewPassphrase(flags.passphraseEnv, "Enter passphrase for encryption: ", "Confirm passphrase: ")
+}
+
+// applyLikeParams copies the algorithm, Argon2 variant and KDF parameters
+// of the --like backup into flags that were not given explicitly
+func applyLikeParams(flags *backupFlags) error {
+	if err := storage.ValidatePath(flags.like); err != nil {
+		return fmt.Errorf("invalid --like path: %w", err)
+	}
+	if !storage.FileExists(flags.like) {
+		return newFileError(fs.ErrNotExist, flags.like, "--like file not found: %s", flags.like)
+	}
+
+	encFile, err := loadValidEncryptedFile(flags.like)
+	if err != nil {
+		return err
+	}
+	header := encFile.Header
+
+	if !flags.algorithmSet {
+		flags.algorithm = header.Algorithm
+	}
+	if !flags.variantSet {
+		flags.kdfVariant = header.KDF
+	}
+	if flags.iterations == 0 {
+		flags.iterations = header.Iterations
+	}
+	if flags.memory == 0 {
+		flags.memory = header.Memory
+	}
+	if flags.threads == 0 {
+		flags.threads = header.Threads
+	}
+	// Keep the strength hint when it still describes the parameters
+	if crypto.MatchesStrength(header.Strength, header.Iterations, header.Memory, header.Threads) {
+		flags.strength = header.Strength
+	}
+
+	fmt.Printf("Using parameters like %s: %s, %s, %d iterations, %d MB, %d threads\n",
+		flags.like, flags.algorithm, flags.kdfVariant, flags.iterations, flags.memory, flags.threads)
+	return nil
+}
+
+// parseExpiry parses --expires: a Go duration such as 720h, or a number of
+// days such as 90d
+func parseExpiry(value string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --expires value: %s (use e.g. 90d or 720h)", value)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --expires value: %s (use e.g. 90d or 720h)", value)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("--expires must be a positive duration")
+	}
+	return d, nil
 }
 
+// stdinInput is the --input value that reads the key from standard input
+const stdinInput = "-"
+
+// hintKeyTypes are the values accepted by --stdin-key-type
+var hintKeyTypes = []string{"rsa", "ecdsa", "ed25519", "dsa"}
+
 func NewBackupCmd() *cobra.Command {
-	var (
-		inputFile   string
-		outputFile  string
-		comment     string
-		algorithm   string
-		fast        bool
-		githubUpload bool
-	)
+	flags := &backupFlags{}
 
 	cmd := &cobra.Command{
 		Use:   "backup",
@@ -51,6 +181,9 @@ The encrypted file can be safely stored or shared, and can optionally be uploade
 		Example: `  # Basic backup with AES-256-GCM
   sshhades backup --input ~/.ssh/id_ed25519 --output backup.enc
 
+  # Backup into a directory as id_ed25519.enc
+  sshhades backup -i ~/.ssh/id_ed25519 --output-dir ~/backups
+
   # Fast backup with ChaCha20-Poly1305
   sshhades backup -i ~/.ssh/id_rsa -o backup.enc --algorithm chacha20 --fast
 
@@ -58,34 +191,142 @@ The encrypted file can be safely stored or shared, and can optionally be uploade
   sshhades backup -i ~/.ssh/id_ed25519 -o backup.enc --github
 
   # Interactive backup with comment
-  sshhades backup -i ~/.ssh/id_ed25519 -o backup.enc --comment "My development key"`,
+  sshhades backup -i ~/.ssh/id_ed25519 -o backup.enc --comment "My development key"
+
+  # Save locally and push to GitHub in one run
+  sshhades backup -i ~/.ssh/id_ed25519 -o backup.enc --to local --to github
+
+  # Store the backup in Bitbucket Cloud
+  sshhades backup -i ~/.ssh/id_ed25519 --to bitbucket
+
+  # Back up every private key in ~/.ssh, continuing past failures
+  sshhades backup -d ~/.ssh --output-dir ~/backups --keep-going
+
+  # Back up a directory but leave host keys alone
+  sshhades backup -d ~/.ssh --exclude '*_host_*' --output-dir ~/backups
+
+  # Re-back up a key with the same algorithm and KDF parameters as before
+  sshhades backup -i ~/.ssh/id_ed25519 -o id_ed25519-2.enc --like id_ed25519.enc
+
+  # Back up a whole key set as one file with one passphrase
+  sshhades backup --bundle ~/.ssh/id_ed25519 ~/.ssh/id_rsa --include-pub -o keys.enc
+
+  # Check which ssh-agent identities can be backed up
+  sshhades backup --from-agent`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			flags := &backupFlags{
-				input:        inputFile,
-				output:       outputFile,
-				comment:      comment,
-				algorithm:    algorithm,
-				fastMode:     fast,
-				githubUpload: githubUpload,
+			for _, arg := range args {
+				file, err := storage.ExpandPath(arg)
+				if err != nil {
+					return err
+				}
+				flags.files = append(flags.files, file)
 			}
-			return runBackup(flags)
+			flags.algorithmSet = cmd.Flags().Changed("algorithm")
+			flags.variantSet = cmd.Flags().Changed("kdf-variant")
+			return runBackup(cmd.Context(), flags)
 		},
 	}
 
-	cmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input SSH private key file (required)")
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output encrypted file (required)")
-	cmd.Flags().StringVarP(&comment, "comment", "c", "", "Comment/description for the backup")
-	cmd.Flags().StringVarP(&algorithm, "algorithm", "a", "aes", "Encryption algorithm: aes (AES-256-GCM) or chacha20 (ChaCha20-Poly1305)")
-	cmd.Flags().BoolVarP(&fast, "fast", "f", false, "Use fast mode (less secure but faster)")
-	cmd.Flags().BoolVar(&githubUpload, "github", false, "Upload encrypted backup to GitHub")
-
-	cmd.MarkFlagRequired("input")
-	cmd.MarkFlagRequired("output")
+	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "Input SSH private key file, or - for stdin (required unless --directory, --bundle or --from-agent)")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output encrypted file (defaults to <input>.enc in --output-dir)")
+	cmd.Flags().StringVar(&flags.outputDir, "output-dir", "", "Directory for the backup (defaults to default_output_dir from config, else next to the key)")
+	cmd.Flags().StringVarP(&flags.comment, "comment", "c", "", "Comment/description for the backup")
+	cmd.Flags().StringVar(&flags.hint, "hint", "", "Passphrase hint stored UNENCRYPTED in the header, readable by anyone with the file")
+	cmd.Flags().StringVarP(&flags.algorithm, "algorithm", "a", "aes", "Encryption algorithm: aes (AES-256-GCM) or chacha20 (ChaCha20-Poly1305)")
+	cmd.Flags().Uint32VarP(&flags.iterations, "iterations", "n", 0, "Argon2id iterations (overrides config and defaults)")
+	cmd.Flags().Uint32Var(&flags.memory, "memory", 0, "Argon2id memory in MB (overrides config and defaults)")
+	cmd.Flags().Uint8Var(&flags.threads, "threads", 0, "Argon2id parallelism (overrides config and defaults)")
+	cmd.Flags().StringVar(&flags.strength, "strength", "", "KDF strength level: interactive, moderate or sensitive (--iterations, --memory and --threads still win)")
+	cmd.Flags().StringVar(&flags.kdfVariant, "kdf-variant", "argon2id", "Argon2 variant: argon2id or argon2i (argon2d is not available)")
+	cmd.Flags().StringVar(&flags.like, "like", "", "Use the algorithm and KDF parameters of this existing backup (explicit flags still win)")
+	cmd.Flags().StringVar(&flags.passphraseEnv, "passphrase-env", "", "Environment variable containing passphrase")
+	cmd.Flags().BoolVarP(&flags.fastMode, "fast", "f", false, "Use fast mode (less secure but faster)")
+	cmd.Flags().BoolVar(&flags.fastAck, "i-understand-fast-is-insecure", false, "Use --fast without the confirmation prompt (required when not interactive)")
+	cmd.Flags().BoolVar(&flags.githubUpload, "github", false, "Also upload the encrypted backup to GitHub (adds github to the --to targets)")
+	cmd.Flags().StringArrayVar(&flags.targets, "to", nil, "Backup target: local, github or bitbucket (repeatable, default: local)")
+	cmd.Flags().BoolVar(&flags.shredSource, "shred-source", false, "Overwrite and delete the source key after the backup is verified")
+	cmd.Flags().BoolVar(&flags.verifyAfter, "verify-after-backup", false, "Re-read the written backup and decrypt it before reporting success")
+	cmd.Flags().BoolVar(&flags.force, "force", false, "Do not ask for confirmation before shredding the source key")
+	cmd.Flags().BoolVar(&flags.noInputCheck, "no-input-check", false, "Back up the input even if it looks like an sshhades backup")
+	addPostHookFlags(cmd, &flags.hook)
+	cmd.Flags().StringVar(&flags.expires, "expires", "", "Mark the backup as expiring after this long, e.g. 90d or 720h (restore warns, or refuses with --strict)")
+	cmd.Flags().BoolVar(&flags.askComment, "ask-comment", false, "Prompt for a comment when --comment is not given (terminal only)")
+	cmd.Flags().BoolVar(&flags.tagHost, "tag-host", false, "Record the hostname and OS user in the header and group GitHub uploads by host")
+	cmd.Flags().BoolVar(&flags.noFollow, "no-follow-symlinks", false, "Refuse to read an input key that is a symbolic link (symlinks are followed by default)")
+	cmd.Flags().BoolVar(&flags.includePub, "include-pub", false, "Bundle <input>.pub into the same encrypted file when it exists")
+	cmd.Flags().BoolVar(&flags.bundle, "bundle", false, "Encrypt the files given as arguments together into the single --output file")
+	cmd.Flags().StringVar(&flags.compress, "compress", "none", "Compress the key before encryption: none, auto (only when it makes the file smaller) or gzip (always)")
+	cmd.Flags().StringVar(&flags.split, "split", "", "Encrypt with a random key split into shares, e.g. 2-of-3: writes 3 share files, any 2 of which restore the key without a passphrase")
+	cmd.Flags().BoolVar(&flags.base64, "base64", false, "Write the backup as one armored base64 blob for pasting into text fields or secrets")
+	cmd.Flags().BoolVar(&flags.encryptMeta, "encrypt-metadata", false, "Encrypt the comment, timestamp, fingerprint and key name instead of storing them in the plaintext header")
+	cmd.Flags().BoolVar(&flags.noMetadata, "no-metadata", false, "Omit comment, timestamp, fingerprint and key name from the plaintext header")
+	cmd.Flags().StringVarP(&flags.directory, "directory", "d", "", "Back up every private key in this directory")
+	cmd.Flags().BoolVar(&flags.keepGoing, "keep-going", false, "With --directory, continue past failed keys and report them at the end")
+	cmd.Flags().StringVar(&flags.outputTemplate, "output-template", "", "Name backups from a template with {name}, {type}, {date}, {fingerprint} and {host}, e.g. {name}-{date}.enc")
+	cmd.Flags().BoolVar(&flags.resume, "resume", false, "With --directory, skip keys an interrupted earlier run already backed up")
+	cmd.Flags().DurationVar(&flags.delay, "delay", 0, "With --directory, wait this long between keys, e.g. to stay under API rate limits")
+	cmd.Flags().StringArrayVar(&flags.exclude, "exclude", nil, "With --directory, skip files whose name matches this glob (repeatable)")
+	cmd.Flags().BoolVar(&flags.fromAgent, "from-agent", false, "Back up identities held by ssh-agent ($SSH_AUTH_SOCK) instead of --input")
+	cmd.Flags().StringVar(&flags.stdinKeyType, "stdin-key-type", "", "With --input -, key type to record when it cannot be detected: rsa, ecdsa, ed25519 or dsa")
 
 	return cmd
 }
 
-func runBackup(flags *backupFlags) error {
+func runBackup(ctx context.Context, flags *backupFlags) error {
+	if flags.fromAgent {
+		if flags.input != "" {
+			return fmt.Errorf("--from-agent cannot be combined with --input")
+		}
+		return runBackupFromAgent()
+	}
+
+	if flags.noMetadata && flags.comment != "" {
+		return fmt.Errorf("--comment cannot be combined with --no-metadata")
+	}
+
+	if flags.noMetadata && flags.tagHost {
+		return fmt.Errorf("--tag-host cannot be combined with --no-metadata")
+	}
+
+	if flags.noMetadata && flags.encryptMeta {
+		return fmt.Errorf("--encrypt-metadata cannot be combined with --no-metadata")
+	}
+
+	if err := format.ValidateComment(flags.comment); err != nil {
+		return fmt.Errorf("invalid --comment: %w", err)
+	}
+	if err := format.ValidateHint(flags.hint); err != nil {
+		return fmt.Errorf("invalid --hint: %w", err)
+	}
+
+	if flags.strength != "" {
+		if flags.fastMode {
+			return fmt.Errorf("--strength cannot be combined with --fast")
+		}
+		if flags.like != "" {
+			return fmt.Errorf("--strength cannot be combined with --like")
+		}
+		if _, err := crypto.StrengthParams(flags.strength); err != nil {
+			return err
+		}
+		flags.strength = strings.ToLower(flags.strength)
+	}
+
+	if flags.like != "" {
+		if flags.fastMode {
+			return fmt.Errorf("--like cannot be combined with --fast")
+		}
+		if err := applyLikeParams(flags); err != nil {
+			return err
+		}
+	}
+
+	if flags.fastMode {
+		if err := confirmFastMode(flags); err != nil {
+			return err
+		}
+	}
+
 	// Normalize algorithm name
 	switch strings.ToLower(flags.algorithm) {
 	case "aes", "aes-gcm", "aes-256-gcm":
@@ -96,39 +337,718 @@ func runBackup(flags *backupFlags) error {
 		return fmt.Errorf("unsupported algorithm: %s (use: aes-gcm, chacha20)", flags.algorithm)
 	}
 
+	variant, err := crypto.ParseKDFVariant(flags.kdfVariant)
+	if err != nil {
+		return err
+	}
+	flags.kdfVariant = variant
+
+	if flags.expires != "" {
+		flags.expiresIn, err = parseExpiry(flags.expires)
+		if err != nil {
+			return err
+		}
+	}
+
+	flags.compress = strings.ToLower(flags.compress)
+	if !slices.Contains(compressModes, flags.compress) {
+		return fmt.Errorf("unsupported --compress: %s (use: %s)", flags.compress, strings.Join(compressModes, ", "))
+	}
+
+	if (flags.resume || flags.delay != 0) && flags.directory == "" {
+		return fmt.Errorf("--resume and --delay require --directory")
+	}
+	if flags.delay < 0 {
+		return fmt.Errorf("--delay must not be negative")
+	}
+
+	if flags.outputTemplate != "" {
+		if flags.output != "" {
+			return fmt.Errorf("--output-template cannot be combined with --output")
+		}
+		if flags.bundle || flags.input == stdinInput {
+			return fmt.Errorf("--output-template is not supported with --bundle or stdin input")
+		}
+		if err := validateOutputTemplate(flags.outputTemplate); err != nil {
+			return err
+		}
+	}
+
+	if len(flags.exclude) > 0 {
+		if flags.directory == "" {
+			return fmt.Errorf("--exclude requires --directory")
+		}
+		if err := ssh.ValidateExcludePatterns(flags.exclude); err != nil {
+			return err
+		}
+	}
+
+	// Verification re-reads the file from disk, so there must be one
+	if flags.verifyAfter && !slices.Contains(backupTargets(flags), "local") {
+		return fmt.Errorf("--verify-after-backup re-reads the local copy and requires the local target")
+	}
+
+	if flags.split != "" {
+		if err := parseSplit(flags); err != nil {
+			return err
+		}
+	}
+
+	if flags.bundle {
+		return runBackupBundle(ctx, flags)
+	}
+	if len(flags.files) > 0 {
+		return fmt.Errorf("unexpected arguments: %s (use --input, or --bundle to back up several files)", strings.Join(flags.files, " "))
+	}
+
+	if flags.directory != "" {
+		if flags.input != "" || flags.output != "" {
+			return fmt.Errorf("--directory cannot be combined with --input/--output")
+		}
+		if flags.shredSource {
+			return fmt.Errorf("--shred-source is not supported with --directory")
+		}
+		return runBackupDirectory(ctx, flags)
+	}
+
+	if flags.input == "" {
+		return fmt.Errorf("--input is required (or use --directory, --bundle or --from-agent)")
+	}
+
+	if flags.input == stdinInput {
+		return runBackupStdin(ctx, flags)
+	}
+
+	if flags.stdinKeyType != "" {
+		return fmt.Errorf("--stdin-key-type requires --input -")
+	}
+
 	// Validate input file
 	if err := storage.ValidatePath(flags.input); err != nil {
 		return fmt.Errorf("invalid input path: %w", err)
 	}
 
+	// Refuse to double-encrypt an existing backup
+	if err := checkNotBackupFile(flags, flags.input); err != nil {
+		return err
+	}
+
 	// Read SSH key
 	fmt.Printf("Reading SSH key from %s...\n", flags.input)
-	keyData, err := ssh.ReadKeyFile(flags.input)
+	keyData, err := readInputKey(flags, flags.input)
 	if err != nil {
 		return fmt.Errorf("failed to read SSH key: %w", err)
 	}
 
+	// Offer to label the backup before the passphrase prompt
+	if flags.askComment && flags.comment == "" && !flags.noMetadata && isTerminal() {
+		flags.comment = readLine("Comment for this backup (optional): ")
+		if err := format.ValidateComment(flags.comment); err != nil {
+			return fmt.Errorf("invalid comment: %w", err)
+		}
+	}
+
 	// Generate output path if not specified
 	if flags.output == "" {
-		flags.output = storage.CreateBackupPath(flags.input, "")
+		outputDir := flags.outputDir
+		if outputDir == "" {
+			outputDir, err = defaultOutputDir()
+			if err != nil {
+				return err
+			}
+		}
+		flags.output = backupOutputPath(flags, flags.input, outputDir, keyData)
+	} else if flags.outputDir != "" {
+		return fmt.Errorf("--output and --output-dir cannot be used together")
+	}
+
+	if err := storage.ValidatePath(flags.output); err != nil {
+		return fmt.Errorf("invalid output path: %w", err)
+	}
+
+	// Check if output file already exists; a split backup writes share
+	// files in its place
+	outputs := []string{flags.output}
+	if flags.splitTotal > 0 {
+		outputs = shareFilePaths(flags.output, flags.splitTotal)
+	}
+	for _, output := range outputs {
+		if storage.FileExists(output) {
+			return newFileError(fs.ErrExist, output, "output file already exists: %s", output)
+		}
+	}
+
+	var hostname, username string
+	if flags.tagHost {
+		hostname, username, err = hostTags()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Resolve backup targets before asking for the passphrase
+	sinks, err := newBackupSinks(ctx, flags, filepath.Dir(flags.output), hostname)
+	if err != nil {
+		return err
+	}
+
+	// Shredding needs a local copy that can be re-read and verified
+	if flags.shredSource && !hasSink(sinks, "local") {
+		return fmt.Errorf("--shred-source requires the local target")
+	}
+
+	extra, err := publicKeyMember(flags, flags.input, keyData)
+	if err != nil {
+		return err
+	}
+	defer clearMembers(extra)
+
+	// Read the passphrase last, so it is not held while anything else can
+	// still fail or prompt
+	passphrase, err := readBackupPassphrase(flags)
+	if err != nil {
+		return fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	defer crypto.ClearBytes(passphrase)
+
+	return backupKey(ctx, flags, backupJob{input: flags.input, output: flags.output, keyData: keyData, extra: extra}, passphrase, sinks, hostname, username)
+}
+
+// runBackupFromAgent enumerates ssh-agent identities and reports, per key,
+// whether private material could be obtained. The agent protocol offers no
+// export request, so every identity is currently skipped.
+func runBackupFromAgent() error {
+	identities, err := ssh.ListAgentIdentities()
+	if err != nil {
+		return err
+	}
+
+	if len(identities) == 0 {
+		fmt.Println("ssh-agent holds no identities.")
+		return nil
+	}
+
+	fmt.Printf("Found %d identity(ies) in ssh-agent:\n", len(identities))
+	for _, identity := range identities {
+		fmt.Printf("⚠️  %s %s (%s): agent does not export private keys, skipped\n",
+			identity.Type, identity.Fingerprint, identity.Comment)
+	}
+
+	return fmt.Errorf("no agent identity could be backed up; back up the key file it was loaded from with --input")
+}
+
+// confirmFastMode makes --fast a deliberate choice: weak KDF parameters
+// must be acknowledged by flag, or by prompt when running interactively
+func confirmFastMode(flags *backupFlags) error {
+	if flags.fastAck {
+		return nil
+	}
+
+	// With --input - stdin holds the key, so there is no one to ask
+	if !assumeYes && (flags.input == stdinInput || !isTerminal()) {
+		return fmt.Errorf("--fast uses weak KDF parameters unfit for real keys; pass --i-understand-fast-is-insecure to use it non-interactively")
+	}
+
+	params := crypto.FastKDFParams()
+	fmt.Printf("⚠️  --fast uses %d Argon2id iterations and %d MB of memory, which is far too weak for real keys.\n", params.Iterations, params.Memory)
+	if !confirm("Create a fast-mode backup anyway? (y/N): ") {
+		return fmt.Errorf("cancelled; run without --fast for a production backup")
+	}
+	return nil
+}
+
+// runBackupBundle encrypts several files into one backup. The first file is
+// the primary key: its metadata labels the backup and it restores to
+// --output, while the others restore next to it under their own names.
+func runBackupBundle(ctx context.Context, flags *backupFlags) error {
+	if flags.input != "" || flags.directory != "" {
+		return fmt.Errorf("--bundle takes its files as arguments and cannot be combined with --input or --directory")
+	}
+	if flags.shredSource {
+		return fmt.Errorf("--shred-source is not supported with --bundle")
+	}
+	if flags.stdinKeyType != "" {
+		return fmt.Errorf("--stdin-key-type requires --input -")
+	}
+	if flags.output == "" {
+		return fmt.Errorf("--bundle requires --output")
+	}
+	if flags.outputDir != "" {
+		return fmt.Errorf("--output and --output-dir cannot be used together")
+	}
+	if len(flags.files) < 2 {
+		return fmt.Errorf("--bundle needs at least two files (use --input for a single key)")
+	}
+
+	var members []format.Member
+	defer func() { clearMembers(members) }()
+
+	// Members restore by base name, so two files may not share one
+	seen := make(map[string]string)
+	add := func(path string, data []byte) error {
+		name := filepath.Base(path)
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("%s and %s would both restore as %s", other, path, name)
+		}
+		member, err := fileMember(path, data)
+		if err != nil {
+			return err
+		}
+		seen[name] = path
+		members = append(members, member)
+		return nil
+	}
+
+	for _, path := range flags.files {
+		if path == stdinInput {
+			return fmt.Errorf("--bundle cannot read from stdin")
+		}
+		if err := storage.ValidatePath(path); err != nil {
+			return fmt.Errorf("invalid input path: %w", err)
+		}
+		if err := checkNotBackupFile(flags, path); err != nil {
+			return err
+		}
+
+		fmt.Printf("Reading %s...\n", path)
+		data, err := readInputKey(flags, path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := add(path, data); err != nil {
+			crypto.ClearBytes(data)
+			return err
+		}
+	}
+
+	// Public keys and certificates listed explicitly are not picked up a
+	// second time
+	if flags.includePub {
+		for i, path := range flags.files {
+			_, pubSeen := seen[filepath.Base(path)+".pub"]
+			_, certSeen := seen[filepath.Base(ssh.CertificatePath(path))]
+			if pubSeen && certSeen {
+				continue
+			}
+			extra, err := publicKeyMember(flags, path, members[i].Data)
+			if err != nil {
+				return err
+			}
+			for _, m := range extra {
+				if _, ok := seen[m.Name]; ok {
+					continue
+				}
+				if err := add(filepath.Join(filepath.Dir(path), m.Name), m.Data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if flags.askComment && flags.comment == "" && !flags.noMetadata && isTerminal() {
+		flags.comment = readLine("Comment for this backup (optional): ")
+		if err := format.ValidateComment(flags.comment); err != nil {
+			return fmt.Errorf("invalid comment: %w", err)
+		}
 	}
 
 	if err := storage.ValidatePath(flags.output); err != nil {
 		return fmt.Errorf("invalid output path: %w", err)
 	}
+	if storage.FileExists(flags.output) {
+		return newFileError(fs.ErrExist, flags.output, "output file already exists: %s", flags.output)
+	}
+
+	var hostname, username string
+	if flags.tagHost {
+		var err error
+		hostname, username, err = hostTags()
+		if err != nil {
+			return err
+		}
+	}
+
+	sinks, err := newBackupSinks(ctx, flags, filepath.Dir(flags.output), hostname)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := readBackupPassphrase(flags)
+	if err != nil {
+		return fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	defer crypto.ClearBytes(passphrase)
 
-	// Check if output file already exists
+	job := backupJob{input: flags.files[0], output: flags.output, keyData: members[0].Data, extra: members[1:]}
+	return backupKey(ctx, flags, job, passphrase, sinks, hostname, username)
+}
+
+// runBackupStdin backs up a key piped on standard input. Stdin carries the
+// key, so the passphrase must come from --passphrase-env and the backup path
+// from --output.
+func runBackupStdin(ctx context.Context, flags *backupFlags) error {
+	if flags.output == "" {
+		return fmt.Errorf("--input - requires --output")
+	}
+	if flags.shredSource {
+		return fmt.Errorf("--shred-source cannot be used with --input -")
+	}
+	if flags.includePub {
+		return fmt.Errorf("--include-pub cannot be used with --input -")
+	}
+	if flags.passphraseEnv == "" || os.Getenv(flags.passphraseEnv) == "" {
+		return fmt.Errorf("--input - reads the key from stdin; pass the passphrase with --passphrase-env")
+	}
+	if flags.stdinKeyType != "" {
+		flags.stdinKeyType = strings.ToLower(flags.stdinKeyType)
+		if !slices.Contains(hintKeyTypes, flags.stdinKeyType) {
+			return fmt.Errorf("unsupported --stdin-key-type: %s (use: %s)", flags.stdinKeyType, strings.Join(hintKeyTypes, ", "))
+		}
+	}
+
+	if err := storage.ValidatePath(flags.output); err != nil {
+		return fmt.Errorf("invalid output path: %w", err)
+	}
 	if storage.FileExists(flags.output) {
-		return fmt.Errorf("output file already exists: %s", flags.output)
+		return newFileError(fs.ErrExist, flags.output, "output file already exists: %s", flags.output)
 	}
 
-	// Read passphrase
-	passphrase, err := readPassphrase(flags.passphraseEnv, "Enter passphrase for encryption: ")
+	fmt.Println("Reading SSH key from stdin...")
+	done := awaitInput(nil)
+	keyData, err := storage.ReadLimited(stdinReader, "stdin", storage.MaxEncryptedFileSize, "key file")
+	done()
+	if err != nil {
+		return fmt.Errorf("failed to read SSH key from stdin: %w", err)
+	}
+	defer crypto.ClearBytes(keyData)
+
+	if ssh.IsEmptyKey(keyData) {
+		return fmt.Errorf("stdin: %w", ssh.ErrEmptyKey)
+	}
+
+	// Refuse to double-encrypt an existing backup
+	if err := checkNotBackup(flags, "stdin", keyData); err != nil {
+		return err
+	}
+
+	// Unarmored key blobs fail the text checks; the type hint vouches for them
+	if !ssh.IsValidSSHKey(keyData) && flags.stdinKeyType == "" {
+		return fmt.Errorf("stdin does not appear to be a valid SSH key (set --stdin-key-type to back it up anyway)")
+	}
+
+	var hostname, username string
+	if flags.tagHost {
+		hostname, username, err = hostTags()
+		if err != nil {
+			return err
+		}
+	}
+
+	sinks, err := newBackupSinks(ctx, flags, filepath.Dir(flags.output), hostname)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := readBackupPassphrase(flags)
 	if err != nil {
 		return fmt.Errorf("failed to read passphrase: %w", err)
 	}
 	defer crypto.ClearBytes(passphrase)
 
+	return backupKey(ctx, flags, backupJob{input: stdinInput, output: flags.output, keyData: keyData}, passphrase, sinks, hostname, username)
+}
+
+// runBackupDirectory backs up every private key in a directory with one passphrase
+func runBackupDirectory(ctx context.Context, flags *backupFlags) error {
+	if err := storage.ValidatePath(flags.directory); err != nil {
+		return fmt.Errorf("invalid directory path: %w", err)
+	}
+
+	outputDir := flags.outputDir
+	if outputDir == "" {
+		var err error
+		outputDir, err = defaultOutputDir()
+		if err != nil {
+			return err
+		}
+	}
+	if outputDir == "" {
+		outputDir = flags.directory
+	}
+
+	scan, err := ssh.ScanSSHKeys(flags.directory, flags.exclude...)
+	if err != nil {
+		return fmt.Errorf("failed to search for SSH keys: %w", err)
+	}
+
+	var inputs []string
+	for _, key := range scan.Keys {
+		if key.HasPrivate {
+			inputs = append(inputs, key.Path)
+		}
+	}
+
+	if len(inputs) == 0 {
+		fmt.Printf("No private SSH keys found in %s\n", flags.directory)
+		return nil
+	}
+
+	fmt.Printf("Found %d private key(s) in %s\n", len(inputs), flags.directory)
+
+	state, err := openBackupState(outputDir, flags.directory, flags.resume)
+	if err != nil {
+		return err
+	}
+
+	pending := inputs[:0:0]
+	for _, input := range inputs {
+		if !state.isDone(input) {
+			pending = append(pending, input)
+		}
+	}
+	if skipped := len(inputs) - len(pending); skipped > 0 {
+		fmt.Printf("Resuming: skipping %d key(s) backed up by the earlier run\n", skipped)
+	}
+
+	var hostname, username string
+	if flags.tagHost {
+		hostname, username, err = hostTags()
+		if err != nil {
+			return err
+		}
+	}
+
+	sinks, err := newBackupSinks(ctx, flags, outputDir, hostname)
+	if err != nil {
+		return err
+	}
+
+	// Read passphrase once for the whole batch
+	passphrase, err := readBackupPassphrase(flags)
+	if err != nil {
+		return fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	defer crypto.ClearBytes(passphrase)
+
+	// planned maps each templated output name to the key it was made for,
+	// so two keys rendering to the same name are caught
+	planned := make(map[string]string)
+
+	// stopped ends the run early, pointing at --resume when there is
+	// progress to resume from
+	stopped := func(err error) error {
+		if len(state.Completed) > 0 {
+			fmt.Printf("Progress saved; rerun with --resume to continue after %d completed key(s)\n", len(state.Completed))
+		}
+		return err
+	}
+
+	run := &batchRun{keepGoing: flags.keepGoing}
+	for i, input := range pending {
+		if i > 0 && flags.delay > 0 {
+			if err := sleepDeadline(ctx, flags.delay); err != nil {
+				return stopped(err)
+			}
+		}
+		if err := checkDeadline(ctx); err != nil {
+			return stopped(err)
+		}
+
+		fmt.Println()
+		if err := backupDirectoryEntry(ctx, flags, input, outputDir, planned, passphrase, sinks, hostname, username); err != nil {
+			if errors.Is(err, errDeadlineExceeded) {
+				return stopped(err)
+			}
+			if err := run.fail(input, err); err != nil {
+				return stopped(err)
+			}
+			continue
+		}
+		if err := state.complete(input); err != nil {
+			return err
+		}
+	}
+
+	if err := run.finish(len(pending)); err != nil {
+		if len(state.Completed) > 0 {
+			fmt.Println("Progress saved; fix the failures and rerun with --resume to retry only them")
+		}
+		return err
+	}
+
+	if err := state.remove(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ Backed up %d key(s) to %s\n", len(pending), sinkNames(sinks))
+	return nil
+}
+
+// backupDirectoryEntry reads and backs up one key of a directory backup
+func backupDirectoryEntry(ctx context.Context, flags *backupFlags, input, outputDir string, planned map[string]string, passphrase []byte, sinks []storage.Sink, hostname, username string) error {
+	fmt.Printf("Reading SSH key from %s...\n", input)
+	keyData, err := readInputKey(flags, input)
+	if err != nil {
+		return fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	defer crypto.ClearBytes(keyData)
+
+	output := backupOutputPath(flags, input, outputDir, keyData)
+	if other, ok := planned[output]; ok {
+		return fmt.Errorf("--output-template gives %s and %s the same name %s", other, input, filepath.Base(output))
+	}
+	if storage.FileExists(output) {
+		return newFileError(fs.ErrExist, output, "output file already exists: %s", output)
+	}
+	planned[output] = input
+
+	extra, err := publicKeyMember(flags, input, keyData)
+	if err != nil {
+		return err
+	}
+	defer clearMembers(extra)
+
+	return backupKey(ctx, flags, backupJob{input: input, output: output, keyData: keyData, extra: extra}, passphrase, sinks, hostname, username)
+}
+
+// publicKeyMember returns the <input>.pub sibling as a bundle member when
+// --include-pub is set and the file exists
+func publicKeyMember(flags *backupFlags, input string, keyData []byte) ([]format.Member, error) {
+	if !flags.includePub {
+		return nil, nil
+	}
+
+	var members []format.Member
+
+	pubPath := input + ".pub"
+	if storage.FileExists(pubPath) {
+		pubData, err := readInputKey(flags, pubPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+
+		// A stale .pub from another key would restore a mismatched pair
+		privFingerprint, privErr := ssh.Fingerprint(keyData)
+		pubFingerprint, pubErr := ssh.Fingerprint(pubData)
+		if privErr == nil && pubErr == nil && privFingerprint != pubFingerprint {
+			return nil, fmt.Errorf("%s does not match %s (fingerprints %s and %s)", pubPath, input, pubFingerprint, privFingerprint)
+		}
+
+		member, err := fileMember(pubPath, pubData)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	// The certificate ssh-keygen -s issued for the key goes with it too
+	certPath := ssh.CertificatePath(input)
+	if storage.FileExists(certPath) {
+		certData, err := readInputKey(flags, certPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate: %w", err)
+		}
+		if !ssh.IsCertificate(certData) {
+			return nil, fmt.Errorf("%s is not an OpenSSH certificate", certPath)
+		}
+		if _, err := ssh.ParsePublicKey(keyData); err == nil && !ssh.CertifiesKey(certData, keyData) {
+			return nil, fmt.Errorf("%s is a certificate for another key than %s", certPath, input)
+		}
+
+		member, err := fileMember(certPath, certData)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if len(members) == 0 {
+		fmt.Printf("⚠️  No %s found; backing up the private key only\n", pubPath)
+	}
+	return members, nil
+}
+
+// readInputKey reads a key file to back up, refusing symbolic links when
+// --no-follow-symlinks is set
+func readInputKey(flags *backupFlags, path string) ([]byte, error) {
+	if flags.noFollow {
+		return ssh.ReadKeyFileNoFollow(path)
+	}
+	return ssh.ReadKeyFile(path)
+}
+
+// checkNotBackupFile refuses to back up a file that is already an sshhades
+// backup; see checkNotBackup. Files that cannot be read are left for the
+// key reader to report.
+func checkNotBackupFile(flags *backupFlags, path string) error {
+	if flags.noInputCheck {
+		return nil
+	}
+	data, err := storage.ReadFileLimited(path, storage.MaxEncryptedFileSize, "key file")
+	if err != nil {
+		return nil
+	}
+	defer crypto.ClearBytes(data)
+	return checkNotBackup(flags, path, data)
+}
+
+// checkNotBackup refuses input that is an sshhades backup, even a damaged
+// or armored one, since backing up a backup nearly always means restore was
+// meant. --no-input-check skips the check.
+func checkNotBackup(flags *backupFlags, name string, data []byte) error {
+	if flags.noInputCheck || format.Identify(data).Kind != format.KindSSHHades {
+		return nil
+	}
+	if name == "stdin" {
+		return fmt.Errorf("stdin is already an sshhades backup, not an SSH key; did you mean restore? (--no-input-check skips this check)")
+	}
+	return fmt.Errorf("%s is already an sshhades backup, not an SSH key; did you mean 'sshhades restore -i %s'? (--no-input-check skips this check)", name, name)
+}
+
+// fileMember describes a key file as a bundle member
+func fileMember(path string, data []byte) (format.Member, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return format.Member{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return format.Member{
+		Name: filepath.Base(path),
+		Mode: uint32(info.Mode().Perm()),
+		Type: ssh.DetectKeyType(data),
+		Data: data,
+	}, nil
+}
+
+// memberNames lists the names of bundle members for messages
+func memberNames(members []format.Member) string {
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// clearMembers wipes the content of bundle members
+func clearMembers(members []format.Member) {
+	for _, m := range members {
+		crypto.ClearBytes(m.Data)
+	}
+}
+
+// backupJob is one key to back up: its source path, backup path and
+// contents, plus any further files bundled with it
+type backupJob struct {
+	input   string
+	output  string
+	keyData []byte
+	extra   []format.Member
+}
+
+// backupKey encrypts one key and writes it to every sink
+func backupKey(ctx context.Context, flags *backupFlags, job backupJob, passphrase []byte, sinks []storage.Sink, hostname, username string) error {
+	var err error
+
 	// Set up encryption parameters
 	var kdfParams crypto.KDFParams
 	var header format.Header
@@ -140,6 +1060,36 @@ func runBackup(flags *backupFlags) error {
 	} else {
 		kdfParams = crypto.DefaultKDFParams()
 		header = format.DefaultHeader()
+
+		// Per-algorithm defaults from config sit between the built-in
+		// defaults and explicit flags
+		profile, err := kdfProfile(flags.algorithm)
+		if err != nil {
+			return err
+		}
+		if profile.Iterations > 0 {
+			kdfParams.Iterations = profile.Iterations
+			header.Iterations = profile.Iterations
+		}
+		if profile.Memory > 0 {
+			kdfParams.Memory = profile.Memory
+			header.Memory = profile.Memory
+		}
+		if profile.Threads > 0 {
+			kdfParams.Threads = profile.Threads
+			header.Threads = profile.Threads
+		}
+
+		// A strength level replaces the defaults and the config profile
+		if flags.strength != "" {
+			preset, err := crypto.StrengthParams(flags.strength)
+			if err != nil {
+				return err
+			}
+			kdfParams.Iterations, header.Iterations = preset.Iterations, preset.Iterations
+			kdfParams.Memory, header.Memory = preset.Memory, preset.Memory
+			kdfParams.Threads, header.Threads = preset.Threads, preset.Threads
+		}
 	}
 
 	// Override with custom parameters if provided
@@ -156,15 +1106,85 @@ func runBackup(flags *backupFlags) error {
 		header.Threads = flags.threads
 	}
 
+	kdfParams.Variant = flags.kdfVariant
+	header.KDF = flags.kdfVariant
+
+	// Only record the strength level while it still names the parameters;
+	// explicit overrides turn the file into a custom setting
+	if flags.strength != "" && crypto.MatchesStrength(flags.strength, header.Iterations, header.Memory, header.Threads) {
+		header.Strength = flags.strength
+	}
+
 	header.Algorithm = flags.algorithm
 	header.Comment = flags.comment
+	if flags.hint != "" {
+		if hintRevealsPassphrase(flags.hint, passphrase) {
+			return fmt.Errorf("--hint contains the passphrase; the hint is stored unencrypted")
+		}
+		header.Hint = flags.hint
+	}
+	setKeyMetadata(&header, job.input, job.keyData)
+	if header.Certificate == nil {
+		header.Certificate = bundledCertificate(job.extra)
+	}
+	if job.input == stdinInput {
+		applyKeyTypeHint(&header, flags.stdinKeyType)
+	}
+	printKeyRoleNote(job.input, header.KeyRole)
+	header.Hostname = hostname
+	header.Username = username
+	if flags.noMetadata {
+		header.StripMetadata()
+	}
+	if flags.expiresIn > 0 {
+		expiresAt := time.Now().UTC().Add(flags.expiresIn).Truncate(time.Second)
+		header.ExpiresAt = &expiresAt
+	}
+
+	// Several files are encrypted together as a bundle, with the key first
+	body := job.keyData
+	if len(job.extra) > 0 {
+		primary, err := fileMember(job.input, job.keyData)
+		if err != nil {
+			return err
+		}
+		members := append([]format.Member{primary}, job.extra...)
+
+		body, err = format.EncodeBundle(members)
+		if err != nil {
+			return fmt.Errorf("failed to bundle files: %w", err)
+		}
+		defer crypto.ClearBytes(body)
+
+		header.Version = format.BundleVersion
+		header.Members = len(members)
+		fmt.Printf("Bundling %d files: %s\n", len(members), memberNames(members))
+	}
 
-	// Encrypt the key
+	// Compress last, so a bundle is compressed as a whole
+	sealed, err := compressPlaintext(flags.compress, body, &header)
+	if err != nil {
+		return err
+	}
+	if header.Compression != "" {
+		defer crypto.ClearBytes(sealed)
+	}
+
+	// Key derivation cannot be interrupted, so the deadline is checked on
+	// both sides of it; nothing is written once it has passed
+	if err := checkDeadline(ctx); err != nil {
+		return err
+	}
 	fmt.Printf("Encrypting SSH key with %s...\n", flags.algorithm)
-	result, err := crypto.Encrypt(keyData, passphrase, flags.algorithm, kdfParams)
+	keys := crypto.NewKeyCache()
+	defer keys.Clear()
+	result, err := crypto.EncryptCached(sealed, passphrase, flags.algorithm, kdfParams, keys)
 	if err != nil {
 		return fmt.Errorf("encryption failed: %w", err)
 	}
+	if err := checkDeadline(ctx); err != nil {
+		return err
+	}
 
 	// Create encrypted file structure
 	encFile := &format.EncryptedFile{
@@ -175,84 +1195,367 @@ func runBackup(flags *backupFlags) error {
 		Tag:        result.Tag,
 	}
 
-	// Save encrypted file
-	fmt.Printf("Saving encrypted key to %s...\n", flags.output)
-	if err := storage.SaveEncryptedFile(flags.output, encFile); err != nil {
-		return fmt.Errorf("failed to save encrypted file: %w", err)
+	// Move the descriptive fields into a section only the passphrase opens
+	if flags.encryptMeta {
+		encFile.Header.StripMetadata()
+		if err := crypto.SealMetadataCached(encFile, header.Metadata(), passphrase, keys); err != nil {
+			return fmt.Errorf("failed to encrypt metadata: %w", err)
+		}
+		if err := checkDeadline(ctx); err != nil {
+			return err
+		}
+	}
+
+	// A split backup is saved as share files instead of a single file
+	if flags.splitTotal > 0 {
+		return writeShareFiles(flags, job, encFile, passphrase, body)
+	}
+
+	data, err := encFile.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize encrypted file: %w", err)
+	}
+	if flags.base64 {
+		data = format.Armor(data)
 	}
 
-	// Upload to GitHub if requested
-	if flags.githubRepo != "" {
-		fmt.Printf("Uploading to GitHub repository %s...\n", flags.githubRepo)
-		if err := uploadToGitHub(flags.output, flags.comment); err != nil {
-			fmt.Printf("⚠️  Warning: GitHub upload failed: %v\n", err)
-			fmt.Println("   The file has been saved locally successfully.")
+	// Write to every target, reporting each one
+	fmt.Printf("Saving encrypted key to %s...\n", sinkNames(sinks))
+	results, writeErr := storage.WriteAll(sinks, filepath.Base(job.output), data)
+	var savedTo []string
+	for _, result := range results {
+		if result.Err != nil {
+			printError(fmt.Sprintf("%s: %v", result.Sink, result.Err))
 		} else {
-			fmt.Println("✓ Successfully uploaded to GitHub")
+			fmt.Printf("✓ Saved to %s\n", result.Sink)
+			savedTo = append(savedTo, result.Sink)
+		}
+	}
+	saved := len(savedTo) > 0
+
+	if saved {
+		recordBackup(job.output, encFile.Header, savedTo)
+	}
+
+	if writeErr != nil {
+		return fmt.Errorf("backup failed for some targets: %w", writeErr)
+	}
+
+	if flags.verifyAfter {
+		if err := verifyAfterBackup(flags, job.output, passphrase, body); err != nil {
+			return err
 		}
 	}
 
 	// Get absolute path for display
-	absPath, _ := filepath.Abs(flags.output)
-	fmt.Printf("✓ SSH key successfully encrypted and saved to: %s\n", absPath)
+	savedPath := filepath.Base(job.output)
+	if hasSink(sinks, "local") {
+		absPath, _ := filepath.Abs(job.output)
+		savedPath = absPath
+		fmt.Printf("✓ SSH key successfully encrypted and saved to: %s\n", absPath)
+	} else {
+		fmt.Printf("✓ SSH key successfully encrypted and saved to: %s\n", sinkNames(sinks))
+	}
 	
 	if flags.comment != "" {
 		fmt.Printf("  Comment: %s\n", flags.comment)
 	}
-	fmt.Printf("  Encryption: %s with Argon2id (%d iterations)\n", flags.algorithm, header.Iterations)
+	if flags.hint != "" {
+		fmt.Printf("  Hint: %s (stored unencrypted)\n", flags.hint)
+	}
+	kdf := fmt.Sprintf("%s (%d iterations, %d MB, %d threads)", header.KDF, header.Iterations, header.Memory, header.Threads)
+	if header.Strength != "" {
+		kdf = fmt.Sprintf("%s, strength %s", kdf, header.Strength)
+	}
+	fmt.Printf("  Encryption: %s with %s\n", flags.algorithm, kdf)
 
-	// Upload to GitHub if requested
-	if flags.githubUpload {
-		fmt.Println("\n📤 Uploading to GitHub...")
-		if err := uploadToGitHub(flags.output, flags.comment); err != nil {
-			github.PrintError(fmt.Sprintf("GitHub upload failed: %v", err))
-			github.PrintInfo("Backup saved locally, but not uploaded to GitHub")
-		} else {
-			github.PrintSuccess("Successfully uploaded to GitHub!")
+	if flags.shredSource {
+		if err := shredSourceKey(flags, passphrase, body); err != nil {
+			return err
 		}
 	}
 
+	fingerprint, _ := ssh.Fingerprint(job.keyData)
+	return flags.hook.run(ctx, hookEvent{operation: "backup", path: savedPath, fingerprint: fingerprint, targets: savedTo}, flags.passphraseEnv)
+}
+
+// hintRevealsPassphrase reports whether a passphrase hint contains the
+// passphrase itself, ignoring case
+func hintRevealsPassphrase(hint string, passphrase []byte) bool {
+	if len(passphrase) == 0 {
+		return false
+	}
+	lower := bytes.ToLower(passphrase)
+	defer crypto.ClearBytes(lower)
+	return bytes.Contains(bytes.ToLower([]byte(hint)), lower)
+}
+
+// compressModes are the values --compress accepts
+var compressModes = []string{"none", "auto", "gzip"}
+
+// compressPlaintext applies --compress to plaintext and records the method
+// actually used in header. With auto, plaintext is returned unchanged
+// unless compressing makes the backup smaller.
+func compressPlaintext(mode string, plaintext []byte, header *format.Header) ([]byte, error) {
+	var compressed []byte
+	var method string
+	var err error
+	switch mode {
+	case "auto":
+		compressed, method, err = format.CompressAuto(plaintext)
+	case "gzip":
+		compressed, err = format.Compress(plaintext)
+		method = format.CompressionGzip
+	default:
+		return plaintext, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "" {
+		fmt.Println("Compression would not make this backup smaller; storing it uncompressed")
+		return plaintext, nil
+	}
+	if len(compressed) >= len(plaintext) {
+		fmt.Printf("⚠️  Warning: compressed data (%d bytes) is not smaller than the key (%d bytes); use --compress auto to skip it\n", len(compressed), len(plaintext))
+	}
+
+	header.Version = format.CompressionVersion
+	header.Compression = method
+	fmt.Printf("Compressed %d bytes to %d with %s\n", len(plaintext), len(compressed), method)
+	return compressed, nil
+}
+
+// shredSourceKey destroys the plaintext source key once its backup is proven recoverable
+func shredSourceKey(flags *backupFlags, passphrase, plaintext []byte) error {
+	// --verify-after-backup has already proven it
+	if !flags.verifyAfter {
+		fmt.Println("\nVerifying backup before shredding the source key...")
+		if err := verifyWrittenBackup(flags.output, passphrase, plaintext); err != nil {
+			return fmt.Errorf("backup verification failed, source key was NOT shredded: %w", err)
+		}
+		fmt.Println("✓ Backup verified")
+	}
+
+	if !flags.force && !confirm(fmt.Sprintf("Shred %s? This cannot be undone (y/N): ", flags.input)) {
+		fmt.Println("Source key kept")
+		return nil
+	}
+
+	fmt.Println("⚠️  Shredding is best-effort: SSDs and journaling filesystems may retain copies of the key")
+	if err := storage.ShredFile(flags.input); err != nil {
+		return fmt.Errorf("failed to shred source key: %w", err)
+	}
+
+	fmt.Printf("✓ Source key shredded: %s\n", flags.input)
+	return nil
+}
+
+// verifyAfterBackup runs --verify-after-backup on a saved backup, warning
+// loudly when it cannot be recovered
+func verifyAfterBackup(flags *backupFlags, path string, passphrase, plaintext []byte) error {
+	fmt.Println("Verifying the written backup...")
+	if err := verifyWrittenBackup(path, passphrase, plaintext); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s was written but cannot be restored; do not rely on it\n", path)
+		if flags.shredSource {
+			fmt.Fprintln(os.Stderr, "   The source key was NOT shredded")
+		}
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
+	fmt.Println("✓ Backup re-read, validated and decrypted")
 	return nil
 }
 
-// uploadToGitHub handles uploading the encrypted file to GitHub repository
-func uploadToGitHub(localPath, comment string) error {
-	// Load configuration
-	cfg, err := config.LoadConfig()
+// verifyWrittenBackup re-reads a saved backup and checks it decrypts to the expected plaintext
+func verifyWrittenBackup(path string, passphrase, expected []byte) error {
+	encFile, err := loadValidEncryptedFile(path)
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := crypto.Decrypt(encFile, passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return fmt.Errorf("decryption failed: %w", err)
+	}
+	defer crypto.ClearBytes(decrypted)
+
+	if !crypto.Equal(decrypted, expected) {
+		return fmt.Errorf("decrypted content does not match the source key")
+	}
+
+	return nil
+}
+
+// backupTargets returns the targets selected by --to and --github, each
+// once, defaulting to local. --github adds github to those targets, so
+// "-o backup.enc --github" saves locally and uploads, and giving both
+// --github and --to github still uploads only once.
+func backupTargets(flags *backupFlags) []string {
+	requested := append([]string{}, flags.targets...)
+	if len(requested) == 0 {
+		requested = append(requested, "local")
+	}
+	if flags.githubUpload {
+		requested = append(requested, "github")
 	}
 
-	if !cfg.IsGitHubConfigured() {
-		return fmt.Errorf("GitHub is not configured. Run 'sshhades github login' first")
+	var targets []string
+	seen := make(map[string]bool)
+	for _, target := range requested {
+		target = strings.ToLower(strings.TrimSpace(target))
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
 	}
+	return targets
+}
 
-	githubCfg := cfg.GetGitHubConfig()
+// newBackupSinks builds the sinks selected by --to and --github, with the
+// local sink writing into localDir. A non-empty hostname groups GitHub
+// uploads under ssh-keys/<hostname>/.
+func newBackupSinks(ctx context.Context, flags *backupFlags, localDir, hostname string) ([]storage.Sink, error) {
+	var sinks []storage.Sink
+
+	for _, target := range backupTargets(flags) {
+		switch target {
+		case "local":
+			sink := storage.NewLocalSink(localDir)
+			sink.Context = ctx
+			sinks = append(sinks, sink)
+		case "github":
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load config: %w", err)
+			}
+			sink, err := github.NewSink(cfg, flags.comment)
+			if err != nil {
+				return nil, err
+			}
+			sink.Context = ctx
+			if hostname != "" {
+				sink.Dir = path.Join(github.DefaultBackupDir, sanitizeFilename(hostname))
+			}
+			sinks = append(sinks, sink)
+		case "bitbucket":
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load config: %w", err)
+			}
+			sink, err := bitbucket.NewSink(cfg, flags.comment)
+			if err != nil {
+				return nil, err
+			}
+			sink.Context = ctx
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unsupported backup target: %s (use: local, github, bitbucket)", target)
+		}
+	}
+
+	return sinks, nil
+}
+
+// sinkNames returns a comma-separated list of sink names
+func sinkNames(sinks []storage.Sink) string {
+	names := make([]string, len(sinks))
+	for i, sink := range sinks {
+		names[i] = sink.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+// hasSink reports whether a sink with the given name is selected
+func hasSink(sinks []storage.Sink, name string) bool {
+	for _, sink := range sinks {
+		if sink.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// recordBackup appends the backup to the local manifest. The manifest is an
+// audit aid, so failing to update it only produces a warning.
+func recordBackup(output string, header format.Header, targets []string) {
+	path, err := manifest.DefaultPath()
+	if err == nil {
+		file, _ := filepath.Abs(output)
+		err = manifest.Append(path, manifest.Entry{
+			Time:        time.Now().UTC(),
+			File:        file,
+			Fingerprint: header.Fingerprint,
+			Algorithm:   header.Algorithm,
+			Targets:     targets,
+		})
+	}
 
-	// Create authenticated client
-	client, err := github.NewAuthenticatedClient(githubCfg)
 	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+		fmt.Printf("⚠️  Warning: failed to update backup manifest: %v\n", err)
 	}
+}
 
-	// Read the encrypted file
-	content, err := os.ReadFile(localPath)
+// hostTags returns the local hostname and OS username for --tag-host
+func hostTags() (string, string, error) {
+	hostname, err := os.Hostname()
 	if err != nil {
-		return fmt.Errorf("failed to read encrypted file: %w", err)
+		return "", "", fmt.Errorf("failed to get hostname: %w", err)
 	}
 
-	// Generate remote path and commit message
-	filename := filepath.Base(localPath)
-	remotePath := fmt.Sprintf("ssh-keys/%s", filename)
-	
-	commitMessage := fmt.Sprintf("Backup SSH key: %s", filename)
-	if comment != "" {
-		commitMessage = fmt.Sprintf("Backup SSH key: %s - %s", filename, comment)
+	current, err := user.Current()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get current user: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	return hostname, current.Username, nil
+}
+
+// setKeyMetadata records the key type, role, fingerprint and original filename in the header
+func setKeyMetadata(header *format.Header, inputPath string, keyData []byte) {
+	header.KeyType = ssh.DetectKeyType(keyData)
+	if inputPath != stdinInput {
+		header.OriginalName = filepath.Base(inputPath)
+		header.KeyRole = ssh.ClassifyKeyRole(inputPath, keyData)
+	} else {
+		header.KeyRole = ssh.ClassifyKeyRole("", keyData)
+	}
+
+	// Fingerprint is best-effort; unparseable keys are still backed up
+	if fingerprint, err := ssh.Fingerprint(keyData); err == nil {
+		header.Fingerprint = fingerprint
+	}
 
-	// Upload file
-	return client.UploadFile(ctx, githubCfg.RepoOwner, githubCfg.RepoName, remotePath, content, commitMessage)
-}
\ No newline at end of file
+	if ssh.IsCertificate(keyData) {
+		header.Certificate, _ = ssh.ParseCertificate(keyData)
+	}
+}
+
+// bundledCertificate describes the first OpenSSH certificate among bundle
+// members, or returns nil when there is none
+func bundledCertificate(members []format.Member) *format.CertificateInfo {
+	for _, m := range members {
+		if ssh.IsCertificate(m.Data) {
+			if cert, err := ssh.ParseCertificate(m.Data); err == nil {
+				return cert
+			}
+		}
+	}
+	return nil
+}
+
+// applyKeyTypeHint records the --stdin-key-type hint when detection failed.
+// A detected type always wins; a disagreeing hint is only reported.
+func applyKeyTypeHint(header *format.Header, hint string) {
+	if hint == "" || hint == header.KeyType {
+		return
+	}
+
+	if header.KeyType == "unknown" {
+		header.KeyType = hint
+		return
+	}
+
+	fmt.Printf("⚠️  --stdin-key-type %s conflicts with detected key type %s; recording %s\n", hint, header.KeyType, header.KeyType)
+}
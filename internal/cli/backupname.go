@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sshhades/sshhades/internal/ssh"
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+// outputTemplatePlaceholders are the placeholders --output-template accepts
+var outputTemplatePlaceholders = []string{"{name}", "{type}", "{date}", "{fingerprint}", "{host}"}
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// validateOutputTemplate rejects empty templates and unknown placeholders,
+// which would otherwise end up in file names as literal text
+func validateOutputTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("--output-template must not be empty")
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		known := false
+		for _, p := range outputTemplatePlaceholders {
+			if placeholder == p {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s in --output-template (use: %s)", placeholder, strings.Join(outputTemplatePlaceholders, ", "))
+		}
+	}
+	return nil
+}
+
+// renderBackupName expands an --output-template for the key at input.
+// Every value is sanitized, so the result is always a single file name,
+// and .enc is appended when the template does not end with it.
+func renderBackupName(template, input string, keyData []byte, now time.Time) string {
+	fingerprint, _ := ssh.Fingerprint(keyData)
+	// Drop the "SHA256:" prefix so names stay short
+	fingerprint = strings.TrimPrefix(fingerprint, "SHA256:")
+	if fingerprint == "" {
+		fingerprint = "unknown"
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	replacer := strings.NewReplacer(
+		"{name}", sanitizeFilename(filepath.Base(input)),
+		"{type}", sanitizeFilename(ssh.DetectKeyType(keyData)),
+		"{date}", now.UTC().Format("2006-01-02"),
+		"{fingerprint}", sanitizeFilename(fingerprint),
+		"{host}", sanitizeFilename(host),
+	)
+	name := sanitizeFilename(replacer.Replace(template))
+	if !strings.HasSuffix(name, ".enc") {
+		name += ".enc"
+	}
+	return name
+}
+
+// backupOutputPath returns where the backup of input goes in outputDir (or
+// next to the key when outputDir is empty), named by --output-template
+// when one is set
+func backupOutputPath(flags *backupFlags, input, outputDir string, keyData []byte) string {
+	if flags.outputTemplate == "" {
+		return storage.CreateBackupPath(input, outputDir)
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(input)
+	}
+	return filepath.Join(outputDir, renderBackupName(flags.outputTemplate, input, keyData, time.Now()))
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateOutputTemplate(t *testing.T) {
+	for _, valid := range []string{"{name}", "{host}-{type}-{date}.enc", "{fingerprint}", "backup"} {
+		if err := validateOutputTemplate(valid); err != nil {
+			t.Errorf("validateOutputTemplate(%q) error = %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "  ", "{user}", "{name}-{Name}", "{}"} {
+		if err := validateOutputTemplate(invalid); err == nil {
+			t.Errorf("validateOutputTemplate(%q) accepted an invalid template", invalid)
+		}
+	}
+}
+
+func TestRenderBackupName(t *testing.T) {
+	dir := t.TempDir()
+	key, err := os.ReadFile(writeTestKey(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 9, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+
+	tests := []struct {
+		template string
+		input    string
+		want     string
+	}{
+		{"{name}-{date}", "/home/alice/.ssh/id_ed25519", "id_ed25519-2024-03-10.enc"},
+		{"{type}.enc", "id", "openssh.enc"},
+		{"{name}", "/keys/my key;rm -rf", "my_key_rm_-rf.enc"},
+		{"../{name}", "id_ed25519", "_id_ed25519.enc"},
+		{"{name}/{type}", "id_ed25519", "id_ed25519_openssh.enc"},
+		{"{name}", "/keys/.hidden", "hidden.enc"},
+	}
+	for _, tt := range tests {
+		if got := renderBackupName(tt.template, tt.input, key, now); got != tt.want {
+			t.Errorf("renderBackupName(%q, %q) = %q, want %q", tt.template, tt.input, got, tt.want)
+		}
+	}
+
+	// A garbage key still gets a usable single file name
+	got := renderBackupName("{fingerprint}-{type}", "id", []byte("not a key"), now)
+	if strings.ContainsAny(got, `/\:`) || !strings.HasSuffix(got, ".enc") {
+		t.Errorf("renderBackupName() of an unparseable key = %q", got)
+	}
+}
+
+func TestBackupDirectoryTemplateCollision(t *testing.T) {
+	useFastKDFConfig(t)
+	keys := t.TempDir()
+	writeTestKey(t, keys)
+	if err := os.Rename(filepath.Join(keys, "id_ed25519"), filepath.Join(keys, "id_first")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestKey(t, keys)
+	output := t.TempDir()
+	t.Setenv("TEST_PASSPHRASE", "correct horse battery staple")
+
+	var err error
+	captureStdout(t, func() {
+		err = runTestCommand(t, "backup", "--directory", keys, "--output-dir", output, "--output-template", "{type}", "--passphrase-env", "TEST_PASSPHRASE")
+	})
+	if err == nil || !strings.Contains(err.Error(), "the same name openssh.enc") {
+		t.Fatalf("backup --output-template {type} error = %v, want a collision error", err)
+	}
+
+	// The first key was backed up; the second did not overwrite it
+	entries, _ := os.ReadDir(output)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !strings.Contains(strings.Join(names, " "), "openssh.enc") {
+		t.Errorf("output directory holds %v, want openssh.enc", names)
+	}
+}
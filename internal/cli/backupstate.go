@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+// backupStateFile is the name of the directory backup state file, kept in
+// the output directory while a run is incomplete
+const backupStateFile = ".sshhades-backup-state.json"
+
+// maxBackupStateSize bounds how much of a state file is read
+const maxBackupStateSize = 4 << 20
+
+// backupState records which keys of a directory backup have been backed up,
+// so an interrupted run can be resumed with --resume. Keys are recorded by
+// file name, as they all come from one directory.
+type backupState struct {
+	Directory string   `json:"directory"`
+	Completed []string `json:"completed"`
+
+	path string
+	done map[string]bool
+}
+
+// openBackupState loads the state file in outputDir for a backup of
+// directory. Without resume an existing state file is an error, so an
+// unfinished run is never silently restarted.
+func openBackupState(outputDir, directory string, resume bool) (*backupState, error) {
+	absDir, err := filepath.Abs(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &backupState{
+		Directory: absDir,
+		path:      filepath.Join(outputDir, backupStateFile),
+		done:      make(map[string]bool),
+	}
+
+	if !storage.FileExists(state.path) {
+		if resume {
+			fmt.Printf("⚠️  Warning: no unfinished run recorded in %s; starting from the beginning\n", outputDir)
+		}
+		return state, nil
+	}
+
+	if !resume {
+		return nil, newFileError(fs.ErrExist, state.path, "an unfinished directory backup is recorded in %s (use --resume to continue it, or delete the file to start over)", state.path)
+	}
+
+	data, err := storage.ReadFileLimited(state.path, maxBackupStateSize, "state file")
+	if err != nil {
+		return nil, err
+	}
+
+	var saved backupState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", state.path, err)
+	}
+	if saved.Directory != absDir {
+		return nil, fmt.Errorf("%s belongs to a backup of %s, not %s", state.path, saved.Directory, absDir)
+	}
+
+	for _, input := range saved.Completed {
+		state.markDone(input)
+	}
+	return state, nil
+}
+
+// markDone records input as backed up in memory
+func (s *backupState) markDone(input string) {
+	name := filepath.Base(input)
+	if !s.done[name] {
+		s.done[name] = true
+		s.Completed = append(s.Completed, name)
+	}
+}
+
+// isDone reports whether input was backed up by an earlier run
+func (s *backupState) isDone(input string) bool {
+	return s.done[filepath.Base(input)]
+}
+
+// complete records input as backed up and saves the state file
+func (s *backupState) complete(input string) error {
+	s.markDone(input)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save backup state: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the state file once every key has been backed up
+func (s *backupState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove backup state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupStateResume(t *testing.T) {
+	outputDir := t.TempDir()
+	keys := t.TempDir()
+
+	state, err := openBackupState(outputDir, keys, false)
+	if err != nil {
+		t.Fatalf("openBackupState() error = %v", err)
+	}
+	if err := state.complete(filepath.Join(keys, "id_ed25519")); err != nil {
+		t.Fatalf("complete() error = %v", err)
+	}
+
+	// An unfinished run must not be restarted without --resume
+	if _, err := openBackupState(outputDir, keys, false); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("openBackupState() without resume error = %v, want ErrExist", err)
+	}
+
+	resumed, err := openBackupState(outputDir, keys, true)
+	if err != nil {
+		t.Fatalf("openBackupState() with resume error = %v", err)
+	}
+	if !resumed.isDone(filepath.Join(keys, "id_ed25519")) {
+		t.Error("resumed state forgot a completed key")
+	}
+	if resumed.isDone(filepath.Join(keys, "id_rsa")) {
+		t.Error("resumed state reports a key that was never backed up")
+	}
+
+	if err := resumed.remove(); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, backupStateFile)); !os.IsNotExist(err) {
+		t.Errorf("state file still exists after remove(): %v", err)
+	}
+	if err := resumed.remove(); err != nil {
+		t.Errorf("remove() of a missing state file error = %v", err)
+	}
+}
+
+func TestBackupStateDirectoryMismatch(t *testing.T) {
+	outputDir := t.TempDir()
+	keys := t.TempDir()
+
+	state, err := openBackupState(outputDir, keys, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.complete(filepath.Join(keys, "id_ed25519")); err != nil {
+		t.Fatal(err)
+	}
+
+	other := t.TempDir()
+	_, err = openBackupState(outputDir, other, true)
+	if err == nil || !strings.Contains(err.Error(), "belongs to a backup of "+keys) {
+		t.Errorf("openBackupState() for another directory error = %v, want a mismatch error", err)
+	}
+}
+
+func TestBackupStateResumeWithoutState(t *testing.T) {
+	outputDir := t.TempDir()
+
+	var err error
+	var state *backupState
+	stdout := captureStdout(t, func() {
+		state, err = openBackupState(outputDir, t.TempDir(), true)
+	})
+	if err != nil {
+		t.Fatalf("openBackupState() error = %v", err)
+	}
+	if len(state.Completed) != 0 {
+		t.Errorf("fresh state has completed keys: %v", state.Completed)
+	}
+	if !strings.Contains(stdout, "starting from the beginning") {
+		t.Errorf("resume without a state file did not warn:\n%s", stdout)
+	}
+}
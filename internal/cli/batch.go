@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/sshhades/sshhades/internal/github"
+)
+
+// batchFailure records one item that failed during a directory-mode run
+type batchFailure struct {
+	item string
+	err  error
+}
+
+// batchRun tracks per-item failures of a directory-mode command. Without
+// --keep-going the first failure aborts the run; with it, failures are
+// collected and summarized once every item has been processed.
+type batchRun struct {
+	keepGoing bool
+	failures  []batchFailure
+}
+
+// fail records a failed item. It returns a non-nil error when the run
+// should stop immediately.
+func (b *batchRun) fail(item string, err error) error {
+	if !b.keepGoing {
+		return fmt.Errorf("%s: %w", item, err)
+	}
+
+	// Items can fail on GitHub API errors, which may echo credentials
+	err = github.RedactError(err)
+	fmt.Printf("❌ %s: %v\n", item, err)
+	b.failures = append(b.failures, batchFailure{item: item, err: err})
+	return nil
+}
+
+// finish prints the failure summary and reports whether any item failed
+func (b *batchRun) finish(total int) error {
+	if len(b.failures) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n%d of %d item(s) failed:\n", len(b.failures), total)
+	for _, failure := range b.failures {
+		fmt.Printf("  %s: %v\n", failure.item, failure.err)
+	}
+
+	return fmt.Errorf("%d of %d item(s) failed", len(b.failures), total)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// typeSecrets answers successive passphrase prompts with answers, as if
+// they were typed at a terminal, and returns the slices handed out
+func typeSecrets(t *testing.T, answers ...string) *[][]byte {
+	t.Helper()
+
+	previousTerminal, previousReader := stdinIsTerminal, secretReader
+	t.Cleanup(func() { stdinIsTerminal, secretReader = previousTerminal, previousReader })
+	stdinIsTerminal = func() bool { return true }
+
+	var issued [][]byte
+	secretReader = func(prompt, hint string) ([]byte, error) {
+		secret := []byte(answers[len(issued)])
+		issued = append(issued, secret)
+		return secret, nil
+	}
+	return &issued
+}
+
+func TestReadNewPassphraseConfirms(t *testing.T) {
+	typeSecrets(t, "correct horse", "correct horse")
+	passphrase, err := readNewPassphrase("", "Passphrase: ", "Again: ")
+	if err != nil || string(passphrase) != "correct horse" {
+		t.Fatalf("readNewPassphrase() = %q, %v", passphrase, err)
+	}
+
+	// Same length, last byte differs
+	issued := typeSecrets(t, "correct horse", "correct horsf")
+	if _, err := readNewPassphrase("", "Passphrase: ", "Again: "); err == nil || !strings.Contains(err.Error(), "do not match") {
+		t.Fatalf("readNewPassphrase() with a typo error = %v, want a mismatch", err)
+	}
+	checkWiped(t, *issued)
+}
+
+func TestSameSplit(t *testing.T) {
+	share := func() *format.EncryptedFile {
+		return &format.EncryptedFile{
+			Header:     format.Header{Share: &format.ShareParams{SetID: "set", Index: 1, Threshold: 2, Total: 3, KeyCheck: "check"}},
+			Salt:       []byte("salt"),
+			Nonce:      []byte("nonce"),
+			Ciphertext: []byte("ciphertext"),
+			Tag:        []byte("tag"),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*format.EncryptedFile)
+		wantErr string
+	}{
+		{"same split", func(f *format.EncryptedFile) { f.Header.Share.Index = 2 }, ""},
+		{"other set", func(f *format.EncryptedFile) { f.Header.Share.SetID = "other" }, "different split"},
+		{"other key check", func(f *format.EncryptedFile) { f.Header.Share.KeyCheck = "checl" }, "different split"},
+		{"other salt", func(f *format.EncryptedFile) { f.Salt[3] ^= 1 }, "different ciphertext"},
+		{"other ciphertext", func(f *format.EncryptedFile) { f.Ciphertext[len(f.Ciphertext)-1] ^= 1 }, "different ciphertext"},
+		{"other tag", func(f *format.EncryptedFile) { f.Tag[0] ^= 1 }, "different ciphertext"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := share()
+			tt.change(other)
+			err := sameSplit(share(), other)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("sameSplit() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestoreFromSharesChecksDataKey(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	if err := runTestCommand(t, append([]string{"backup", "-i", key, "-o", filepath.Join(dir, "id_ed25519.enc"), "--split", "2-of-3"}, testKDFArgs...)...); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+	paths := shareFilePaths(filepath.Join(dir, "id_ed25519.enc"), 3)
+	plaintext, err := os.ReadFile(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, dataKey, err := loadShareFiles(paths[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreFromShares(paths[1:], dataKey, plaintext); err != nil {
+		t.Fatalf("restoreFromShares() error = %v", err)
+	}
+
+	// A data key differing only in its last byte
+	wrongKey := append([]byte{}, dataKey...)
+	wrongKey[len(wrongKey)-1] ^= 1
+	if err := restoreFromShares(paths[1:], wrongKey, plaintext); err == nil || !strings.Contains(err.Error(), "do not combine to the key") {
+		t.Errorf("restoreFromShares() with the wrong data key error = %v", err)
+	}
+
+	// The right key, but the backup does not hold the expected plaintext
+	if err := restoreFromShares(paths[1:], dataKey, []byte("other key")); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("restoreFromShares() with other plaintext error = %v", err)
+	}
+}
@@ -0,0 +1,413 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/manifest"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// configSetters maps settable config keys to functions that apply a value
+var configSetters = map[string]func(cfg *config.Config, value string) error{
+	"default_output_dir": func(cfg *config.Config, value string) error {
+		cfg.DefaultOutputDir = value
+		return nil
+	},
+	"github.protect_token": func(cfg *config.Config, value string) error {
+		protect := false
+		if value != "" {
+			var err error
+			protect, err = strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean: %s", value)
+			}
+		}
+		if protect && !config.TokenProtectionAvailable {
+			return fmt.Errorf("token protection uses Windows DPAPI and is not available on this platform")
+		}
+		if cfg.GitHub == nil {
+			cfg.GitHub = &config.GitHubConfig{}
+		}
+		cfg.GitHub.ProtectToken = protect
+		return nil
+	},
+	"github.base_url": func(cfg *config.Config, value string) error {
+		if err := config.ValidateBaseURL(value); err != nil {
+			return err
+		}
+		if cfg.GitHub == nil {
+			cfg.GitHub = &config.GitHubConfig{}
+		}
+		cfg.GitHub.BaseURL = value
+		return nil
+	},
+	"github.commit_author_name": func(cfg *config.Config, value string) error {
+		if cfg.GitHub == nil {
+			cfg.GitHub = &config.GitHubConfig{}
+		}
+		cfg.GitHub.CommitAuthorName = value
+		return nil
+	},
+	"github.commit_author_email": func(cfg *config.Config, value string) error {
+		if value != "" && !strings.Contains(value, "@") {
+			return fmt.Errorf("invalid email address: %s", value)
+		}
+		if cfg.GitHub == nil {
+			cfg.GitHub = &config.GitHubConfig{}
+		}
+		cfg.GitHub.CommitAuthorEmail = value
+		return nil
+	},
+}
+
+// secretConfigKeys are never echoed back after being set
+var secretConfigKeys = map[string]bool{
+	"bitbucket.app_password": true,
+}
+
+func init() {
+	bitbucketFields := map[string]func(b *config.BitbucketConfig, value string) error{
+		"workspace":    func(b *config.BitbucketConfig, value string) error { b.Workspace = value; return nil },
+		"repo":         func(b *config.BitbucketConfig, value string) error { b.Repo = value; return nil },
+		"username":     func(b *config.BitbucketConfig, value string) error { b.Username = value; return nil },
+		"app_password": func(b *config.BitbucketConfig, value string) error { b.AppPassword = value; return nil },
+		"timeout_seconds": func(b *config.BitbucketConfig, value string) error {
+			if value == "" {
+				b.TimeoutSeconds = 0
+				return nil
+			}
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				return fmt.Errorf("invalid number of seconds: %s", value)
+			}
+			b.TimeoutSeconds = seconds
+			return nil
+		},
+	}
+	for field, set := range bitbucketFields {
+		set := set
+		configSetters["bitbucket."+field] = func(cfg *config.Config, value string) error {
+			if cfg.Bitbucket == nil {
+				cfg.Bitbucket = &config.BitbucketConfig{}
+			}
+			return set(cfg.Bitbucket, value)
+		}
+	}
+
+	for _, name := range []string{config.ProfileAES, config.ProfileChaCha20} {
+		name := name
+		configSetters["kdf."+name+".iterations"] = kdfProfileSetter(name, func(p *config.KDFProfile, v uint64) { p.Iterations = uint32(v) }, 32)
+		configSetters["kdf."+name+".memory"] = kdfProfileSetter(name, func(p *config.KDFProfile, v uint64) { p.Memory = uint32(v) }, 32)
+		configSetters["kdf."+name+".threads"] = kdfProfileSetter(name, func(p *config.KDFProfile, v uint64) { p.Threads = uint8(v) }, 8)
+	}
+}
+
+// kdfProfileSetter builds a setter for one numeric field of a KDF profile.
+// An empty value resets the field to the built-in default.
+func kdfProfileSetter(name string, set func(p *config.KDFProfile, v uint64), bits int) func(cfg *config.Config, value string) error {
+	return func(cfg *config.Config, value string) error {
+		var v uint64
+		if value != "" {
+			var err error
+			v, err = strconv.ParseUint(value, 10, bits)
+			if err != nil {
+				return fmt.Errorf("invalid number: %s", value)
+			}
+		}
+
+		profile := cfg.KDFProfileFor(name)
+		set(&profile, v)
+		if err := profile.Validate(); err != nil {
+			return err
+		}
+
+		if cfg.KDFProfiles == nil {
+			cfg.KDFProfiles = make(map[string]config.KDFProfile)
+		}
+		if profile == (config.KDFProfile{}) {
+			delete(cfg.KDFProfiles, name)
+		} else {
+			cfg.KDFProfiles[name] = profile
+		}
+		return nil
+	}
+}
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage sshhades configuration",
+		Long:  `View and change settings stored in the sshhades configuration file.`,
+	}
+
+	cmd.AddCommand(NewConfigShowCmd())
+	cmd.AddCommand(NewConfigSetCmd())
+	cmd.AddCommand(NewConfigResetCmd())
+
+	return cmd
+}
+
+type configShowFlags struct {
+	reveal bool
+	force  bool
+}
+
+func NewConfigShowCmd() *cobra.Command {
+	flags := &configShowFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Long: `Print the configuration sshhades uses, as JSON: the configuration file with
+SSHHADES_GITHUB_* environment variables applied. Tokens and passwords are
+redacted, so the output is safe to share when reporting a problem; --reveal
+prints them in full after confirmation.`,
+		Example: `  # Check which settings are in effect
+  sshhades config show
+
+  # Include the real GitHub token
+  sshhades config show --reveal`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigShow(flags)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.reveal, "reveal", false, "Show tokens and passwords instead of redacting them")
+	cmd.Flags().BoolVar(&flags.force, "force", false, "With --reveal, do not ask for confirmation (required when not interactive)")
+
+	return cmd
+}
+
+func runConfigShow(flags *configShowFlags) error {
+	if flags.force && !flags.reveal {
+		return fmt.Errorf("--force only applies to --reveal")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if flags.reveal && !flags.force {
+		if !isTerminal() && !assumeYes {
+			return fmt.Errorf("--reveal prints secrets in full; pass --force to confirm non-interactively")
+		}
+		if !confirm("Print tokens and passwords in plain text? (y/N): ") {
+			return fmt.Errorf("cancelled")
+		}
+	}
+
+	shown := cfg.Redacted()
+	if flags.reveal {
+		shown = cfg
+	}
+
+	data, err := json.MarshalIndent(shown, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if dir, err := config.DirPath(); err == nil {
+		fmt.Fprintf(os.Stderr, "Config file: %s\n", filepath.Join(dir, config.FileName))
+	}
+	if cfg.GitHubFromEnv() {
+		fmt.Fprintln(os.Stderr, "GitHub settings include SSHHADES_GITHUB_* environment variables")
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func NewConfigSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a configuration value",
+		Long: fmt.Sprintf(`Set a configuration value. Use an empty value to unset it.
+
+Supported keys: %s`, strings.Join(configKeys(), ", ")),
+		Example: `  # Put new backups in ~/backups by default
+  sshhades config set default_output_dir ~/backups
+
+  # Go back to saving backups next to the source key
+  sshhades config set default_output_dir ""
+
+  # Lighter Argon2id defaults whenever --algorithm chacha20 is used
+  sshhades config set kdf.chacha20.memory 16`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSet(args[0], args[1])
+		},
+	}
+}
+
+func runConfigSet(key, value string) error {
+	setter, ok := configSetters[key]
+	if !ok {
+		return fmt.Errorf("unknown config key: %s (supported: %s)", key, strings.Join(configKeys(), ", "))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := setter(cfg, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if value == "" {
+		printSuccess(fmt.Sprintf("%s unset", key))
+	} else if secretConfigKeys[key] {
+		printSuccess(fmt.Sprintf("%s set", key))
+	} else {
+		printSuccess(fmt.Sprintf("%s set to %s", key, value))
+	}
+	return nil
+}
+
+type configResetFlags struct {
+	dryRun bool
+	force  bool
+}
+
+func NewConfigResetCmd() *cobra.Command {
+	flags := &configResetFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "reset",
+		Short: "Delete all sshhades state",
+		Long: `Delete the configuration file, including stored GitHub and Bitbucket
+credentials, and the backup manifest, returning sshhades to a first-run state.
+The configuration directory is removed too when nothing else is left in it.
+Encrypted backups are never touched.`,
+		Example: `  # Show what would be deleted
+  sshhades config reset --dry-run
+
+  # Reset without the confirmation prompt
+  sshhades config reset --force`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigReset(flags)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "List the files that would be deleted without deleting them")
+	cmd.Flags().BoolVar(&flags.force, "force", false, "Do not ask for confirmation (required when not interactive)")
+
+	return cmd
+}
+
+func runConfigReset(flags *configResetFlags) error {
+	dir, err := config.DirPath()
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, name := range []string{config.FileName, manifest.FileName} {
+		if path := filepath.Join(dir, name); storage.FileExists(path) {
+			files = append(files, path)
+		}
+	}
+
+	if len(files) == 0 {
+		fmt.Printf("Nothing to reset: no sshhades state in %s\n", dir)
+		return nil
+	}
+
+	if flags.dryRun {
+		fmt.Println("Would delete:")
+		for _, path := range files {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Printf("  %s (if empty afterwards)\n", dir)
+		return nil
+	}
+
+	if !flags.force {
+		if !isTerminal() && !assumeYes {
+			return fmt.Errorf("config reset deletes %d file(s); pass --force to confirm non-interactively", len(files))
+		}
+		fmt.Println("This will delete:")
+		for _, path := range files {
+			fmt.Printf("  %s\n", path)
+		}
+		if !confirm("Delete these files? This cannot be undone (y/N): ") {
+			fmt.Println("Reset cancelled")
+			return nil
+		}
+	}
+
+	for _, path := range files {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		fmt.Printf("✓ Deleted %s\n", path)
+	}
+
+	// Leave the directory alone if the user keeps anything else in it
+	if err := os.Remove(dir); err == nil {
+		fmt.Printf("✓ Deleted %s\n", dir)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("⚠️  Kept %s: it contains other files\n", dir)
+	}
+
+	printSuccess("sshhades is back to a first-run state")
+	return nil
+}
+
+// configKeys returns the sorted list of settable keys
+func configKeys() []string {
+	keys := make([]string, 0, len(configSetters))
+	for key := range configSetters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// kdfProfile returns the configured KDF defaults for an algorithm
+func kdfProfile(algorithm string) (config.KDFProfile, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.KDFProfile{}, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	switch algorithm {
+	case format.AlgorithmAESGCM:
+		return cfg.KDFProfileFor(config.ProfileAES), nil
+	case format.AlgorithmChaCha20:
+		return cfg.KDFProfileFor(config.ProfileChaCha20), nil
+	default:
+		return config.KDFProfile{}, nil
+	}
+}
+
+// defaultOutputDir returns the configured backup directory with ~ and
+// environment variables expanded, or "" when none is configured
+func defaultOutputDir() (string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.DefaultOutputDir == "" {
+		return "", nil
+	}
+
+	return storage.ExpandPath(cfg.DefaultOutputDir)
+}
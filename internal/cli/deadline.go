@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// errDeadlineExceeded is returned when a command runs past --deadline
+var errDeadlineExceeded = errors.New("deadline exceeded")
+
+// deadlineKey is the context key startDeadline records the --deadline of
+// the running command under
+type deadlineKey struct{}
+
+// commandDeadline is the --deadline a command runs under
+type commandDeadline struct {
+	command string
+	limit   time.Duration
+	cancel  context.CancelFunc
+}
+
+// err describes a command that ran past its deadline
+func (d *commandDeadline) err() error {
+	return fmt.Errorf("%s did not finish within --deadline %s: %w", d.command, d.limit, errDeadlineExceeded)
+}
+
+// startDeadline bounds the running command to --deadline by replacing its
+// context with one that ends then. Network requests and child processes are
+// run with that context and stop when it ends; work that cannot be
+// interrupted, such as Argon2 key derivation, is followed by checkDeadline,
+// so the command stops at the next step with a timeout error and exit code
+// exitTimeout. A read from stdin cannot be interrupted either, so a command
+// still waiting for input when the deadline passes is ended by the input
+// watchdog with the same error and exit code.
+func startDeadline(cmd *cobra.Command) error {
+	limit, err := cmd.Flags().GetDuration("deadline")
+	if err != nil {
+		return err
+	}
+	if limit < 0 {
+		return &usageError{err: fmt.Errorf("invalid --deadline %s: must be positive", limit)}
+	}
+	if limit == 0 {
+		return nil
+	}
+
+	deadline := &commandDeadline{command: cmd.CommandPath(), limit: limit}
+	ctx := context.WithValue(cmd.Context(), deadlineKey{}, deadline)
+	ctx, deadline.cancel = context.WithTimeout(ctx, limit)
+	cmd.SetContext(ctx)
+	watchInput(cmd)
+	return nil
+}
+
+// stopDeadline releases the timer of a command's --deadline once the
+// command has finished
+func stopDeadline(cmd *cobra.Command) {
+	if deadline := deadlineOf(cmd.Context()); deadline != nil {
+		deadline.cancel()
+	}
+}
+
+// deadlineOf returns the --deadline ctx was started with, if any
+func deadlineOf(ctx context.Context) *commandDeadline {
+	if ctx == nil {
+		return nil
+	}
+	deadline, _ := ctx.Value(deadlineKey{}).(*commandDeadline)
+	return deadline
+}
+
+// checkDeadline returns the --deadline error once ctx has ended. Commands
+// call it between steps, where stopping leaves nothing half done.
+func checkDeadline(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if deadline := deadlineOf(ctx); deadline != nil {
+		return deadline.err()
+	}
+	return ctx.Err()
+}
+
+// sleepDeadline waits for delay, or returns the checkDeadline error once
+// ctx ends
+func sleepDeadline(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return checkDeadline(ctx)
+	}
+}
+
+// timedOut turns an error caused by the --deadline of ctx into the
+// --deadline error, so a request cut short reads the same as a command
+// stopped by checkDeadline
+func timedOut(ctx context.Context, err error) error {
+	deadline := deadlineOf(ctx)
+	if deadline == nil || ctx.Err() == nil || !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errDeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w (%v)", deadline.err(), err)
+}
+
+// exitProcess ends the process; tests replace it to observe the watchdog
+var exitProcess = os.Exit
+
+// inputWatch is the state of the input watchdog: the command whose
+// --deadline it enforces, the reads from stdin under way, and the terminal
+// state to put back if a secret prompt is cut short
+var inputWatch struct {
+	sync.Mutex
+	cmd      *cobra.Command
+	waiting  int
+	terminal *term.State
+}
+
+func init() {
+	// Commands that fail skip PersistentPostRun, so the watch is dropped
+	// once cobra is done with the command either way
+	cobra.OnFinalize(unwatchInput)
+}
+
+// watchInput starts the input watchdog for cmd, whose context ends at its
+// --deadline
+func watchInput(cmd *cobra.Command) {
+	inputWatch.Lock()
+	inputWatch.cmd = cmd
+	inputWatch.Unlock()
+
+	ctx := cmd.Context()
+	context.AfterFunc(ctx, func() { expireInput(cmd) })
+}
+
+// unwatchInput stops the input watchdog once the command has finished
+func unwatchInput() {
+	inputWatch.Lock()
+	inputWatch.cmd = nil
+	inputWatch.Unlock()
+}
+
+// awaitInput records a read from stdin until the returned function is
+// called. terminal is the state to restore if the read is cut short, or nil
+// when stdin is not in raw mode. A read started after the deadline has
+// passed is ended at once.
+func awaitInput(terminal *term.State) (done func()) {
+	inputWatch.Lock()
+	inputWatch.waiting++
+	inputWatch.terminal = terminal
+	cmd := inputWatch.cmd
+	inputWatch.Unlock()
+
+	if cmd != nil {
+		expireInput(cmd)
+	}
+
+	return func() {
+		inputWatch.Lock()
+		inputWatch.waiting--
+		inputWatch.terminal = nil
+		inputWatch.Unlock()
+	}
+}
+
+// expireInput ends the process with the --deadline error of cmd when its
+// deadline has passed while it waits for input. Otherwise the command stops
+// at its next checkDeadline.
+func expireInput(cmd *cobra.Command) {
+	inputWatch.Lock()
+	ctx := cmd.Context()
+	blocked := inputWatch.cmd == cmd && inputWatch.waiting > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+	terminal := inputWatch.terminal
+	inputWatch.Unlock()
+	if !blocked {
+		return
+	}
+
+	if terminal != nil {
+		_ = term.Restore(int(syscall.Stdin), terminal)
+	}
+	fmt.Fprintln(os.Stderr)
+	exitProcess(ReportError(cmd, os.Stderr, checkDeadline(ctx)))
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+// expiredDeadline returns a context whose --deadline of limit has passed
+func expiredDeadline(t *testing.T, limit time.Duration) context.Context {
+	t.Helper()
+
+	deadline := &commandDeadline{command: "sshhades backup", limit: limit}
+	ctx := context.WithValue(context.Background(), deadlineKey{}, deadline)
+	ctx, deadline.cancel = context.WithTimeout(ctx, time.Nanosecond)
+	t.Cleanup(deadline.cancel)
+	<-ctx.Done()
+	return ctx
+}
+
+func TestTimedOut(t *testing.T) {
+	requestErr := fmt.Errorf("failed to upload: %w", context.DeadlineExceeded)
+
+	// Without --deadline a request timeout stays as it is
+	if err := timedOut(context.Background(), requestErr); err != requestErr {
+		t.Errorf("timedOut() = %v, want the error unchanged", err)
+	}
+
+	// A command that never ran has no context at all
+	var report strings.Builder
+	if code := ReportError(NewRootCommand("test", "", ""), &report, requestErr); code != exitTimeout || strings.Contains(report.String(), "--deadline") {
+		t.Errorf("ReportError() = %d, %q, want the request timeout as it is", code, report.String())
+	}
+
+	ctx := expiredDeadline(t, time.Minute)
+	err := timedOut(ctx, requestErr)
+	if !errors.Is(err, errDeadlineExceeded) || !strings.Contains(err.Error(), "--deadline 1m0s") {
+		t.Errorf("timedOut() = %v, want the --deadline error", err)
+	}
+	if code, exitCode := classifyError(err); code != "timeout" || exitCode != exitTimeout {
+		t.Errorf("classifyError() = %s, %d, want timeout, %d", code, exitCode, exitTimeout)
+	}
+
+	// The --deadline error is not wrapped a second time
+	if again := timedOut(ctx, err); again != err {
+		t.Errorf("timedOut() = %v, want the --deadline error unchanged", again)
+	}
+
+	other := errors.New("permission denied")
+	if err := timedOut(ctx, other); err != other {
+		t.Errorf("timedOut() = %v, want unrelated errors unchanged", err)
+	}
+}
+
+func TestCheckDeadline(t *testing.T) {
+	if err := checkDeadline(context.Background()); err != nil {
+		t.Errorf("checkDeadline() = %v, want nil without a deadline", err)
+	}
+
+	err := checkDeadline(expiredDeadline(t, time.Second))
+	if !errors.Is(err, errDeadlineExceeded) || !strings.Contains(err.Error(), "sshhades backup did not finish within --deadline 1s") {
+		t.Errorf("checkDeadline() = %v, want the --deadline error", err)
+	}
+
+	if err := sleepDeadline(expiredDeadline(t, time.Second), time.Hour); !errors.Is(err, errDeadlineExceeded) {
+		t.Errorf("sleepDeadline() = %v, want the --deadline error", err)
+	}
+}
+
+func TestStartDeadlineRejectsNegative(t *testing.T) {
+	root := NewRootCommand("test", "", "")
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	root.SetArgs([]string{"list", "--deadline", "-1s"})
+
+	var usage *usageError
+	if _, err := root.ExecuteC(); !errors.As(err, &usage) {
+		t.Errorf("list --deadline -1s error = %v, want a usage error", err)
+	}
+}
+
+func TestDeadlineLeavesNoBackup(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	output := filepath.Join(dir, "id_ed25519.enc")
+	t.Setenv("TEST_PASSPHRASE", "correct horse battery staple")
+
+	root := NewRootCommand("test", "", "")
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	root.SetArgs(append([]string{"backup", "-i", key, "-o", output, "--passphrase-env", "TEST_PASSPHRASE", "--deadline", "1ns"}, testKDFArgs...))
+
+	cmd, err := root.ExecuteC()
+	if !errors.Is(err, errDeadlineExceeded) {
+		t.Fatalf("backup --deadline 1ns error = %v, want the --deadline error", err)
+	}
+	if storage.FileExists(output) {
+		t.Error("backup past its deadline still wrote the output file")
+	}
+
+	var report strings.Builder
+	if code := ReportError(cmd, &report, err); code != exitTimeout {
+		t.Errorf("ReportError() = %d, want %d", code, exitTimeout)
+	}
+}
+
+func TestDeadlineEndsBlockedPrompt(t *testing.T) {
+	configureGitHub(t)
+
+	// A prompt whose input never arrives
+	r, w := io.Pipe()
+	defer w.Close()
+	previous := stdinReader
+	t.Cleanup(func() { stdinReader = previous })
+	stdinReader = bufio.NewReader(r)
+
+	exited := make(chan int, 1)
+	t.Cleanup(func() { exitProcess = os.Exit })
+	exitProcess = func(code int) { exited <- code }
+
+	finished := make(chan error, 1)
+	go func() { finished <- runTestCommand(t, "github", "logout", "--deadline", "50ms") }()
+
+	select {
+	case code := <-exited:
+		if code != exitTimeout {
+			t.Errorf("exit code = %d, want %d", code, exitTimeout)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the watchdog did not end a prompt blocked past --deadline")
+	}
+
+	// Let the read the test kept alive return
+	w.Close()
+	<-finished
+}
+
+func TestPostHookStopsAtDeadline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	hook := &postHook{command: "sleep 10", strict: true}
+	start := time.Now()
+	err := hook.run(expiredDeadline(t, time.Second), hookEvent{operation: "backup"}, "")
+	if !errors.Is(err, errDeadlineExceeded) {
+		t.Errorf("run() error = %v, want the --deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hook ran for %s past the deadline", elapsed)
+	}
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+type dumpFlags struct {
+	input   string
+	preview int
+}
+
+func NewDumpCmd() *cobra.Command {
+	flags := &dumpFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Show the raw structure of an encrypted file",
+		Long: `Print the header fields of an encrypted file and hex/base64 previews of its
+salt, nonce, ciphertext and tag, with their lengths. dump never asks for a
+passphrase and never decrypts; it is meant for diagnosing why a file fails
+validation.`,
+		Example: `  # Inspect a backup that verify rejects
+  sshhades dump -i ~/backups/id_ed25519.enc
+
+  # Show the first 64 bytes of each field
+  sshhades dump -i ~/backups/id_ed25519.enc --preview 64`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDump(flags)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "Path to encrypted file (required)")
+	cmd.Flags().IntVar(&flags.preview, "preview", 16, "Number of leading bytes of each field to show")
+	cmd.MarkFlagRequired("input")
+
+	return cmd
+}
+
+func runDump(flags *dumpFlags) error {
+	if flags.preview < 0 {
+		return fmt.Errorf("--preview must not be negative")
+	}
+
+	if err := storage.ValidatePath(flags.input); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+
+	info, err := os.Stat(flags.input)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newFileError(fs.ErrNotExist, flags.input, "file not found: %s", flags.input)
+		}
+		return err
+	}
+
+	encFile, err := storage.LoadEncryptedFile(flags.input)
+	if err != nil {
+		return fmt.Errorf("failed to load encrypted file: %w", err)
+	}
+
+	fmt.Printf("%s (%d bytes)\n\n", flags.input, info.Size())
+
+	header, err := json.MarshalIndent(encFile.Header, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format header: %w", err)
+	}
+	fmt.Printf("Header:\n  %s\n\n", header)
+
+	fmt.Println("Fields:")
+	printDumpField("salt", encFile.Salt, flags.preview)
+	printDumpField("nonce", encFile.Nonce, flags.preview)
+	printDumpField("ciphertext", encFile.Ciphertext, flags.preview)
+	printDumpField("tag", encFile.Tag, flags.preview)
+	if m := encFile.Metadata; m != nil {
+		printDumpField("metadata.nonce", m.Nonce, flags.preview)
+		printDumpField("metadata.ciphertext", m.Ciphertext, flags.preview)
+	}
+	fmt.Println()
+
+	if err := crypto.ValidateEncryptedFile(encFile); err != nil {
+		fmt.Printf("Validation: failed (%v)\n", err)
+	} else {
+		fmt.Println("Validation: ok")
+	}
+
+	return nil
+}
+
+// printDumpField prints the length of a binary field and hex and base64
+// previews of at most limit leading bytes
+func printDumpField(name string, data []byte, limit int) {
+	fmt.Printf("  %-20s %d bytes\n", name, len(data))
+	if len(data) == 0 || limit == 0 {
+		return
+	}
+
+	shown, more := data, ""
+	if len(data) > limit {
+		shown, more = data[:limit], "…"
+	}
+	indent := strings.Repeat(" ", 23)
+	fmt.Printf("%shex:    %s%s\n", indent, hex.EncodeToString(shown), more)
+	fmt.Printf("%sbase64: %s%s\n", indent, base64.StdEncoding.EncodeToString(shown), more)
+}
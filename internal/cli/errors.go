@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/github"
+	"github.com/sshhades/sshhades/internal/ssh"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// Exit codes, so scripts can tell failures apart without parsing messages
+const (
+	exitFailure     = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitExists      = 4
+	exitDecryption  = 5
+	exitInvalidFile = 6
+	exitPermission  = 7
+	exitTimeout     = 8
+)
+
+// errorReport is the --json-errors object written to stderr on failure
+type errorReport struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// fileError is an error about a specific file. The message is kept as
+// written while the path and cause stay available to --json-errors.
+type fileError struct {
+	msg  string
+	path string
+	err  error
+}
+
+func (e *fileError) Error() string { return e.msg }
+func (e *fileError) Unwrap() error { return e.err }
+
+// newFileError formats a message about path and records cause (such as
+// fs.ErrExist) for classification
+func newFileError(cause error, path, format string, args ...any) error {
+	return &fileError{msg: fmt.Sprintf(format, args...), path: path, err: cause}
+}
+
+// errBackupExpired is returned by --strict restores and verifies of a
+// backup past its --expires time
+var errBackupExpired = errors.New("backup expired")
+
+// errRemoteMismatch is returned by verify --compare-remote when the
+// repository copy differs from the local file
+var errRemoteMismatch = errors.New("remote copy differs")
+
+// errFingerprintMismatch is returned by restore --verify-fingerprint when
+// the restored key is not the one that was backed up
+var errFingerprintMismatch = errors.New("fingerprint mismatch")
+
+// usageError marks invalid flags or arguments
+type usageError struct{ err error }
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// jsonErrors reports whether --json-errors was given
+func jsonErrors(cmd *cobra.Command) bool {
+	enabled, _ := cmd.Root().PersistentFlags().GetBool("json-errors")
+	return enabled
+}
+
+// silenceForJSON stops cobra from printing usage, so stderr holds only the
+// JSON report; its error text is always silenced in favor of ReportError
+func silenceForJSON(cmd *cobra.Command) {
+	if jsonErrors(cmd) {
+		cmd.Root().SilenceUsage = true
+	}
+}
+
+// classifyError maps an error to its code and exit code
+func classifyError(err error) (string, int) {
+	var usage *usageError
+	switch {
+	case errors.As(err, &usage):
+		return "usage", exitUsage
+	case errors.Is(err, crypto.ErrDecryptionFailed):
+		return "decryption_failed", exitDecryption
+	case errors.Is(err, format.ErrNewerVersion):
+		return "unsupported_version", exitInvalidFile
+	case errors.Is(err, storage.ErrFileTooLarge):
+		return "file_too_large", exitInvalidFile
+	case errors.Is(err, ssh.ErrEmptyKey):
+		return "empty_key", exitInvalidFile
+	case errors.Is(err, ssh.ErrSymlink):
+		return "symlink_refused", exitInvalidFile
+	case errors.Is(err, errDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "timeout", exitTimeout
+	case errors.Is(err, errNoTerminal):
+		return "no_terminal", exitUsage
+	case errors.Is(err, errBackupExpired):
+		return "backup_expired", exitFailure
+	case errors.Is(err, errFingerprintMismatch):
+		return "fingerprint_mismatch", exitFailure
+	case errors.Is(err, errRemoteMismatch):
+		return "remote_mismatch", exitFailure
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found", exitNotFound
+	case errors.Is(err, fs.ErrExist):
+		return "already_exists", exitExists
+	case errors.Is(err, fs.ErrPermission):
+		return "permission_denied", exitPermission
+	default:
+		return "error", exitFailure
+	}
+}
+
+// errorPath returns the file an error is about, if it names one
+func errorPath(err error) string {
+	var fileErr *fileError
+	if errors.As(err, &fileErr) {
+		return fileErr.path
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Path
+	}
+	return ""
+}
+
+// ReportError prints a failed command's error and returns the exit code.
+// GitHub tokens are redacted from the message.
+// With --json-errors the error is written to w as one JSON object; otherwise
+// the usual "Error: ..." line is printed. cmd is the command that ran, as
+// returned by ExecuteC, whose context tells a --deadline timeout apart.
+func ReportError(cmd *cobra.Command, w io.Writer, err error) int {
+	// API errors can echo a request, so credentials are scrubbed first
+	err = github.RedactError(timedOut(cmd.Context(), err))
+	code, exitCode := classifyError(err)
+
+	if !jsonErrors(cmd) {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return exitCode
+	}
+
+	report := errorReport{
+		Code:     code,
+		Message:  err.Error(),
+		Path:     errorPath(err),
+		ExitCode: exitCode,
+	}
+	data, jsonErr := json.Marshal(report)
+	if jsonErr != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return exitCode
+	}
+	fmt.Fprintln(w, string(data))
+	return exitCode
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportErrorIsOnlyPrinter(t *testing.T) {
+	token := "ghp_" + strings.Repeat("a", 36)
+	dir := t.TempDir()
+
+	var stderr bytes.Buffer
+	root := NewRootCommand("test", "", "")
+	root.SetOut(&stderr)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"restore", "-i", filepath.Join(dir, token+".enc"), "-o", filepath.Join(dir, "key")})
+
+	cmd, err := root.ExecuteC()
+	if err == nil {
+		t.Fatal("restore of a missing file succeeded")
+	}
+	ReportError(cmd, &stderr, err)
+
+	if strings.Contains(stderr.String(), token) {
+		t.Errorf("stderr shows the token:\n%s", stderr.String())
+	}
+	if n := strings.Count(stderr.String(), "Error:"); n != 1 {
+		t.Errorf("stderr has %d \"Error:\" lines, want 1:\n%s", n, stderr.String())
+	}
+}
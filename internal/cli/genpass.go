@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/crypto"
+)
+
+// minGeneratedEntropy is the strength below which genpass warns
+const minGeneratedEntropy = 64
+
+type genPassFlags struct {
+	words        int
+	separator    string
+	length       int
+	charset      string
+	useForBackup string
+	output       string
+
+	// lengthSet records an explicit --length, which selects random
+	// characters instead of words
+	lengthSet bool
+	wordsSet  bool
+}
+
+func NewGenPassCmd() *cobra.Command {
+	flags := &genPassFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "genpass",
+		Short: "Generate a strong random passphrase",
+		Long: `Generate a random passphrase and print it to stdout. By default it is made of
+words from the bundled EFF diceware wordlist (7776 words, about 12.9 bits each);
+with --length it is a string of random characters from --charset instead. The
+estimated strength is printed to stderr so the passphrase can be piped.
+
+With --use-for-backup, the given key is backed up straight away with the
+generated passphrase, which is printed once at the end. Store it before
+closing the terminal: there is no way to recover it.`,
+		Example: `  # Six random words
+  sshhades genpass --words 6
+
+  # 32 random characters including symbols
+  sshhades genpass --length 32 --charset ascii
+
+  # Back up a key with a fresh passphrase
+  sshhades genpass --use-for-backup ~/.ssh/id_ed25519 -o ~/backups/id_ed25519.enc`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags.lengthSet = cmd.Flags().Changed("length")
+			flags.wordsSet = cmd.Flags().Changed("words")
+			return runGenPass(cmd.Context(), flags)
+		},
+	}
+
+	cmd.Flags().IntVar(&flags.words, "words", 6, "Number of diceware words")
+	cmd.Flags().StringVar(&flags.separator, "separator", "-", "Separator between words")
+	cmd.Flags().IntVar(&flags.length, "length", 0, "Generate this many random characters instead of words")
+	cmd.Flags().StringVar(&flags.charset, "charset", "alnum", "Characters for --length: alnum, alpha, lower, digits, hex or ascii")
+	cmd.Flags().StringVar(&flags.useForBackup, "use-for-backup", "", "Back up this SSH key with the generated passphrase")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "With --use-for-backup, the encrypted output file (defaults as for backup)")
+
+	return cmd
+}
+
+func runGenPass(ctx context.Context, flags *genPassFlags) error {
+	if flags.lengthSet && flags.wordsSet {
+		return fmt.Errorf("--words and --length cannot be used together")
+	}
+	if flags.output != "" && flags.useForBackup == "" {
+		return fmt.Errorf("--output requires --use-for-backup")
+	}
+	if flags.useForBackup == stdinInput {
+		return fmt.Errorf("--use-for-backup needs a key file, not stdin")
+	}
+
+	var passphrase []byte
+	var entropy float64
+	var err error
+	if flags.lengthSet {
+		passphrase, err = crypto.GenerateRandomPassphrase(flags.length, flags.charset)
+		entropy = crypto.RandomPassphraseEntropy(flags.length, flags.charset)
+	} else {
+		passphrase, err = crypto.GenerateWordPassphrase(flags.words, flags.separator)
+		entropy = crypto.WordPassphraseEntropy(flags.words)
+	}
+	if err != nil {
+		return err
+	}
+	defer crypto.ClearBytes(passphrase)
+
+	if entropy < minGeneratedEntropy {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: only about %.0f bits of entropy; use at least %d\n", entropy, minGeneratedEntropy)
+	} else if flags.useForBackup == "" {
+		fmt.Fprintf(os.Stderr, "Strength: about %.0f bits of entropy\n", entropy)
+	}
+
+	if flags.useForBackup == "" {
+		fmt.Printf("%s\n", passphrase)
+		return nil
+	}
+
+	backup := &backupFlags{
+		input:      flags.useForBackup,
+		output:     flags.output,
+		algorithm:  "aes",
+		kdfVariant: "argon2id",
+		compress:   "none",
+		passphrase: passphrase,
+	}
+	if err := runBackup(ctx, backup); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nGenerated passphrase (about %.0f bits; shown once, store it now):\n\n  %s\n", entropy, passphrase)
+	return nil
+}
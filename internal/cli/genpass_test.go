@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/crypto"
+)
+
+// useFastKDFConfig points the config at a temporary directory whose KDF
+// profiles make backups without explicit KDF flags quick to derive
+func useFastKDFConfig(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, setting := range [][]string{{"iterations", "1"}, {"memory", "8"}, {"threads", "1"}} {
+		if err := runTestCommand(t, "config", "set", "kdf.aes."+setting[0], setting[1]); err != nil {
+			t.Fatalf("config set error = %v", err)
+		}
+	}
+}
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureStderr returns what fn prints to stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+// captureFile returns what fn writes to *file, which is replaced by a pipe
+// while fn runs
+func captureFile(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := *file
+	*file = w
+	defer func() { *file = previous }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestGenPassUseForBackup(t *testing.T) {
+	useFastKDFConfig(t)
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	output := filepath.Join(dir, "id_ed25519.enc")
+
+	var err error
+	stdout := captureStdout(t, func() {
+		err = runTestCommand(t, "genpass", "--use-for-backup", key, "-o", output)
+	})
+	if err != nil {
+		t.Fatalf("genpass --use-for-backup error = %v", err)
+	}
+
+	// The passphrase is the last line printed
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	passphrase := strings.TrimSpace(lines[len(lines)-1])
+
+	encFile, err := loadValidEncryptedFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := crypto.Decrypt(encFile, []byte(passphrase))
+	if err != nil {
+		t.Fatalf("backup does not decrypt with the printed passphrase %q: %v", passphrase, err)
+	}
+	want, _ := os.ReadFile(key)
+	if string(decrypted) != string(want) {
+		t.Error("backup does not hold the key")
+	}
+}
+
+func TestGenPassOutput(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		check func(string) bool
+	}{
+		{"words", []string{"--words", "4", "--separator", "."}, func(p string) bool {
+			return len(strings.Split(p, ".")) == 4
+		}},
+		{"length", []string{"--length", "24", "--charset", "digits"}, func(p string) bool {
+			return len(p) == 24 && strings.Trim(p, "0123456789") == ""
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			stdout := captureStdout(t, func() {
+				err = runTestCommand(t, append([]string{"genpass"}, tt.args...)...)
+			})
+			if err != nil {
+				t.Fatalf("genpass error = %v", err)
+			}
+			if passphrase := strings.TrimSuffix(stdout, "\n"); !tt.check(passphrase) {
+				t.Errorf("genpass %v printed %q", tt.args, passphrase)
+			}
+		})
+	}
+}
+
+func TestGenPassRejectsInvalidFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"words and length", []string{"--words", "6", "--length", "20"}, "cannot be used together"},
+		{"output without backup", []string{"-o", "out.enc"}, "--output requires --use-for-backup"},
+		{"stdin key", []string{"--use-for-backup", "-"}, "not stdin"},
+		{"unknown charset", []string{"--length", "20", "--charset", "emoji"}, "charset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runTestCommand(t, append([]string{"genpass"}, tt.args...)...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("genpass %v error = %v, want it to mention %q", tt.args, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenPassUseForBackupKeepsExistingOutput(t *testing.T) {
+	useFastKDFConfig(t)
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	output := filepath.Join(dir, "id_ed25519.enc")
+	if err := os.WriteFile(output, []byte("existing"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	stdout := captureStdout(t, func() {
+		err = runTestCommand(t, "genpass", "--use-for-backup", key, "-o", output)
+	})
+	if err == nil {
+		t.Fatal("genpass --use-for-backup overwrote an existing backup")
+	}
+	if strings.Contains(stdout, "Generated passphrase") {
+		t.Error("genpass printed a passphrase for a backup that was not written")
+	}
+	if data, _ := os.ReadFile(output); string(data) != "existing" {
+		t.Error("existing output was modified")
+	}
+}
@@ -1,18 +1,15 @@
 package cli
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/sshhades/sshhades/internal/config"
 	"github.com/sshhades/sshhades/internal/github"
-	"golang.org/x/term"
 )
 
 func NewGitHubCmd() *cobra.Command {
@@ -27,6 +24,8 @@ func NewGitHubCmd() *cobra.Command {
 	cmd.AddCommand(NewGitHubStatusCmd())
 	cmd.AddCommand(NewGitHubLogoutCmd())
 	cmd.AddCommand(NewGitHubReposCmd())
+	cmd.AddCommand(NewGitHubCheckCmd())
+	cmd.AddCommand(NewGitHubSyncCmd())
 
 	return cmd
 }
@@ -35,7 +34,7 @@ func NewGitHubLoginCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "login",
 		Short: "Setup GitHub authentication",
-		Long:  `Interactive wizard to setup GitHub authentication using token or SSH key.`,
+		Long:  `Interactive wizard to setup GitHub authentication using a token, an SSH key or the login of the GitHub CLI (gh).`,
 		RunE:  runGitHubLogin,
 	}
 }
@@ -67,12 +66,36 @@ func NewGitHubReposCmd() *cobra.Command {
 	}
 }
 
+func NewGitHubCheckCmd() *cobra.Command {
+	var writeTest bool
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check GitHub connectivity",
+		Long: `Validate the stored GitHub credentials, confirm the configured repository is
+reachable and check write access. Exits non-zero if any step fails, so it can
+guard automated uploads.`,
+		Example: `  # Quick health check
+  sshhades github check
+
+  # Also create and delete a small file to prove write access
+  sshhades github check --write-test`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGitHubCheck(cmd.Context(), writeTest)
+		},
+	}
+
+	cmd.Flags().BoolVar(&writeTest, "write-test", false, "Create and delete a test file in the repository")
+
+	return cmd
+}
+
 func runGitHubSetup(cmd *cobra.Command, args []string) error {
 	return runGitHubLogin(cmd, args)
 }
 
 func runGitHubLogin(cmd *cobra.Command, args []string) error {
-	github.PrintTitle("GitHub Integration Setup")
+	printTitle("GitHub Integration Setup")
 	
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -80,108 +103,176 @@ func runGitHubLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	if cfg.IsGitHubConfigured() {
-		github.PrintInfo("GitHub is already configured!")
+		printInfo("GitHub is already configured!")
 		fmt.Printf("Current setup: %s authentication as %s\n", 
 			cfg.GitHub.AuthMethod, cfg.GitHub.Username)
 		
-		fmt.Print("Do you want to reconfigure? (y/N): ")
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-		
-		if response != "y" && response != "yes" {
-			github.PrintInfo("GitHub configuration unchanged.")
+		if !confirm("Do you want to reconfigure? (y/N): ") {
+			printInfo("GitHub configuration unchanged.")
 			return nil
 		}
 	}
 
 	// Choose authentication method
-	github.PrintPrompt("Choose GitHub authentication method")
+	printPrompt("Choose GitHub authentication method")
 	fmt.Println("\n1. Personal Access Token (recommended)")
 	fmt.Println("2. SSH Key")
-	fmt.Print("\nEnter your choice (1 or 2): ")
+	fmt.Println("3. Reuse the GitHub CLI login (gh auth token)")
+	choice := readLine("\nEnter your choice (1, 2 or 3): ")
 
-	reader := bufio.NewReader(os.Stdin)
-	choice, _ := reader.ReadString('\n')
-	choice = strings.TrimSpace(choice)
+	// A GitHub Enterprise server is set beforehand with config set
+	// github.base_url and kept across logins
+	baseURL := ""
+	if previous := cfg.GetGitHubConfig(); previous != nil {
+		baseURL = previous.BaseURL
+	}
+	if baseURL != "" {
+		printInfo(fmt.Sprintf("Using GitHub Enterprise server %s", baseURL))
+	}
 
 	var githubConfig *config.GitHubConfig
 
+	ctx := cmd.Context()
 	switch choice {
 	case "1":
-		githubConfig, err = setupTokenAuth()
+		githubConfig, err = setupTokenAuth(baseURL)
 	case "2":
-		githubConfig, err = setupSSHAuth()
+		githubConfig, err = setupSSHAuth(ctx, baseURL)
+	case "3":
+		githubConfig, err = setupGHAuth(ctx, baseURL)
 	default:
-		return fmt.Errorf("invalid choice. Please enter 1 or 2")
+		return fmt.Errorf("invalid choice. Please enter 1, 2 or 3")
 	}
 
 	if err != nil {
-		github.PrintError(fmt.Sprintf("Setup failed: %v", err))
+		printError(fmt.Sprintf("Setup failed: %v", err))
 		return err
 	}
 
 	// Setup repository
-	repoName, err := setupRepository(githubConfig)
+	repoOwner, repoName, err := setupRepository(ctx, githubConfig)
 	if err != nil {
-		github.PrintError(fmt.Sprintf("Repository setup failed: %v", err))
+		printError(fmt.Sprintf("Repository setup failed: %v", err))
 		return err
 	}
 
 	githubConfig.RepoName = repoName
-	githubConfig.RepoOwner = githubConfig.Username
+	githubConfig.RepoOwner = repoOwner
+
+	// Logging in again keeps the choice of protecting the token and the
+	// commit author
+	if previous := cfg.GetGitHubConfig(); previous != nil {
+		githubConfig.ProtectToken = previous.ProtectToken
+		githubConfig.CommitAuthorName = previous.CommitAuthorName
+		githubConfig.CommitAuthorEmail = previous.CommitAuthorEmail
+		if previous.RepoOwner == githubConfig.RepoOwner && previous.RepoName == githubConfig.RepoName {
+			githubConfig.LastSyncedSHA = previous.LastSyncedSHA
+		}
+	}
 
 	// Save configuration
 	cfg.SetGitHubConfig(githubConfig)
 	if err := cfg.SaveConfig(); err != nil {
-		github.PrintError(fmt.Sprintf("Failed to save configuration: %v", err))
+		printError(fmt.Sprintf("Failed to save configuration: %v", err))
 		return err
 	}
 
-	github.PrintSuccess("GitHub integration configured successfully!")
-	github.PrintInfo(fmt.Sprintf("Repository: %s/%s", githubConfig.Username, githubConfig.RepoName))
-	github.PrintInfo("You can now use 'sshhades backup --github' to backup to GitHub")
+	printSuccess("GitHub integration configured successfully!")
+	printInfo(fmt.Sprintf("Repository: %s/%s", githubConfig.RepoOwner, githubConfig.RepoName))
+	printInfo("You can now use 'sshhades backup --github' to backup to GitHub")
 
 	return nil
 }
 
-func setupTokenAuth() (*config.GitHubConfig, error) {
-	github.PrintInfo("Setting up Personal Access Token authentication...")
-	github.PrintInfo("You need a GitHub Personal Access Token with 'repo' scope.")
-	github.PrintInfo("Create one at: https://github.com/settings/tokens")
-	
-	fmt.Print("\nEnter your GitHub Personal Access Token: ")
-	
-	// Hide token input
-	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read token: %w", err)
-	}
-	
-	token := string(bytePassword)
-	fmt.Println() // New line after hidden input
+// tokenAttempts is how often login asks for the token before giving up
+const tokenAttempts = 3
+
+func setupTokenAuth(baseURL string) (*config.GitHubConfig, error) {
+	printInfo("Setting up Personal Access Token authentication...")
+	printInfo("You need a GitHub Personal Access Token with 'repo' scope.")
+	printInfo(fmt.Sprintf("Create one at: %s/settings/tokens", github.WebURL(baseURL)))
 	
-	if token == "" {
-		return nil, fmt.Errorf("token cannot be empty")
+	fmt.Println()
+
+	// A mistyped or mispasted token can be entered again at the terminal;
+	// a token piped in with --passphrase-stdin gets a single try
+	attempts := 1
+	if stdinIsTerminal() {
+		attempts = tokenAttempts
 	}
 
-	github.PrintInfo("Validating token...")
-	user, err := github.ValidateToken(token)
-	if err != nil {
-		return nil, fmt.Errorf("token validation failed: %w", err)
+	var token, login string
+	for attempt := 1; ; attempt++ {
+		// Hide token input
+		bytePassword, err := readSecret("Enter your GitHub Personal Access Token: ", "set "+config.EnvGitHubToken+" instead of running login")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		token = string(bytePassword)
+
+		if token == "" {
+			err = fmt.Errorf("token cannot be empty")
+		} else {
+			printInfo("Validating token...")
+			if user, validateErr := github.ValidateToken(token, baseURL); validateErr != nil {
+				err = fmt.Errorf("token validation failed: %w", validateErr)
+			} else {
+				login = user.GetLogin()
+			}
+		}
+		if err == nil {
+			break
+		}
+		if attempt == attempts {
+			return nil, err
+		}
+		printError(fmt.Sprintf("%v (attempt %d of %d)", err, attempt, attempts))
+		fmt.Println("Paste the token again, checking it has not expired and has the 'repo' scope.")
 	}
 
-	github.PrintSuccess(fmt.Sprintf("Token validated! Logged in as: %s", user.GetLogin()))
+	printSuccess(fmt.Sprintf("Token validated! Logged in as: %s", login))
 
 	return &config.GitHubConfig{
 		Token:      token,
-		Username:   user.GetLogin(),
+		Username:   login,
 		AuthMethod: "token",
+		BaseURL:    baseURL,
 	}, nil
 }
 
-func setupSSHAuth() (*config.GitHubConfig, error) {
-	github.PrintInfo("Setting up SSH Key authentication...")
+// setupGHAuth reuses the token of the GitHub CLI. The config records gh as
+// the token source, so the token is read from gh whenever it is needed and
+// follows gh's own logins. Without a usable gh login it falls back to
+// asking for a Personal Access Token.
+func setupGHAuth(ctx context.Context, baseURL string) (*config.GitHubConfig, error) {
+	printInfo("Reading the token of the GitHub CLI...")
+
+	token, err := config.GHToken(ctx, baseURL)
+	if err == nil {
+		printInfo("Validating token...")
+		user, validateErr := github.ValidateToken(token, baseURL)
+		if validateErr == nil {
+			printSuccess(fmt.Sprintf("Token validated! Logged in as: %s", user.GetLogin()))
+			printInfo("The token stays with gh and is read from it each time; it is not stored in the sshhades config.")
+			return &config.GitHubConfig{
+				Token:       token,
+				Username:    user.GetLogin(),
+				AuthMethod:  "token",
+				TokenSource: config.TokenSourceGH,
+				BaseURL:     baseURL,
+			}, nil
+		}
+		err = fmt.Errorf("token validation failed: %w", validateErr)
+	}
+
+	printWarning(fmt.Sprintf("Cannot use the GitHub CLI login: %v", err))
+	printInfo("Falling back to a Personal Access Token.")
+	fmt.Println()
+	return setupTokenAuth(baseURL)
+}
+
+func setupSSHAuth(ctx context.Context, baseURL string) (*config.GitHubConfig, error) {
+	printInfo("Setting up SSH Key authentication...")
 	
 	// Find available SSH keys
 	sshKeys, err := github.FindSSHKeys()
@@ -199,10 +290,7 @@ func setupSSHAuth() (*config.GitHubConfig, error) {
 		fmt.Printf("%d. %s\n", i+1, key)
 	}
 
-	fmt.Printf("\nSelect SSH key (1-%d): ", len(sshKeys))
-	reader := bufio.NewReader(os.Stdin)
-	choice, _ := reader.ReadString('\n')
-	choice = strings.TrimSpace(choice)
+	choice := readLine(fmt.Sprintf("\nSelect SSH key (1-%d): ", len(sshKeys)))
 
 	keyIndex, err := strconv.Atoi(choice)
 	if err != nil || keyIndex < 1 || keyIndex > len(sshKeys) {
@@ -211,47 +299,51 @@ func setupSSHAuth() (*config.GitHubConfig, error) {
 
 	selectedKey := sshKeys[keyIndex-1]
 
-	github.PrintInfo(fmt.Sprintf("Testing SSH connection with key: %s", selectedKey))
+	printInfo(fmt.Sprintf("Testing SSH connection with key: %s", selectedKey))
 	
 	// Test SSH connection
-	if err := github.TestSSHConnection(selectedKey); err != nil {
-		github.PrintError("SSH connection test failed!")
-		github.PrintInfo("Make sure your SSH key is added to your GitHub account:")
-		github.PrintInfo("https://github.com/settings/ssh/new")
+	if err := github.TestSSHConnection(ctx, selectedKey, baseURL); err != nil {
+		printError("SSH connection test failed!")
+		printInfo("Make sure your SSH key is added to your GitHub account:")
+		printInfo(github.WebURL(baseURL) + "/settings/ssh/new")
 		return nil, err
 	}
 
 	// Get username from SSH
-	username, err := github.GetGitHubUsername("ssh", "", selectedKey)
+	username, err := github.GetGitHubUsername(ctx, "ssh", "", selectedKey, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get GitHub username: %w", err)
 	}
 
-	github.PrintSuccess(fmt.Sprintf("SSH authentication successful! Logged in as: %s", username))
+	printSuccess(fmt.Sprintf("SSH authentication successful! Logged in as: %s", username))
 
 	return &config.GitHubConfig{
 		Username:   username,
 		AuthMethod: "ssh",
 		SSHKeyPath: selectedKey,
+		BaseURL:    baseURL,
 	}, nil
 }
 
-func setupRepository(githubConfig *config.GitHubConfig) (string, error) {
-	github.PrintInfo("Setting up backup repository...")
+// setupRepository asks for the backup repository and returns its owner and
+// name. The repository may belong to an organization: it can be entered as
+// owner/name, and with token authentication a name not found under the
+// user's account prompts for the organization. The owner is resolved
+// through the API, so the stored value always matches GitHub.
+func setupRepository(ctx context.Context, githubConfig *config.GitHubConfig) (string, string, error) {
+	printInfo("Setting up backup repository...")
 
 	// Create authenticated client
 	client, err := github.NewAuthenticatedClient(githubConfig)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GitHub client: %w", err)
+		return "", "", fmt.Errorf("failed to create GitHub client: %w", err)
 	}
 
-	ctx := context.Background()
-
 	// List existing repositories
 	if githubConfig.AuthMethod == "token" {
 		repos, err := client.ListRepositories(ctx)
 		if err != nil {
-			github.PrintError("Failed to list repositories, but continuing...")
+			printError("Failed to list repositories, but continuing...")
 		} else {
 			fmt.Println("\nYour existing repositories:")
 			for i, repo := range repos {
@@ -263,44 +355,74 @@ func setupRepository(githubConfig *config.GitHubConfig) (string, error) {
 				if repo.GetPrivate() {
 					privacy = "private"
 				}
-				fmt.Printf("  - %s (%s)\n", repo.GetName(), privacy)
+				// Organization repositories are shown with their owner
+				name := repo.GetName()
+				if !strings.EqualFold(repo.GetOwner().GetLogin(), githubConfig.Username) {
+					name = repo.GetFullName()
+				}
+				fmt.Printf("  - %s (%s)\n", name, privacy)
 			}
 		}
 	}
 
-	fmt.Print("\nEnter repository name for backups (default: ssh-keys-backup): ")
-	reader := bufio.NewReader(os.Stdin)
-	repoName, _ := reader.ReadString('\n')
-	repoName = strings.TrimSpace(repoName)
+	repoName := readLine("\nEnter repository name for backups, or owner/name for an organization repository (default: ssh-keys-backup): ")
 
 	if repoName == "" {
 		repoName = "ssh-keys-backup"
 	}
 
-	// Check if repository exists (only for token auth)
-	if githubConfig.AuthMethod == "token" {
-		_, err := client.GetRepository(ctx, githubConfig.Username, repoName)
-		if err == nil {
-			github.PrintInfo(fmt.Sprintf("Repository '%s' already exists. Using existing repository.", repoName))
-			return repoName, nil
+	owner := githubConfig.Username
+	if o, name, ok := strings.Cut(repoName, "/"); ok {
+		if o == "" || name == "" || strings.Contains(name, "/") {
+			return "", "", fmt.Errorf("invalid repository %q: use name or owner/name", repoName)
 		}
+		owner, repoName = o, name
+	}
 
-		// Ask if user wants to create the repository
-		fmt.Print("Repository doesn't exist. Create it? (Y/n): ")
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
+	// The owner cannot be checked without the API
+	if githubConfig.AuthMethod != "token" {
+		return owner, repoName, nil
+	}
 
-		if response == "" || response == "y" || response == "yes" {
-			github.PrintInfo("Creating repository...")
-			_, err := client.CreateRepository(ctx, repoName, "SSH Keys Backup Repository", true)
-			if err != nil {
-				return "", fmt.Errorf("failed to create repository: %w", err)
-			}
-			github.PrintSuccess(fmt.Sprintf("Repository '%s' created successfully!", repoName))
+	// Check if repository exists
+	repo, err := client.GetRepository(ctx, owner, repoName)
+	if err != nil && strings.EqualFold(owner, githubConfig.Username) {
+		fmt.Printf("Repository '%s' was not found under %s.\n", repoName, owner)
+		if org := readLine("Organization that owns it (leave empty to use your account): "); org != "" {
+			owner = org
+			repo, err = client.GetRepository(ctx, owner, repoName)
 		}
 	}
+	if err == nil {
+		// Use the owner as GitHub spells it
+		owner = repo.GetOwner().GetLogin()
+		printInfo(fmt.Sprintf("Repository '%s/%s' already exists. Using existing repository.", owner, repoName))
+		return owner, repoName, nil
+	}
+
+	// Ask if user wants to create the repository
+	prompt := fmt.Sprintf("Repository %s/%s doesn't exist. Create it? (Y/n): ", owner, repoName)
+	response := "yes"
+	if !assumedYes(prompt) {
+		response = strings.ToLower(readLine(prompt))
+	}
 
-	return repoName, nil
+	if response == "" || response == "y" || response == "yes" {
+		org := ""
+		if !strings.EqualFold(owner, githubConfig.Username) {
+			org = owner
+		}
+
+		printInfo("Creating repository...")
+		created, err := client.CreateRepository(ctx, org, repoName, "SSH Keys Backup Repository", true)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create repository: %w", err)
+		}
+		owner = created.GetOwner().GetLogin()
+		printSuccess(fmt.Sprintf("Repository '%s/%s' created successfully!", owner, repoName))
+	}
+
+	return owner, repoName, nil
 }
 
 func runGitHubStatus(cmd *cobra.Command, args []string) error {
@@ -309,19 +431,28 @@ func runGitHubStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	github.PrintTitle("GitHub Integration Status")
+	printTitle("GitHub Integration Status")
 
 	if !cfg.IsGitHubConfigured() {
-		github.PrintError("GitHub is not configured")
-		github.PrintInfo("Run 'sshhades github login' to setup GitHub integration")
+		printError("GitHub is not configured")
+		if err := cfg.GHTokenError(); err != nil {
+			printInfo(fmt.Sprintf("The GitHub CLI did not provide a token: %v", err))
+		}
+		printInfo("Run 'sshhades github login' to setup GitHub integration")
 		return nil
 	}
 
 	githubCfg := cfg.GetGitHubConfig()
 	
-	github.PrintSuccess("GitHub is configured")
+	printSuccess("GitHub is configured")
 	fmt.Printf("  Username: %s\n", githubCfg.Username)
 	fmt.Printf("  Authentication: %s\n", githubCfg.AuthMethod)
+	if githubCfg.TokenSource == config.TokenSourceGH {
+		fmt.Printf("  Token source: GitHub CLI (gh auth token)\n")
+	}
+	if githubCfg.BaseURL != "" {
+		fmt.Printf("  Server: %s (GitHub Enterprise)\n", githubCfg.BaseURL)
+	}
 	
 	if githubCfg.AuthMethod == "ssh" {
 		fmt.Printf("  SSH Key: %s\n", githubCfg.SSHKeyPath)
@@ -331,6 +462,10 @@ func runGitHubStatus(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Repository: %s/%s\n", githubCfg.RepoOwner, githubCfg.RepoName)
 	}
 
+	if cfg.GitHubFromEnv() {
+		fmt.Printf("  Source: SSHHADES_GITHUB_* environment variables override the config file\n")
+	}
+
 	return nil
 }
 
@@ -341,26 +476,25 @@ func runGitHubLogout(cmd *cobra.Command, args []string) error {
 	}
 
 	if !cfg.IsGitHubConfigured() {
-		github.PrintInfo("GitHub is not configured")
+		printInfo("GitHub is not configured")
 		return nil
 	}
 
-	fmt.Print("Are you sure you want to remove GitHub configuration? (y/N): ")
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response != "y" && response != "yes" {
-		github.PrintInfo("GitHub configuration unchanged")
+	if !confirm("Are you sure you want to remove GitHub configuration? (y/N): ") {
+		printInfo("GitHub configuration unchanged")
 		return nil
 	}
 
+	fromEnv := cfg.GitHubFromEnv()
 	cfg.SetGitHubConfig(nil)
 	if err := cfg.SaveConfig(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	github.PrintSuccess("GitHub configuration removed")
+	printSuccess("GitHub configuration removed")
+	if fromEnv {
+		printWarning("SSHHADES_GITHUB_* environment variables are still set and keep GitHub configured")
+	}
 	return nil
 }
 
@@ -371,16 +505,16 @@ func runGitHubRepos(cmd *cobra.Command, args []string) error {
 	}
 
 	if !cfg.IsGitHubConfigured() {
-		github.PrintError("GitHub is not configured")
-		github.PrintInfo("Run 'sshhades github login' to setup GitHub integration")
+		printError("GitHub is not configured")
+		printInfo("Run 'sshhades github login' to setup GitHub integration")
 		return nil
 	}
 
 	githubCfg := cfg.GetGitHubConfig()
 	
 	if githubCfg.AuthMethod != "token" {
-		github.PrintError("Repository management requires token authentication")
-		github.PrintInfo("SSH authentication doesn't support repository listing via API")
+		printError("Repository management requires token authentication")
+		printInfo("SSH authentication doesn't support repository listing via API")
 		return nil
 	}
 
@@ -389,16 +523,15 @@ func runGitHubRepos(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create GitHub client: %w", err)
 	}
 
-	github.PrintTitle("Your GitHub Repositories")
+	printTitle("Your GitHub Repositories")
 
-	ctx := context.Background()
-	repos, err := client.ListRepositories(ctx)
+	repos, err := client.ListRepositories(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("failed to list repositories: %w", err)
 	}
 
 	if len(repos) == 0 {
-		github.PrintInfo("No repositories found")
+		printInfo("No repositories found")
 		return nil
 	}
 
@@ -420,4 +553,86 @@ func runGitHubRepos(cmd *cobra.Command, args []string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// githubCheckPath is the file created and removed by github check --write-test
+const githubCheckPath = github.DefaultBackupDir + "/.sshhades-check"
+
+func runGitHubCheck(ctx context.Context, writeTest bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	printTitle("GitHub Connectivity Check")
+
+	if !cfg.IsGitHubConfigured() {
+		printError("GitHub is not configured")
+		printInfo("Run 'sshhades github login' to setup GitHub integration")
+		return fmt.Errorf("GitHub is not configured")
+	}
+
+	githubCfg := cfg.GetGitHubConfig()
+	failed := 0
+	report := func(step string, err error) bool {
+		if err != nil {
+			printError(fmt.Sprintf("%s: %v", step, err))
+			failed++
+			return false
+		}
+		printSuccess(step)
+		return true
+	}
+
+	// Step 1: credentials
+	switch githubCfg.AuthMethod {
+	case "token":
+		_, err = github.ValidateToken(githubCfg.Token, githubCfg.BaseURL)
+	case "ssh":
+		err = github.TestSSHConnection(ctx, githubCfg.SSHKeyPath, githubCfg.BaseURL)
+	default:
+		err = fmt.Errorf("unsupported authentication method: %s", githubCfg.AuthMethod)
+	}
+	report(fmt.Sprintf("Credentials (%s)", githubCfg.AuthMethod), err)
+
+	// Step 2: repository
+	if githubCfg.RepoName == "" {
+		report("Repository", fmt.Errorf("no repository configured"))
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	client, err := github.NewAuthenticatedClient(githubCfg)
+	if !report("API client", err) {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	repoName := fmt.Sprintf("%s/%s", githubCfg.RepoOwner, githubCfg.RepoName)
+	repo, err := client.GetRepository(ctx, githubCfg.RepoOwner, githubCfg.RepoName)
+	if report(fmt.Sprintf("Repository %s reachable", repoName), err) {
+		// Step 3: write access
+		if githubCfg.AuthMethod != "token" {
+			printInfo("Write access: skipped (API writes require token authentication)")
+		} else if writeTest {
+			err = client.UploadFile(ctx, githubCfg.RepoOwner, githubCfg.RepoName, githubCheckPath, []byte("sshhades connectivity check\n"), "sshhades: connectivity check")
+			if err == nil {
+				err = client.DeleteFile(ctx, githubCfg.RepoOwner, githubCfg.RepoName, githubCheckPath, "sshhades: remove connectivity check")
+			}
+			report("Write access (test file created and removed)", err)
+		} else if !repo.GetPermissions()["push"] {
+			report("Write access", fmt.Errorf("credentials do not grant push permission (use --write-test to try a real write)"))
+		} else {
+			report("Write access (push permission)", nil)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	fmt.Println()
+	printSuccess("GitHub is ready for automated backups")
+	return nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/config"
+)
+
+// feedStdin makes every prompt read its answers from input for the rest of
+// the test
+func feedStdin(t *testing.T, input string) {
+	t.Helper()
+
+	previous := stdinReader
+	t.Cleanup(func() { stdinReader = previous })
+	stdinReader = bufio.NewReader(strings.NewReader(input))
+}
+
+// configureGitHub saves a token login in a temporary config
+func configureGitHub(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetGitHubConfig(&config.GitHubConfig{AuthMethod: "token", Token: "ghp_test", Username: "octocat", RepoName: "ssh-keys-backup"})
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitHubLoginReadsSharedStdin(t *testing.T) {
+	configureGitHub(t)
+
+	// Both answers arrive in one read; the second prompt must still see its
+	// line rather than losing it to a reader of its own
+	feedStdin(t, "y\n9\n")
+	err := runTestCommand(t, "github", "login")
+	if err == nil || !strings.Contains(err.Error(), "invalid choice") {
+		t.Errorf("github login error = %v, want the choice 9 to be read and rejected", err)
+	}
+}
+
+func TestPromptsShareStdin(t *testing.T) {
+	if stdinIsTerminal() {
+		t.Skip("readSecret reads the terminal itself")
+	}
+
+	previous := passphraseStdin
+	t.Cleanup(func() { passphraseStdin = previous })
+	passphraseStdin = true
+
+	feedStdin(t, "1\nghp_token\nrepo\n")
+	if choice := readLine("choice: "); choice != "1" {
+		t.Errorf("readLine() = %q, want 1", choice)
+	}
+	token, err := readSecret("token: ", "")
+	if err != nil || string(token) != "ghp_token" {
+		t.Errorf("readSecret() = %q, %v, want ghp_token", token, err)
+	}
+	if repo := readLine("repo: "); repo != "repo" {
+		t.Errorf("readLine() = %q, want repo", repo)
+	}
+}
+
+func TestAssumedYesLogsToStderr(t *testing.T) {
+	useFastKDFConfig(t)
+
+	var err error
+	var stdout string
+	stderr := captureStderr(t, func() {
+		stdout = captureStdout(t, func() {
+			err = runTestCommand(t, "config", "reset", "--yes")
+		})
+	})
+	if err != nil {
+		t.Fatalf("config reset --yes error = %v", err)
+	}
+	if !strings.Contains(stderr, "yes (assumed by --yes)") {
+		t.Errorf("auto-confirmation not logged to stderr:\n%s", stderr)
+	}
+	if strings.Contains(stdout, "assumed by --yes") {
+		t.Errorf("auto-confirmation printed to stdout:\n%s", stdout)
+	}
+}
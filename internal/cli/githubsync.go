@@ -0,0 +1,423 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/github"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+type githubSyncFlags struct {
+	dir         string
+	pull        bool
+	apply       bool
+	keepGoing   bool
+	since       string
+	concurrency int
+}
+
+// defaultSyncConcurrency and maxSyncConcurrency bound --concurrency, so a
+// large directory does not trip GitHub's secondary rate limits
+const (
+	defaultSyncConcurrency = 3
+	maxSyncConcurrency     = 10
+)
+
+// errSyncSkipped marks transfers not started because an earlier one failed
+var errSyncSkipped = errors.New("not started after an earlier failure")
+
+// Kinds of step in a sync plan
+const (
+	syncUpload     = "upload"
+	syncUpdate     = "update"
+	syncDownload   = "download"
+	syncRemoteOnly = "remote-only"
+)
+
+// syncStep is one file a sync would transfer, or leaves alone because it
+// only exists remotely and --pull was not given
+type syncStep struct {
+	name string
+	kind string
+}
+
+func NewGitHubSyncCmd() *cobra.Command {
+	flags := &githubSyncFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Reconcile a backup directory with the GitHub repository",
+		Long: `Compare the .enc files in a local directory with the ssh-keys/ directory of the
+configured repository, by name and content hash. Local files that are missing
+or different remotely are uploaded; with --pull, files that only exist
+remotely are downloaded. A file present on both sides with different content
+is always resolved in favour of the local copy.
+
+With --since-commit, only remote files added or changed by commits since the
+given commit SHA or date are downloaded, found from the commit history of
+ssh-keys/ rather than by comparing every file. Each successful --pull records
+the latest repository commit, which --since-commit last starts from.
+
+The plan is printed first and nothing changes until --apply is given. Up to
+--concurrency transfers run at once; uploads GitHub rejects because of a
+concurrent commit or a secondary rate limit are retried with a backoff, and
+the results are reported in plan order.`,
+		Example: `  # Show what would be transferred
+  sshhades github sync --dir ~/backups
+
+  # Upload missing and changed backups, and fetch remote-only ones
+  sshhades github sync --dir ~/backups --pull --apply
+
+  # Only fetch backups changed since the previous pull
+  sshhades github sync --dir ~/backups --pull --since-commit last --apply`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGitHubSync(cmd.Context(), flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.dir, "dir", "", "Local backup directory (required)")
+	cmd.Flags().BoolVar(&flags.pull, "pull", false, "Also download backups that only exist in the repository")
+	cmd.Flags().BoolVar(&flags.apply, "apply", false, "Carry out the plan instead of only printing it")
+	cmd.Flags().StringVar(&flags.since, "since-commit", "", "With --pull, only download files changed since this commit SHA, date (2006-01-02 or RFC 3339) or \"last\" synced commit")
+	cmd.Flags().BoolVar(&flags.keepGoing, "keep-going", false, "Continue past failed transfers and report them at the end")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", defaultSyncConcurrency, fmt.Sprintf("Number of files transferred at once (1-%d)", maxSyncConcurrency))
+	cmd.MarkFlagRequired("dir")
+
+	return cmd
+}
+
+func runGitHubSync(ctx context.Context, flags *githubSyncFlags) error {
+	if err := storage.ValidatePath(flags.dir); err != nil {
+		return fmt.Errorf("invalid directory path: %w", err)
+	}
+	if flags.since != "" && !flags.pull {
+		return fmt.Errorf("--since-commit requires --pull")
+	}
+	if flags.concurrency < 1 || flags.concurrency > maxSyncConcurrency {
+		return fmt.Errorf("--concurrency must be between 1 and %d", maxSyncConcurrency)
+	}
+
+	local, err := readLocalBackups(flags.dir)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	sink, err := github.NewSink(cfg, "")
+	if err != nil {
+		return err
+	}
+	sink.Context = ctx
+
+	githubCfg := cfg.GetGitHubConfig()
+
+	// The head is read before the listing, so anything committed during
+	// the sync is picked up by the next --since-commit last
+	var head string
+	if flags.pull {
+		head, err = sink.Head()
+		if err != nil {
+			fmt.Printf("⚠️  Warning: %v; this sync will not be recorded for --since-commit last\n", github.RedactError(err))
+		}
+	}
+
+	remoteFiles, err := sink.List()
+	if err != nil {
+		return err
+	}
+	remote := make(map[string]string)
+	for _, file := range remoteFiles {
+		if strings.HasSuffix(file.Name, ".enc") {
+			remote[file.Name] = file.SHA
+		}
+	}
+
+	unchanged := 0
+	if flags.since != "" {
+		since, err := resolveSyncSince(flags.since, sink, githubCfg)
+		if err != nil {
+			return err
+		}
+		changed, err := sink.ChangedSince(since)
+		if err != nil {
+			return err
+		}
+		// Remote-only files nobody touched since then are left alone
+		for name := range remote {
+			if _, isLocal := local[name]; !isLocal && !changed[name] {
+				delete(remote, name)
+				unchanged++
+			}
+		}
+	}
+
+	steps, inSync := planSync(local, remote, flags.pull)
+
+	fmt.Printf("Sync plan for %s <-> %s/%s/%s:\n", flags.dir, githubCfg.RepoOwner, githubCfg.RepoName, sink.Dir)
+	for _, step := range steps {
+		switch step.kind {
+		case syncUpload:
+			fmt.Printf("  + upload    %s (missing remotely)\n", step.name)
+		case syncUpdate:
+			fmt.Printf("  ~ upload    %s (changed locally)\n", step.name)
+		case syncDownload:
+			fmt.Printf("  - download  %s\n", step.name)
+		case syncRemoteOnly:
+			fmt.Printf("  ? remote    %s (only in the repository; use --pull to download)\n", step.name)
+		}
+	}
+	fmt.Printf("  %d file(s) already in sync\n", inSync)
+	if flags.since != "" {
+		fmt.Printf("  %d remote-only file(s) unchanged since %s, skipped\n", unchanged, flags.since)
+	}
+
+	var transfers []syncStep
+	for _, step := range steps {
+		if step.kind != syncRemoteOnly {
+			transfers = append(transfers, step)
+		}
+	}
+	if len(transfers) == 0 {
+		fmt.Println("\n✓ Nothing to transfer")
+		return recordSync(cfg, head)
+	}
+	if !flags.apply {
+		fmt.Println("\nRun again with --apply to carry out this plan.")
+		return nil
+	}
+
+	fmt.Printf("\nTransferring %d file(s), up to %d at a time...\n", len(transfers), flags.concurrency)
+	localSink := storage.NewLocalSink(flags.dir)
+	localSink.Context = ctx
+	errs := transferAll(transfers, flags.concurrency, !flags.keepGoing, func(step syncStep) error {
+		if step.kind == syncDownload {
+			return pullBackup(sink, localSink, flags.dir, step.name)
+		}
+		return sink.Write(step.name, local[step.name])
+	})
+
+	// Report in plan order, whichever transfer finished first: successes,
+	// then failures, so a run without --keep-going still lists every file
+	// that did make it
+	skipped := 0
+	for i, step := range transfers {
+		switch errs[i] {
+		case nil:
+			fmt.Printf("✓ %s %s\n", step.kind, step.name)
+		case errSyncSkipped:
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("%d transfer(s) not started after a failure\n", skipped)
+	}
+	run := &batchRun{keepGoing: flags.keepGoing}
+	for i, step := range transfers {
+		if errs[i] != nil && errs[i] != errSyncSkipped {
+			if err := run.fail(step.name, errs[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := run.finish(len(transfers)); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ Synced %d file(s)\n", len(transfers))
+	return recordSync(cfg, head)
+}
+
+// transferAll runs transfer for each step, at most limit at a time, and
+// returns the error of each step in the order of steps. Steps for the same
+// file name run one after another in plan order, never at the same time.
+// With stop, steps not yet started once any step fails are left out and
+// get errSyncSkipped.
+func transferAll(steps []syncStep, limit int, stop bool, transfer func(syncStep) error) []error {
+	errs := make([]error, len(steps))
+
+	// One group per name, in order of first appearance
+	var names []string
+	groups := make(map[string][]int)
+	for i, step := range steps {
+		if _, ok := groups[step.name]; !ok {
+			names = append(names, step.name)
+		}
+		groups[step.name] = append(groups[step.name], i)
+	}
+
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, limit)
+	for _, name := range names {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(indices []int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			for _, i := range indices {
+				if stop && failed.Load() {
+					errs[i] = errSyncSkipped
+					continue
+				}
+				if errs[i] = transfer(steps[i]); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}(groups[name])
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// shaPattern matches full and abbreviated commit SHAs
+var shaPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// resolveSyncSince turns --since-commit into the time from which the
+// history of the backup directory is read. A commit SHA stands for the time
+// it was committed, so files it changed are included again.
+func resolveSyncSince(value string, sink *github.Sink, githubCfg *config.GitHubConfig) (time.Time, error) {
+	if value == "last" {
+		if githubCfg.LastSyncedSHA == "" {
+			return time.Time{}, fmt.Errorf("no earlier sync is recorded; pass a commit SHA or date to --since-commit")
+		}
+		value = githubCfg.LastSyncedSHA
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	if !shaPattern.MatchString(value) {
+		return time.Time{}, fmt.Errorf("invalid --since-commit: %s (use a commit SHA, a date such as 2024-06-01, or last)", value)
+	}
+	return sink.CommitTime(value)
+}
+
+// recordSync stores head as the last synced commit. It is a no-op when the
+// head is unknown, e.g. without --pull.
+func recordSync(cfg *config.Config, head string) error {
+	if head == "" || cfg.GitHub.LastSyncedSHA == head {
+		return nil
+	}
+	cfg.GitHub.LastSyncedSHA = head
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("failed to record the synced commit: %w", err)
+	}
+	return nil
+}
+
+// readLocalBackups reads the valid backups in dir, keyed by file name.
+// Files that are not valid backups are skipped with a warning, so a stray
+// file is never uploaded.
+func readLocalBackups(dir string) (map[string][]byte, error) {
+	paths, err := findBackupFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for encrypted files: %w", err)
+	}
+
+	local := make(map[string][]byte)
+	for _, file := range paths {
+		data, err := storage.ReadFileLimited(file, storage.MaxEncryptedFileSize, "encrypted file")
+		if err != nil {
+			return nil, err
+		}
+		if err := validateBackupData(data); err != nil {
+			fmt.Printf("⚠️  Skipping %s: %v\n", file, err)
+			continue
+		}
+		local[filepath.Base(file)] = data
+	}
+	return local, nil
+}
+
+// validateBackupData checks that data is a backup this build can read
+func validateBackupData(data []byte) error {
+	encFile, err := format.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse encrypted file: %w", err)
+	}
+	return crypto.ValidateEncryptedFile(encFile)
+}
+
+// planSync compares local file contents with remote blob hashes and returns
+// the steps in name order, along with how many files already match
+func planSync(local map[string][]byte, remote map[string]string, pull bool) ([]syncStep, int) {
+	names := make(map[string]bool)
+	for name := range local {
+		names[name] = true
+	}
+	for name := range remote {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var steps []syncStep
+	inSync := 0
+	for _, name := range sorted {
+		data, isLocal := local[name]
+		sha, isRemote := remote[name]
+
+		switch {
+		case isLocal && !isRemote:
+			steps = append(steps, syncStep{name: name, kind: syncUpload})
+		case isLocal && github.BlobSHA(data) != sha:
+			steps = append(steps, syncStep{name: name, kind: syncUpdate})
+		case isLocal:
+			inSync++
+		case pull:
+			steps = append(steps, syncStep{name: name, kind: syncDownload})
+		default:
+			steps = append(steps, syncStep{name: name, kind: syncRemoteOnly})
+		}
+	}
+	return steps, inSync
+}
+
+// pullBackup downloads a remote-only backup into dir after checking that
+// it is a valid backup. An invalid local file of the same name, skipped
+// when planning, is never overwritten.
+func pullBackup(sink *github.Sink, localSink *storage.LocalSink, dir, name string) error {
+	if name != filepath.Base(name) {
+		return fmt.Errorf("refusing remote file name %q", name)
+	}
+	if storage.FileExists(filepath.Join(dir, name)) {
+		return fmt.Errorf("a local file with this name exists but is not a valid backup")
+	}
+
+	data, err := sink.Read(name)
+	if err != nil {
+		return err
+	}
+	if err := validateBackupData(data); err != nil {
+		return fmt.Errorf("remote file is not a valid backup: %w", err)
+	}
+
+	return localSink.Write(name, data)
+}
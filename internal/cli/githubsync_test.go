@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransferAllBoundsConcurrency(t *testing.T) {
+	var steps []syncStep
+	for i := 0; i < 12; i++ {
+		steps = append(steps, syncStep{name: fmt.Sprintf("key%d.enc", i), kind: syncUpload})
+	}
+
+	var running, peak atomic.Int32
+	errs := transferAll(steps, 3, true, func(step syncStep) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+
+	if p := peak.Load(); p > 3 {
+		t.Errorf("%d transfers ran at once, want at most 3", p)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("step %d error = %v", i, err)
+		}
+	}
+}
+
+func TestTransferAllSerializesSameName(t *testing.T) {
+	steps := []syncStep{
+		{name: "a.enc", kind: syncUpload},
+		{name: "b.enc", kind: syncUpload},
+		{name: "a.enc", kind: syncUpdate},
+		{name: "a.enc", kind: syncDownload},
+	}
+
+	var mu sync.Mutex
+	var order []string
+	busy := make(map[string]bool)
+	transferAll(steps, 4, true, func(step syncStep) error {
+		mu.Lock()
+		if busy[step.name] {
+			t.Errorf("two transfers of %s ran at once", step.name)
+		}
+		busy[step.name] = true
+		if step.name == "a.enc" {
+			order = append(order, step.kind)
+		}
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		busy[step.name] = false
+		mu.Unlock()
+		return nil
+	})
+
+	if fmt.Sprint(order) != fmt.Sprint([]string{syncUpload, syncUpdate, syncDownload}) {
+		t.Errorf("a.enc transferred in order %v, want plan order", order)
+	}
+}
+
+func TestTransferAllStopsAfterFailure(t *testing.T) {
+	steps := []syncStep{
+		{name: "bad.enc", kind: syncUpload},
+		{name: "next.enc", kind: syncUpload},
+	}
+	failure := errors.New("upload failed")
+
+	transfer := func(step syncStep) error {
+		if step.name == "bad.enc" {
+			return failure
+		}
+		return nil
+	}
+
+	// One at a time, the failure is known before the next step starts
+	errs := transferAll(steps, 1, true, transfer)
+	if errs[0] != failure || errs[1] != errSyncSkipped {
+		t.Errorf("with stop: errors = %v, want the failure and a skipped step", errs)
+	}
+
+	errs = transferAll(steps, 1, false, transfer)
+	if errs[0] != failure || errs[1] != nil {
+		t.Errorf("without stop: errors = %v, want the failure and a transferred step", errs)
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/config"
+)
+
+// postHook is the --post-hook command run after each successful backup or
+// restore, with details passed in SSHHADES_HOOK_* environment variables
+type postHook struct {
+	command string
+	strict  bool
+}
+
+// addPostHookFlags registers --post-hook and --strict-hook on cmd
+func addPostHookFlags(cmd *cobra.Command, hook *postHook) {
+	cmd.Flags().StringVar(&hook.command, "post-hook", "", "Shell command to run after each successful operation, with details in SSHHADES_HOOK_* variables")
+	cmd.Flags().BoolVar(&hook.strict, "strict-hook", false, "Fail the operation when --post-hook fails instead of only reporting it")
+}
+
+// hookEvent describes a completed operation to the hook. It never holds
+// the passphrase or key material, only what is safe to log.
+type hookEvent struct {
+	operation   string
+	path        string
+	fingerprint string
+	targets     []string
+}
+
+// run executes the hook for event, stopping it when ctx ends. A failing
+// hook is reported as a warning unless --strict-hook is set, since the
+// operation itself has succeeded.
+func (h *postHook) run(ctx context.Context, event hookEvent, passphraseEnv string) error {
+	if h == nil || h.command == "" {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.command)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(hookEnviron(passphraseEnv),
+		"SSHHADES_HOOK_OPERATION="+event.operation,
+		"SSHHADES_HOOK_STATUS=success",
+		"SSHHADES_HOOK_PATH="+event.path,
+		"SSHHADES_HOOK_FINGERPRINT="+event.fingerprint,
+		"SSHHADES_HOOK_TARGETS="+strings.Join(event.targets, ","),
+	)
+
+	if err := cmd.Run(); err != nil {
+		if err := checkDeadline(ctx); err != nil {
+			return err
+		}
+		if h.strict {
+			return fmt.Errorf("post-hook failed: %w", err)
+		}
+		fmt.Printf("⚠️  Warning: post-hook failed: %v\n", err)
+		return nil
+	}
+	return nil
+}
+
+// hookEnviron returns the environment for the hook without the variables
+// that hold secrets: the --passphrase-env variable and the GitHub token
+func hookEnviron(passphraseEnv string) []string {
+	secret := map[string]bool{config.EnvGitHubToken: true}
+	if passphraseEnv != "" {
+		secret[passphraseEnv] = true
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !secret[name] {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+type identifyFlags struct {
+	input        string
+	outputFormat string
+	json         bool
+}
+
+// identifyResult is the text, JSON and YAML rendering of identify
+type identifyResult struct {
+	Path       string   `json:"path" yaml:"path"`
+	Kind       string   `json:"kind" yaml:"kind"`
+	Encoding   string   `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	Version    string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Algorithm  string   `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	KDF        string   `json:"kdf,omitempty" yaml:"kdf,omitempty"`
+	Recipients []string `json:"recipients,omitempty" yaml:"recipients,omitempty"`
+	Truncated  bool     `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+	TooLarge   bool     `json:"too_large,omitempty" yaml:"too_large,omitempty"`
+	Detail     string   `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+func NewIdentifyCmd() *cobra.Command {
+	flags := &identifyFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "identify",
+		Short: "Report which format a file is in",
+		Long: `Sniff a file and report whether it is an sshhades backup (JSON or base64
+armor), an age file, a plain SSH private key or something unrecognized, with
+the version and algorithm where they can be read. identify never decrypts and
+never fails on a damaged file: truncated files are reported as such, with
+whatever could be read before the cut. Use verify to check a backup strictly.`,
+		Example: `  # Triage a mystery file
+  sshhades identify -i backup.enc
+
+  # Machine-readable result
+  sshhades identify -i backup.enc --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runIdentify(flags)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "File to identify (required)")
+	cmd.Flags().StringVar(&flags.outputFormat, "format", outputText, "Output format: text, json or yaml")
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Output as JSON (same as --format json)")
+	cmd.MarkFlagRequired("input")
+
+	return cmd
+}
+
+func runIdentify(flags *identifyFlags) error {
+	outputFormat, err := resolveOutputFormat(flags.outputFormat, flags.json)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.ValidatePath(flags.input); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+
+	if !storage.FileExists(flags.input) {
+		return newFileError(fs.ErrNotExist, flags.input, "file not found: %s", flags.input)
+	}
+
+	file, err := os.Open(flags.input)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Read one byte past the backup size limit to tell oversized files apart
+	data, err := io.ReadAll(io.LimitReader(file, storage.MaxEncryptedFileSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	tooLarge := int64(len(data)) > storage.MaxEncryptedFileSize
+	if tooLarge {
+		data = data[:storage.MaxEncryptedFileSize]
+	}
+
+	id := format.Identify(data)
+	result := identifyResult{
+		Path:       flags.input,
+		Kind:       id.Kind,
+		Encoding:   id.Encoding,
+		Version:    id.Version,
+		Algorithm:  id.Algorithm,
+		KDF:        id.KDF,
+		Recipients: id.Recipients,
+		// Only the start of an oversized file was read, so it is not cut off
+		Truncated: id.Truncated && !tooLarge,
+		TooLarge:  tooLarge,
+		Detail:    id.Detail,
+	}
+
+	if outputFormat != outputText {
+		return writeStructured(outputFormat, result)
+	}
+
+	printIdentifyResult(result)
+	return nil
+}
+
+// identifyKinds are the human-readable names of format.Identify kinds
+var identifyKinds = map[string]string{
+	format.KindSSHHades:      "sshhades backup",
+	format.KindAge:           "age encrypted file",
+	format.KindSSHPrivateKey: "SSH private key",
+	format.KindUnknown:       "unrecognized",
+}
+
+func printIdentifyResult(r identifyResult) {
+	fmt.Printf("%s\n", r.Path)
+
+	kind := identifyKinds[r.Kind]
+	if r.Encoding != "" {
+		kind += fmt.Sprintf(" (%s)", r.Encoding)
+	}
+	printField("Format", kind, 0)
+	if r.Version != "" {
+		printField("Version", r.Version, 0)
+	}
+	if r.Algorithm != "" {
+		printField("Algorithm", r.Algorithm, 0)
+	}
+	if r.KDF != "" {
+		printField("KDF", r.KDF, 0)
+	}
+	if len(r.Recipients) > 0 {
+		printField("Recipients", strings.Join(r.Recipients, ", "), 0)
+	}
+	if r.Detail != "" {
+		printField("Note", r.Detail, 0)
+	}
+
+	if r.Truncated {
+		fmt.Println("⚠️  The file is truncated; restore will fail unless a complete copy is found")
+	}
+	if r.TooLarge {
+		fmt.Printf("⚠️  The file is larger than %d bytes, more than any sshhades backup; only its start was examined\n", storage.MaxEncryptedFileSize)
+	}
+	if r.Kind == format.KindSSHPrivateKey {
+		fmt.Println("⚠️  This is an unencrypted key file (or one protected only by its own passphrase), not a backup")
+	}
+}
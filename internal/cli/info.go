@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+type infoFlags struct {
+	input         string
+	outputFormat  string
+	json          bool
+	wrapWidth     int
+	passphrase    bool
+	passphraseEnv string
+}
+
+func NewInfoCmd() *cobra.Command {
+	flags := &infoFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "info",
+		Short: "Show metadata of an encrypted file",
+		Long: `Show the header metadata of an encrypted SSH key file without decrypting it.
+Unlike verify, info also describes files that fail validation.`,
+		Example: `  # Show backup metadata
+  sshhades info -i ~/backups/id_ed25519.enc
+
+  # Emit metadata as YAML
+  sshhades info -i ~/backups/id_ed25519.enc --format yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInfo(flags)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "Path to encrypted file (required)")
+	cmd.Flags().StringVar(&flags.outputFormat, "format", outputText, "Output format: text, json or yaml")
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Output as JSON (same as --format json)")
+	cmd.Flags().BoolVar(&flags.passphrase, "passphrase", false, "Prompt for the passphrase to reveal metadata encrypted with --encrypt-metadata")
+	cmd.Flags().StringVar(&flags.passphraseEnv, "passphrase-env", "", "Environment variable containing passphrase (implies --passphrase)")
+	cmd.Flags().IntVar(&flags.wrapWidth, "wrap-width", 0, "Wrap long values at this width (default: terminal width, no wrapping when not a terminal)")
+	cmd.MarkFlagRequired("input")
+
+	return cmd
+}
+
+func runInfo(flags *infoFlags) error {
+	outputFormat, err := resolveOutputFormat(flags.outputFormat, flags.json)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.ValidatePath(flags.input); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+
+	if !storage.FileExists(flags.input) {
+		return newFileError(fs.ErrNotExist, flags.input, "file not found: %s", flags.input)
+	}
+
+	encFile, err := storage.LoadEncryptedFile(flags.input)
+	if err != nil {
+		return fmt.Errorf("failed to load encrypted file: %w", err)
+	}
+
+	validationErr := crypto.ValidateEncryptedFile(encFile)
+
+	revealed := false
+	if (flags.passphrase || flags.passphraseEnv != "") && encFile.Metadata != nil && validationErr == nil {
+		passphrase, err := readPassphrase(flags.passphraseEnv, passphrasePrompt("", flags.input))
+		if err != nil {
+			return fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		defer crypto.ClearBytes(passphrase)
+
+		if err := revealMetadata(encFile, passphrase, nil); err != nil {
+			return err
+		}
+		revealed = true
+	}
+
+	metadata := newBackupMetadata(flags.input, encFile, validationErr)
+	metadata.MetadataRevealed = revealed
+
+	if outputFormat != outputText {
+		return writeStructured(outputFormat, metadata)
+	}
+
+	fmt.Printf("%s\n\n", flags.input)
+	printFileInformation(metadata, resolveWrapWidth(flags.wrapWidth))
+	fmt.Println()
+	printCryptoParameters(metadata)
+	fmt.Println()
+
+	if metadata.Valid {
+		fmt.Println("Valid: yes")
+	} else {
+		fmt.Printf("Valid: no (%s)\n", metadata.Error)
+	}
+	printKDFWarning(metadata)
+	printClockWarning(metadata)
+	printExpiryWarning(metadata)
+
+	return nil
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -65,8 +64,7 @@ func (iks *InteractiveKeySelector) SelectKey() (string, error) {
 	fmt.Printf("\n📝 Pilih key yang ingin di-backup (1-%d): ", len(keys))
 	
 	// Read user input
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	input, err := readStdinLine()
 	if err != nil {
 		return "", fmt.Errorf("failed to read input: %w", err)
 	}
@@ -106,8 +104,7 @@ func SelectAlgorithm() (string, error) {
 	
 	fmt.Printf("📝 Pilih algoritma (1-%d) [default: 1]: ", len(algorithms))
 	
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	input, err := readStdinLine()
 	if err != nil {
 		return "", fmt.Errorf("failed to read input: %w", err)
 	}
@@ -152,8 +149,7 @@ func SelectPerformanceMode() (bool, error) {
 	
 	fmt.Printf("📝 Pilih mode (1-%d) [default: 2 untuk development]: ", len(modes))
 	
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	input, err := readStdinLine()
 	if err != nil {
 		return false, fmt.Errorf("failed to read input: %w", err)
 	}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -15,6 +16,8 @@ import (
 )
 
 func NewInteractiveCmd() *cobra.Command {
+	var noUploadOnExists bool
+
 	cmd := &cobra.Command{
 		Use:   "interactive",
 		Short: "Mode interaktif untuk backup SSH key",
@@ -31,14 +34,16 @@ func NewInteractiveCmd() *cobra.Command {
   sshhades i
   sshhades wizard`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runInteractive()
+			return runInteractive(cmd.Context(), noUploadOnExists)
 		},
 	}
 
+	cmd.Flags().BoolVar(&noUploadOnExists, "no-upload-on-exists", false, "Lewati upload ke GitHub tanpa bertanya jika file sudah ada di repository")
+
 	return cmd
 }
 
-func runInteractive() error {
+func runInteractive(ctx context.Context, noUploadOnExists bool) error {
 	fmt.Println("🎯 SSH Hades - Mode Interaktif")
 	fmt.Println("=" + strings.Repeat("=", 40))
 	fmt.Println()
@@ -74,50 +79,43 @@ func runInteractive() error {
 
 	// Step 4: Get comment
 	fmt.Println("💬 Step 4: Komentar (opsional)")
-	fmt.Print("📝 Masukkan komentar untuk backup ini: ")
-	comment := ""
-	fmt.Scanln(&comment)
+	comment := readLine("📝 Masukkan komentar untuk backup ini: ")
 	if comment == "" {
 		comment = fmt.Sprintf("Interactive backup - %s", filepath.Base(inputPath))
 	}
+	if err := format.ValidateComment(comment); err != nil {
+		return fmt.Errorf("invalid comment: %w", err)
+	}
 	fmt.Printf("✅ Komentar: %s\n", comment)
 	fmt.Println()
 
 	// Step 5: Generate output path
-	outputPath := storage.CreateBackupPath(inputPath, "")
+	outputDir, err := defaultOutputDir()
+	if err != nil {
+		return err
+	}
+	outputPath := storage.CreateBackupPath(inputPath, outputDir)
 	fmt.Printf("💾 Step 5: Output file akan disimpan di: %s\n", outputPath)
 	fmt.Println()
 
 	// Check if output file already exists
 	if storage.FileExists(outputPath) {
 		fmt.Printf("⚠️  File output sudah ada: %s\n", outputPath)
-		fmt.Print("❓ Overwrite? (y/N): ")
-		var overwrite string
-		fmt.Scanln(&overwrite)
-		if overwrite != "y" && overwrite != "Y" {
+		if !confirm("❓ Overwrite? (y/N): ") {
 			fmt.Println("❌ Backup dibatalkan")
 			return nil
 		}
 	}
 
-	// Step 6: Get passphrase
-	fmt.Println("🔐 Step 6: Passphrase")
-	passphrase, err := readPassphrase("", "🔑 Masukkan passphrase untuk enkripsi: ")
-	if err != nil {
-		return fmt.Errorf("failed to read passphrase: %w", err)
-	}
-	defer crypto.ClearBytes(passphrase)
-
-	// Step 7: Perform backup
-	fmt.Println()
-	fmt.Println("🚀 Step 7: Memulai Backup...")
+	// Read the key before the passphrase, so a key that cannot be read
+	// fails without a passphrase having been typed
 	fmt.Printf("📖 Membaca SSH key dari %s...\n", inputPath)
-
-	// Read SSH key
 	keyData, err := ssh.ReadKeyFile(inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read SSH key: %w", err)
 	}
+	defer crypto.ClearBytes(keyData)
+	fmt.Println()
 
 	// Set up encryption parameters
 	var kdfParams crypto.KDFParams
@@ -133,65 +131,84 @@ func runInteractive() error {
 
 	header.Algorithm = algorithm
 	header.Comment = comment
+	setKeyMetadata(&header, inputPath, keyData)
 
-	// Encrypt the key
-	fmt.Printf("🔒 Mengenkripsi dengan %s...\n", algorithm)
-	result, err := crypto.Encrypt(keyData, passphrase, algorithm, kdfParams)
+	// Step 6: Get passphrase and encrypt
+	fmt.Println("🔐 Step 6: Passphrase")
+	result, err := encryptWithNewPassphrase(keyData, algorithm, kdfParams)
 	if err != nil {
-		return fmt.Errorf("encryption failed: %w", err)
+		return err
 	}
-
-	// Create encrypted file structure
-	encFile := &format.EncryptedFile{
-		Header:     header,
-		Salt:       result.Salt,
-		Nonce:      result.Nonce,
-		Ciphertext: result.Ciphertext,
-		Tag:        result.Tag,
-	}
-
-	// Save encrypted file
-	fmt.Printf("💾 Menyimpan file terenkripsi ke %s...\n", outputPath)
-	if err := storage.SaveEncryptedFile(outputPath, encFile); err != nil {
-		return fmt.Errorf("failed to save encrypted file: %w", err)
+	if err := checkDeadline(ctx); err != nil {
+		return err
 	}
 
-	// Step 8: GitHub integration (optional)
+	// Step 7: GitHub integration (optional)
 	fmt.Println()
 	githubUpload := false
 	cfg, err := config.LoadConfig()
 	if err == nil && cfg.IsGitHubConfigured() {
-		fmt.Println("☁️  Step 8: Upload ke GitHub")
-		github.PrintInfo("GitHub sudah dikonfigurasi!")
+		fmt.Println("☁️  Step 7: Upload ke GitHub")
+		printInfo("GitHub sudah dikonfigurasi!")
 		fmt.Printf("📂 Repository: %s/%s\n", cfg.GitHub.RepoOwner, cfg.GitHub.RepoName)
-		fmt.Print("❓ Upload backup ke GitHub? (Y/n): ")
-		var upload string
-		fmt.Scanln(&upload)
-		if upload == "" || upload == "y" || upload == "Y" {
+		prompt := "❓ Upload backup ke GitHub? (Y/n): "
+		if assumedYes(prompt) {
 			githubUpload = true
+		} else {
+			upload := strings.ToLower(readLine(prompt))
+			githubUpload = upload == "" || upload == "y" || upload == "yes"
 		}
 	} else {
-		fmt.Println("☁️  Step 8: GitHub Integration (Opsional)")
-		fmt.Print("❓ Ingin setup GitHub untuk backup otomatis? (y/N): ")
-		var setup string
-		fmt.Scanln(&setup)
-		if setup == "y" || setup == "Y" {
-			github.PrintInfo("Menjalankan setup GitHub...")
+		fmt.Println("☁️  Step 7: GitHub Integration (Opsional)")
+		if confirm("❓ Ingin setup GitHub untuk backup otomatis? (y/N): ") {
+			printInfo("Menjalankan setup GitHub...")
 			// We'll just inform them to run the command manually for now
-			github.PrintInfo("Jalankan 'sshhades github login' untuk setup GitHub integration")
+			printInfo("Jalankan 'sshhades github login' untuk setup GitHub integration")
 		}
 	}
 
-	// Upload to GitHub if requested
+	// Like the local file, an existing remote backup is only replaced on request
 	if githubUpload {
-		fmt.Println("📤 Mengupload ke GitHub...")
-		if err := uploadToGitHub(outputPath, comment); err != nil {
-			github.PrintError(fmt.Sprintf("Upload gagal: %v", err))
-			github.PrintInfo("Backup tersimpan lokal, tapi tidak terupload ke GitHub")
-		} else {
-			github.PrintSuccess("✅ Berhasil diupload ke GitHub!")
+		githubUpload = confirmRemoteOverwrite(ctx, cfg, filepath.Base(outputPath), noUploadOnExists)
+	}
+
+	// Step 8: Save the backup locally and, if requested, to GitHub
+	fmt.Println()
+	fmt.Println("🚀 Step 8: Menyimpan Backup...")
+
+	// Create encrypted file structure
+	encFile := &format.EncryptedFile{
+		Header:     header,
+		Salt:       result.Salt,
+		Nonce:      result.Nonce,
+		Ciphertext: result.Ciphertext,
+		Tag:        result.Tag,
+	}
+	data, err := encFile.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize encrypted file: %w", err)
+	}
+
+	sinks, err := newBackupSinks(ctx, &backupFlags{githubUpload: githubUpload, comment: comment}, filepath.Dir(outputPath), "")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("💾 Menyimpan file terenkripsi ke %s...\n", sinkNames(sinks))
+	results, writeErr := storage.WriteAll(sinks, filepath.Base(outputPath), data)
+	for _, result := range results {
+		switch {
+		case result.Err == nil:
+			printSuccess(fmt.Sprintf("✅ Tersimpan di %s", result.Sink))
+		case result.Sink == "local":
+			return fmt.Errorf("failed to save encrypted file: %w", result.Err)
+		default:
+			printError(fmt.Sprintf("Upload ke %s gagal: %v", result.Sink, result.Err))
 		}
 	}
+	if writeErr != nil {
+		printInfo("Backup tersimpan lokal, tapi tidak terupload ke GitHub")
+	}
 
 	// Success summary
 	fmt.Println()
@@ -216,4 +233,55 @@ func runInteractive() error {
 	fmt.Printf("   • Untuk restore: sshhades restore -i %s -o <target>\n", filepath.Base(outputPath))
 
 	return nil
-}
\ No newline at end of file
+}
+
+// encryptWithNewPassphrase asks for a new passphrase and encrypts keyData
+// with it. The passphrase only lives for this call, so it is wiped before
+// the wizard goes on to the GitHub prompts.
+func encryptWithNewPassphrase(keyData []byte, algorithm string, kdfParams crypto.KDFParams) (*crypto.EncryptionResult, error) {
+	passphrase, err := readNewPassphrase("", "🔑 Masukkan passphrase untuk enkripsi: ", "🔑 Ulangi passphrase: ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	defer crypto.ClearBytes(passphrase)
+
+	fmt.Printf("🔒 Mengenkripsi dengan %s...\n", algorithm)
+	result, err := crypto.Encrypt(keyData, passphrase, algorithm, kdfParams)
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
+	return result, nil
+}
+
+// confirmRemoteOverwrite reports whether the wizard should upload name,
+// asking first when the repository already holds a file of that name. The
+// default answer is no, and a failed check counts as an existing file.
+func confirmRemoteOverwrite(ctx context.Context, cfg *config.Config, name string, noUploadOnExists bool) bool {
+	sink, err := github.NewSink(cfg, "")
+	if err != nil {
+		printError(fmt.Sprintf("Upload gagal: %v", err))
+		return false
+	}
+	sink.Context = ctx
+
+	exists, err := sink.Exists(name)
+	if err != nil {
+		printWarning(fmt.Sprintf("Tidak bisa memeriksa file di GitHub: %v", err))
+		exists = true
+	}
+	if !exists {
+		return true
+	}
+
+	fmt.Printf("⚠️  File sudah ada di GitHub: %s/%s\n", sink.Dir, name)
+	if noUploadOnExists {
+		printInfo("Upload dilewati (--no-upload-on-exists); backup tersimpan lokal")
+		return false
+	}
+
+	if !confirm("❓ Overwrite file di GitHub? (y/N): ") {
+		printInfo("Upload dilewati; backup tersimpan lokal")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/crypto"
+)
+
+type kdfBenchFlags struct {
+	target    time.Duration
+	memory    uint32
+	threads   uint8
+	save      bool
+	algorithm string
+}
+
+func NewKDFBenchCmd() *cobra.Command {
+	defaults := crypto.DefaultKDFParams()
+	flags := &kdfBenchFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "kdf-bench",
+		Short: "Calibrate Argon2id parameters for this machine",
+		Long: `Measure Argon2id on this machine and recommend the number of iterations at
+which one key derivation takes about --target, keeping memory and threads fixed.
+
+With --save the recommendation is written to the config as the KDF profile for
+both algorithms (or only --algorithm), so later backups use it without flags.
+Re-running with --save overwrites the saved profile.`,
+		Example: `  # Recommend parameters for ~1s derivations
+  sshhades kdf-bench
+
+  # Calibrate with 128 MB and save the result as the default
+  sshhades kdf-bench --memory 128 --save
+
+  # Lighter ChaCha20 defaults for a small device
+  sshhades kdf-bench --memory 16 --threads 1 --target 500ms --save --algorithm chacha20`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runKDFBench(cmd.Context(), flags)
+		},
+	}
+
+	cmd.Flags().DurationVar(&flags.target, "target", time.Second, "Time one key derivation should take")
+	cmd.Flags().Uint32Var(&flags.memory, "memory", defaults.Memory, "Argon2id memory in MB")
+	cmd.Flags().Uint8Var(&flags.threads, "threads", defaults.Threads, "Argon2id parallelism")
+	cmd.Flags().BoolVar(&flags.save, "save", false, "Save the recommendation as the default KDF profile in the config")
+	cmd.Flags().StringVar(&flags.algorithm, "algorithm", "", "With --save, only set the profile for this algorithm: aes or chacha20")
+
+	return cmd
+}
+
+func runKDFBench(ctx context.Context, flags *kdfBenchFlags) error {
+	if flags.target <= 0 {
+		return fmt.Errorf("--target must be a positive duration")
+	}
+	if flags.threads == 0 {
+		return fmt.Errorf("--threads must be at least 1")
+	}
+
+	profiles := []string{config.ProfileAES, config.ProfileChaCha20}
+	if flags.algorithm != "" {
+		if !flags.save {
+			return fmt.Errorf("--algorithm only applies with --save")
+		}
+		name := strings.ToLower(flags.algorithm)
+		if name != config.ProfileAES && name != config.ProfileChaCha20 {
+			return fmt.Errorf("unsupported algorithm: %s (use: %s, %s)", flags.algorithm, config.ProfileAES, config.ProfileChaCha20)
+		}
+		profiles = []string{name}
+	}
+
+	profile := config.KDFProfile{Memory: flags.memory, Threads: flags.threads}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Calibrating Argon2id with %d MB and %d thread(s) for ~%s per derivation...\n", flags.memory, flags.threads, flags.target)
+	base := crypto.KDFParams{Memory: flags.memory, Threads: flags.threads, KeyLength: 32}
+	params, elapsed := crypto.CalibrateKDF(base, flags.target)
+
+	// One pass is only enough with a lot of memory; below that the floor
+	// asks for a second pass, even if it takes longer than --target
+	if crypto.IsWeakKDF(params.Iterations, params.Memory) && params.Memory >= crypto.MinSecureMemory {
+		params.Iterations = crypto.MinSecureIterations
+		elapsed = crypto.BenchmarkKDF(params)
+	}
+	profile.Iterations = params.Iterations
+	if err := checkDeadline(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Recommended: %d iterations, %d MB, %d thread(s) (%s per derivation on this machine)\n",
+		params.Iterations, params.Memory, params.Threads, elapsed.Round(time.Millisecond))
+
+	if crypto.IsWeakKDF(params.Iterations, params.Memory) {
+		printWarning(fmt.Sprintf("These parameters are below the %s that verify and info treat as secure;", crypto.SecureKDFFloor))
+		fmt.Println("   backups made with them will be reported as weakly protected.")
+	}
+
+	if !flags.save {
+		fmt.Printf("\nRun with --save to use these parameters for future backups, or pass them once:\n")
+		fmt.Printf("  sshhades backup -n %d --memory %d --threads %d ...\n", params.Iterations, params.Memory, params.Threads)
+		return nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, name := range profiles {
+		cfg.SetKDFProfile(name, profile)
+	}
+	if err := cfg.SaveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Saved as the default KDF profile for %s", strings.Join(profiles, ", ")))
+	return nil
+}
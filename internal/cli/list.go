@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,13 +13,25 @@ import (
 	"github.com/sshhades/sshhades/internal/crypto"
 	"github.com/sshhades/sshhades/internal/ssh"
 	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
 )
 
 type listFlags struct {
-	directory string
-	verbose   bool
+	directory    string
+	verbose      bool
+	outputFormat string
+	json         bool
+	keysOnly     bool
+	backupsOnly  bool
+	exclude      []string
+	fullComment  bool
+	withRemote   bool
 }
 
+// maxListComment is how many characters of a comment the text listing
+// shows unless --full-comment is given
+const maxListComment = 60
+
 func NewListCmd() *cobra.Command {
 	flags := &listFlags{}
 
@@ -33,19 +47,55 @@ Shows key types, file sizes, and encryption status.`,
   sshhades list --directory ~/backups
   
   # Show detailed information
-  sshhades list --verbose`,
+  sshhades list --verbose
+
+  # Machine-readable listing
+  sshhades list --format yaml
+
+  # Inventory of backups only
+  sshhades list -d ~/backups --backups-only --json
+
+  # Leave host keys out of the listing
+  sshhades list --exclude '*_host_*'
+
+  # Which backups also have a GitHub copy
+  sshhades list -d ~/backups --backups-only --with-remote`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runList(flags)
+			return runList(cmd.Context(), flags)
 		},
 	}
 
 	cmd.Flags().StringVarP(&flags.directory, "directory", "d", "", "Directory to search (defaults to ~/.ssh)")
 	cmd.Flags().BoolVarP(&flags.verbose, "verbose", "v", false, "Show detailed information")
+	cmd.Flags().StringVar(&flags.outputFormat, "format", outputText, "Output format: text, json or yaml")
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Output as JSON (same as --format json)")
+	cmd.Flags().BoolVar(&flags.keysOnly, "keys-only", false, "List only SSH keys")
+	cmd.Flags().BoolVar(&flags.backupsOnly, "backups-only", false, "List only encrypted backups")
+	cmd.Flags().StringArrayVar(&flags.exclude, "exclude", nil, "Skip files whose name matches this glob (repeatable)")
+	cmd.Flags().BoolVar(&flags.fullComment, "full-comment", false, "Show backup comments untruncated in the text listing")
+	cmd.Flags().BoolVar(&flags.withRemote, "with-remote", false, "Mark which backups also exist in the GitHub repository (needs token authentication)")
 
 	return cmd
 }
 
-func runList(flags *listFlags) error {
+func runList(ctx context.Context, flags *listFlags) error {
+	outputFormat, err := resolveOutputFormat(flags.outputFormat, flags.json)
+	if err != nil {
+		return err
+	}
+
+	if flags.keysOnly && flags.backupsOnly {
+		return fmt.Errorf("--keys-only and --backups-only cannot be used together")
+	}
+
+	if flags.keysOnly && flags.withRemote {
+		return fmt.Errorf("--with-remote lists backups and cannot be used with --keys-only")
+	}
+
+	if err := ssh.ValidateExcludePatterns(flags.exclude); err != nil {
+		return err
+	}
+
 	var searchDir string
 	
 	if flags.directory != "" {
@@ -60,29 +110,43 @@ func runList(flags *listFlags) error {
 
 	// Check if directory exists
 	if _, err := os.Stat(searchDir); os.IsNotExist(err) {
+		if outputFormat != outputText {
+			return newFileError(fs.ErrNotExist, searchDir, "directory not found: %s", searchDir)
+		}
 		fmt.Printf("Directory not found: %s\n", searchDir)
 		return nil
 	}
 
+	if outputFormat != outputText {
+		return writeListing(ctx, flags, outputFormat, searchDir)
+	}
+
+	if flags.backupsOnly {
+		return listBackups(ctx, flags, searchDir)
+	}
+
 	fmt.Printf("Searching for SSH keys in: %s\n\n", searchDir)
 
 	// Find SSH keys
-	keys, err := ssh.FindSSHKeys(searchDir)
+	scan, err := ssh.ScanSSHKeys(searchDir, flags.exclude...)
 	if err != nil {
 		return fmt.Errorf("failed to search for SSH keys: %w", err)
 	}
+	keys := scan.Keys
+
+	if flags.verbose && len(scan.Skipped) > 0 {
+		fmt.Printf("Skipped %d unreadable file(s):\n", len(scan.Skipped))
+		for _, path := range scan.Skipped {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Println()
+	}
 
 	if len(keys) == 0 {
 		fmt.Println("No SSH keys found.")
 		return nil
 	}
 
-	// Also look for encrypted files
-	encryptedFiles, err := findEncryptedFiles(searchDir)
-	if err != nil {
-		fmt.Printf("Warning: failed to search for encrypted files: %v\n", err)
-	}
-
 	// Display SSH keys
 	fmt.Printf("SSH Keys Found (%d):\n", len(keys))
 	fmt.Println(strings.Repeat("-", 50))
@@ -93,51 +157,217 @@ func runList(flags *listFlags) error {
 		status := ""
 		if key.HasPrivate {
 			status = "private"
+		} else if key.Certificate != nil {
+			status = "certificate"
 		} else {
 			status = "public"
 		}
 
-		fmt.Printf("  %-20s  %s (%s)\n", relPath, key.Type, status)
+		fmt.Printf("  %-20s  %s %s key (%s)\n", relPath, key.Type, key.Role, status)
 		
 		if flags.verbose {
 			fmt.Printf("    Path: %s\n", key.Path)
 			fmt.Printf("    Size: %d bytes\n", key.Size)
+			if cert := key.Certificate; cert != nil {
+				fmt.Printf("    Principals: %s\n", format.SanitizeComment(cert.PrincipalList()))
+				if cert.Expired(time.Now()) {
+					fmt.Printf("    Valid: %s (expired)\n", cert.Validity())
+				} else {
+					fmt.Printf("    Valid: %s\n", cert.Validity())
+				}
+			}
 			fmt.Println()
 		}
 	}
 
-	// Display encrypted files
+	if flags.keysOnly {
+		return nil
+	}
+
+	// Also look for encrypted files
+	encryptedFiles, err := findEncryptedFiles(searchDir, flags.exclude)
+	if err != nil {
+		fmt.Printf("Warning: failed to search for encrypted files: %v\n", err)
+	}
+	if flags.withRemote {
+		encryptedFiles, err = withRemote(ctx, encryptedFiles)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(encryptedFiles) > 0 {
-		fmt.Printf("\nEncrypted Backups Found (%d):\n", len(encryptedFiles))
-		fmt.Println(strings.Repeat("-", 50))
-
-		for _, encFile := range encryptedFiles {
-			relPath, _ := filepath.Rel(searchDir, encFile.Path)
-			fmt.Printf("  %-20s  encrypted backup\n", relPath)
-			
-			if flags.verbose {
-				fmt.Printf("    Path: %s\n", encFile.Path)
-				fmt.Printf("    Size: %d bytes\n", encFile.Size)
-				if encFile.Comment != "" {
-					fmt.Printf("    Comment: %s\n", encFile.Comment)
+		fmt.Println()
+		printEncryptedFiles(flags, searchDir, encryptedFiles)
+	}
+
+	return nil
+}
+
+// listBackups is the text listing for --backups-only
+func listBackups(ctx context.Context, flags *listFlags, searchDir string) error {
+	fmt.Printf("Searching for encrypted backups in: %s\n\n", searchDir)
+
+	encryptedFiles, err := findEncryptedFiles(searchDir, flags.exclude)
+	if err != nil {
+		return fmt.Errorf("failed to search for encrypted files: %w", err)
+	}
+	if flags.withRemote {
+		encryptedFiles, err = withRemote(ctx, encryptedFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(encryptedFiles) == 0 {
+		fmt.Println("No encrypted backups found.")
+		return nil
+	}
+
+	printEncryptedFiles(flags, searchDir, encryptedFiles)
+	return nil
+}
+
+// printEncryptedFiles prints the encrypted backups section of the text listing
+func printEncryptedFiles(flags *listFlags, searchDir string, encryptedFiles []encryptedFileInfo) {
+	fmt.Printf("Encrypted Backups Found (%d):\n", len(encryptedFiles))
+	fmt.Println(strings.Repeat("-", 50))
+
+	for _, encFile := range encryptedFiles {
+		if encFile.Sync == remoteOnly {
+			fmt.Printf("  %-20s  [%s]\n", encFile.Path, remoteLabels[encFile.Sync])
+			continue
+		}
+
+		relPath, _ := filepath.Rel(searchDir, encFile.Path)
+		sync := ""
+		if encFile.Sync != "" {
+			sync = fmt.Sprintf("  [%s]", remoteLabels[encFile.Sync])
+		}
+		if encFile.Members > 0 {
+			fmt.Printf("  %-20s  encrypted bundle (%d files)%s\n", relPath, encFile.Members, sync)
+		} else {
+			fmt.Printf("  %-20s  encrypted backup%s\n", relPath, sync)
+		}
+
+		if flags.verbose {
+			fmt.Printf("    Path: %s\n", encFile.Path)
+			fmt.Printf("    Size: %d bytes\n", encFile.Size)
+			if encFile.Comment != "" {
+				comment := format.SanitizeComment(encFile.Comment)
+				if !flags.fullComment {
+					comment = truncateComment(comment, maxListComment)
 				}
-				fmt.Printf("    Created: %s\n", encFile.Timestamp.Format("2006-01-02 15:04:05"))
-				fmt.Println()
+				fmt.Printf("    Comment: %s\n", comment)
+			}
+			if encFile.KeyRole != "" {
+				fmt.Printf("    Role: %s key\n", encFile.KeyRole)
 			}
+			if encFile.Certificate != nil {
+				fmt.Printf("    Certificate: %s\n", format.SanitizeComment(encFile.Certificate.String()))
+			}
+			if encFile.Hostname != "" {
+				fmt.Printf("    Host: %s@%s\n", encFile.Username, encFile.Hostname)
+			}
+			if encFile.Created != nil {
+				fmt.Printf("    Created: %s\n", encFile.Created.Format("2006-01-02 15:04:05"))
+			}
+			fmt.Println()
 		}
 	}
+}
 
-	return nil
+// truncateComment shortens comment to at most limit characters, ending it
+// with an ellipsis when anything was cut
+func truncateComment(comment string, limit int) string {
+	runes := []rune(comment)
+	if len(runes) <= limit {
+		return comment
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// listing is the structured rendering of the list command
+type listing struct {
+	Directory string              `json:"directory" yaml:"directory"`
+	Keys      []keyEntry          `json:"keys" yaml:"keys"`
+	Backups   []encryptedFileInfo `json:"backups" yaml:"backups"`
+	Skipped   []string            `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+type keyEntry struct {
+	Path       string `json:"path" yaml:"path"`
+	Type       string `json:"type" yaml:"type"`
+	Role       string `json:"role" yaml:"role"`
+	Size       int64  `json:"size" yaml:"size"`
+	HasPrivate bool   `json:"has_private" yaml:"has_private"`
+	HasPublic  bool   `json:"has_public" yaml:"has_public"`
+
+	Certificate *format.CertificateInfo `json:"certificate,omitempty" yaml:"certificate,omitempty"`
 }
 
 type encryptedFileInfo struct {
-	Path      string
-	Size      int64
-	Comment   string
-	Timestamp time.Time
+	backupMetadata `yaml:",inline"`
+	Size           int64 `json:"size" yaml:"size"`
+	// Sync is the list --with-remote state: local-only, synced, differs
+	// or remote-only
+	Sync string `json:"sync,omitempty" yaml:"sync,omitempty"`
+}
+
+// writeListing emits keys and backups of a directory as JSON or YAML.
+// A section excluded by --keys-only or --backups-only is an empty list.
+func writeListing(ctx context.Context, flags *listFlags, outputFormat, searchDir string) error {
+	result := listing{
+		Directory: searchDir,
+		Keys:      []keyEntry{},
+		Backups:   []encryptedFileInfo{},
+	}
+
+	var keys []ssh.KeyInfo
+	if !flags.backupsOnly {
+		scan, err := ssh.ScanSSHKeys(searchDir, flags.exclude...)
+		if err != nil {
+			return fmt.Errorf("failed to search for SSH keys: %w", err)
+		}
+		keys = scan.Keys
+		result.Skipped = scan.Skipped
+	}
+
+	if !flags.keysOnly {
+		encryptedFiles, err := findEncryptedFiles(searchDir, flags.exclude)
+		if err != nil {
+			return fmt.Errorf("failed to search for encrypted files: %w", err)
+		}
+		if flags.withRemote {
+			encryptedFiles, err = withRemote(ctx, encryptedFiles)
+			if err != nil {
+				return err
+			}
+		}
+		if encryptedFiles != nil {
+			result.Backups = encryptedFiles
+		}
+	}
+
+	for _, key := range keys {
+		result.Keys = append(result.Keys, keyEntry{
+			Path:       key.Path,
+			Type:       key.Type,
+			Role:       key.Role,
+			Size:       key.Size,
+			HasPrivate: key.HasPrivate,
+			HasPublic:  key.HasPublic,
+
+			Certificate: key.Certificate,
+		})
+	}
+
+	return writeStructured(outputFormat, result)
 }
 
-func findEncryptedFiles(dir string) ([]encryptedFileInfo, error) {
+// findEncryptedFiles returns the valid backups in dir, leaving out files
+// that match an exclude pattern
+func findEncryptedFiles(dir string, exclude []string) ([]encryptedFileInfo, error) {
 	var encFiles []encryptedFileInfo
 
 	entries, err := os.ReadDir(dir)
@@ -151,7 +381,7 @@ func findEncryptedFiles(dir string) ([]encryptedFileInfo, error) {
 		}
 
 		name := entry.Name()
-		if !strings.HasSuffix(name, ".enc") {
+		if !strings.HasSuffix(name, ".enc") || ssh.IsExcluded(name, exclude) {
 			continue
 		}
 
@@ -174,10 +404,8 @@ func findEncryptedFiles(dir string) ([]encryptedFileInfo, error) {
 		}
 
 		encInfo := encryptedFileInfo{
-			Path:      path,
-			Size:      info.Size(),
-			Comment:   encFile.Header.Comment,
-			Timestamp: encFile.Header.Timestamp,
+			backupMetadata: newBackupMetadata(path, encFile, nil),
+			Size:           info.Size(),
 		}
 
 		encFiles = append(encFiles, encInfo)
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/github"
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+// Sync states list --with-remote gives each backup
+const (
+	remoteLocalOnly = "local-only"
+	remoteSynced    = "synced"
+	remoteDiffers   = "differs"
+	remoteOnly      = "remote-only"
+)
+
+// remoteLabels are the text listing names of the sync states
+var remoteLabels = map[string]string{
+	remoteLocalOnly: "local only",
+	remoteSynced:    "synced",
+	remoteDiffers:   "differs from remote",
+	remoteOnly:      "remote only",
+}
+
+// withRemote annotates backups with their GitHub sync state and appends the
+// backups only found in the repository. When GitHub is not set up for token
+// authentication, or the repository cannot be listed, it prints a note and
+// returns backups unchanged, so the listing still shows local files.
+func withRemote(ctx context.Context, backups []encryptedFileInfo) ([]encryptedFileInfo, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.IsGitHubConfigured() || cfg.GetGitHubConfig().AuthMethod != "token" {
+		fmt.Fprintln(os.Stderr, "⚠️  --with-remote needs GitHub token authentication (run 'sshhades github login'); showing local backups only")
+		return backups, nil
+	}
+
+	sink, err := github.NewSink(cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	sink.Context = ctx
+
+	remote, err := listRemoteBackups(sink, remoteDirs(backups))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not list the repository: %v; showing local backups only\n", github.RedactError(err))
+		return backups, nil
+	}
+
+	return crossReference(backups, remote)
+}
+
+// remoteDirs returns the repository directories backups may have been
+// uploaded to: ssh-keys/ itself and the ssh-keys/<hostname>/ directories
+// of --tag-host backups
+func remoteDirs(backups []encryptedFileInfo) []string {
+	dirs := []string{github.DefaultBackupDir}
+	seen := map[string]bool{github.DefaultBackupDir: true}
+	for _, backup := range backups {
+		if backup.Hostname == "" {
+			continue
+		}
+		dir := path.Join(github.DefaultBackupDir, sanitizeFilename(backup.Hostname))
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// listRemoteBackups returns the .enc files in dirs, keyed by repository path
+func listRemoteBackups(sink *github.Sink, dirs []string) (map[string]github.RemoteFile, error) {
+	remote := make(map[string]github.RemoteFile)
+	for _, dir := range dirs {
+		sink.Dir = dir
+		files, err := sink.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file.Name, ".enc") {
+				remote[path.Join(dir, file.Name)] = file
+			}
+		}
+	}
+	return remote, nil
+}
+
+// crossReference sets the sync state of each local backup by comparing it
+// with the repository copy under the same name, looking in its host
+// directory first. Copies are compared by git blob hash, so nothing is
+// downloaded. Repository files no local backup matched are appended as
+// remote-only entries, with their repository path as Path.
+func crossReference(backups []encryptedFileInfo, remote map[string]github.RemoteFile) ([]encryptedFileInfo, error) {
+	result := make([]encryptedFileInfo, 0, len(backups)+len(remote))
+	for _, backup := range backups {
+		name := filepath.Base(backup.Path)
+		candidates := []string{path.Join(github.DefaultBackupDir, name)}
+		if backup.Hostname != "" {
+			candidates = append([]string{path.Join(github.DefaultBackupDir, sanitizeFilename(backup.Hostname), name)}, candidates...)
+		}
+
+		backup.Sync = remoteLocalOnly
+		for _, candidate := range candidates {
+			file, ok := remote[candidate]
+			if !ok {
+				continue
+			}
+			delete(remote, candidate)
+
+			data, err := storage.ReadFileLimited(backup.Path, storage.MaxEncryptedFileSize, "encrypted file")
+			if err != nil {
+				return nil, err
+			}
+			backup.Sync = remoteDiffers
+			if github.BlobSHA(data) == file.SHA {
+				backup.Sync = remoteSynced
+			}
+			break
+		}
+		result = append(result, backup)
+	}
+
+	remotePaths := make([]string, 0, len(remote))
+	for remotePath := range remote {
+		remotePaths = append(remotePaths, remotePath)
+	}
+	sort.Strings(remotePaths)
+	for _, remotePath := range remotePaths {
+		result = append(result, encryptedFileInfo{
+			backupMetadata: backupMetadata{Path: remotePath},
+			Size:           int64(remote[remotePath].Size),
+			Sync:           remoteOnly,
+		})
+	}
+	return result, nil
+}
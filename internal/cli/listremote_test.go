@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/github"
+)
+
+func TestCrossReference(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	synced := write("synced.enc", "same")
+	differs := write("differs.enc", "local")
+	local := write("local.enc", "only here")
+	tagged := write("tagged.enc", "tagged")
+
+	backups := []encryptedFileInfo{
+		{backupMetadata: backupMetadata{Path: synced}},
+		{backupMetadata: backupMetadata{Path: differs}},
+		{backupMetadata: backupMetadata{Path: local}},
+		{backupMetadata: backupMetadata{Path: tagged, Hostname: "laptop"}},
+	}
+	remote := map[string]github.RemoteFile{
+		"ssh-keys/synced.enc":        {Name: "synced.enc", SHA: github.BlobSHA([]byte("same"))},
+		"ssh-keys/differs.enc":       {Name: "differs.enc", SHA: github.BlobSHA([]byte("remote"))},
+		"ssh-keys/laptop/tagged.enc": {Name: "tagged.enc", SHA: github.BlobSHA([]byte("tagged"))},
+		"ssh-keys/tagged.enc":        {Name: "tagged.enc", SHA: github.BlobSHA([]byte("older"))},
+		"ssh-keys/gone.enc":          {Name: "gone.enc", Size: 42},
+	}
+
+	got, err := crossReference(backups, remote)
+	if err != nil {
+		t.Fatalf("crossReference() error = %v", err)
+	}
+
+	want := []struct {
+		path string
+		sync string
+	}{
+		{synced, remoteSynced},
+		{differs, remoteDiffers},
+		{local, remoteLocalOnly},
+		{tagged, remoteSynced},
+		// The untagged copy of tagged.enc was not matched, since the host
+		// directory is looked at first
+		{"ssh-keys/gone.enc", remoteOnly},
+		{"ssh-keys/tagged.enc", remoteOnly},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("crossReference() returned %d entries, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Path != w.path || got[i].Sync != w.sync {
+			t.Errorf("entry %d = %s %s, want %s %s", i, got[i].Path, got[i].Sync, w.path, w.sync)
+		}
+	}
+	if got[4].Size != 42 {
+		t.Errorf("remote-only size = %d, want 42", got[4].Size)
+	}
+}
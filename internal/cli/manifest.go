@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/manifest"
+)
+
+type manifestFlags struct {
+	file         string
+	fingerprint  string
+	algorithm    string
+	since        string
+	verify       bool
+	outputFormat string
+	json         bool
+}
+
+func NewManifestCmd() *cobra.Command {
+	flags := &manifestFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "Show the local ledger of created backups",
+		Long: `Show the append-only manifest of backups created on this machine.
+Every successful backup adds one line to manifest.jsonl in the config directory.
+Each line carries the hash of the previous one, so --verify can detect edits,
+deletions or reordering of earlier entries.`,
+		Example: `  # Show every recorded backup
+  sshhades manifest
+
+  # Backups of one key since the start of the year
+  sshhades manifest --fingerprint SHA256:abc --since 2024-01-01
+
+  # Check the hash chain
+  sshhades manifest --verify`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runManifest(flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.file, "file", "", "Only show entries whose file path contains this text")
+	cmd.Flags().StringVar(&flags.fingerprint, "fingerprint", "", "Only show entries for this key fingerprint")
+	cmd.Flags().StringVar(&flags.algorithm, "algorithm", "", "Only show entries using this algorithm (e.g. aes, chacha20)")
+	cmd.Flags().StringVar(&flags.since, "since", "", "Only show entries created on or after this date (YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&flags.verify, "verify", false, "Verify the hash chain instead of listing entries")
+	cmd.Flags().StringVar(&flags.outputFormat, "format", outputText, "Output format: text, json or yaml")
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Output as JSON (same as --format json)")
+
+	return cmd
+}
+
+func runManifest(flags *manifestFlags) error {
+	outputFormat, err := resolveOutputFormat(flags.outputFormat, flags.json)
+	if err != nil {
+		return err
+	}
+
+	path, err := manifest.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	if flags.verify {
+		if err := manifest.Verify(path); err != nil {
+			return err
+		}
+		printSuccess(fmt.Sprintf("Manifest hash chain intact: %s", path))
+		return nil
+	}
+
+	var since time.Time
+	if flags.since != "" {
+		since, err = time.Parse("2006-01-02", flags.since)
+		if err != nil {
+			return fmt.Errorf("invalid --since date (use YYYY-MM-DD): %s", flags.since)
+		}
+	}
+
+	entries, err := manifest.Read(path)
+	if err != nil {
+		return err
+	}
+
+	matched := make([]manifest.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if flags.file != "" && !strings.Contains(entry.File, flags.file) {
+			continue
+		}
+		if flags.fingerprint != "" && entry.Fingerprint != flags.fingerprint {
+			continue
+		}
+		if flags.algorithm != "" && !strings.HasPrefix(strings.ToLower(entry.Algorithm), strings.ToLower(flags.algorithm)) {
+			continue
+		}
+		if !since.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+
+	if outputFormat != outputText {
+		return writeStructured(outputFormat, matched)
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No matching backups recorded.")
+		return nil
+	}
+
+	for _, entry := range matched {
+		fmt.Printf("%s  %-18s  %s\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Algorithm, entry.File)
+		if entry.Fingerprint != "" {
+			fmt.Printf("    Fingerprint: %s\n", entry.Fingerprint)
+		}
+		if len(entry.Targets) > 0 {
+			fmt.Printf("    Targets: %s\n", strings.Join(entry.Targets, ", "))
+		}
+	}
+
+	fmt.Printf("\n%d of %d recorded backup(s)\n", len(matched), len(entries))
+	return nil
+}
@@ -0,0 +1,338 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/pkg/format"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// minWrapColumns is the narrowest value column worth wrapping into
+const minWrapColumns = 20
+
+// Output formats supported by commands that describe backups
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// backupMetadata describes an encrypted file. It is the single source of
+// truth for the text, JSON and YAML renderings of verify, info and list.
+type backupMetadata struct {
+	Path         string                  `json:"path" yaml:"path"`
+	Valid        bool                    `json:"valid" yaml:"valid"`
+	Error        string                  `json:"error,omitempty" yaml:"error,omitempty"`
+	Version      string                  `json:"version" yaml:"version"`
+	Algorithm    string                  `json:"algorithm" yaml:"algorithm"`
+	KDF          string                  `json:"kdf" yaml:"kdf"`
+	Iterations   uint32                  `json:"iterations" yaml:"iterations"`
+	MemoryMB     uint32                  `json:"memory_mb" yaml:"memory_mb"`
+	Threads      uint8                   `json:"threads" yaml:"threads"`
+	Strength     string                  `json:"strength,omitempty" yaml:"strength,omitempty"`
+	Created      *time.Time              `json:"created,omitempty" yaml:"created,omitempty"`
+	Comment      string                  `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Hint         string                  `json:"hint,omitempty" yaml:"hint,omitempty"`
+	KeyType      string                  `json:"key_type,omitempty" yaml:"key_type,omitempty"`
+	KeyRole      string                  `json:"key_role,omitempty" yaml:"key_role,omitempty"`
+	Fingerprint  string                  `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	Certificate  *format.CertificateInfo `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	OriginalName string                  `json:"original_name,omitempty" yaml:"original_name,omitempty"`
+	Hostname     string                  `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Username     string                  `json:"username,omitempty" yaml:"username,omitempty"`
+	Members      int                     `json:"members,omitempty" yaml:"members,omitempty"`
+	Compression  string                  `json:"compression,omitempty" yaml:"compression,omitempty"`
+	Share        *format.ShareParams     `json:"share,omitempty" yaml:"share,omitempty"`
+	FastMode     bool                    `json:"fast_mode,omitempty" yaml:"fast_mode,omitempty"`
+	WeakKDF      bool                    `json:"weak_kdf,omitempty" yaml:"weak_kdf,omitempty"`
+	FutureTime   bool                    `json:"future_timestamp,omitempty" yaml:"future_timestamp,omitempty"`
+	ExpiresAt    *time.Time              `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Expired      bool                    `json:"expired,omitempty" yaml:"expired,omitempty"`
+	SaltLength   int                     `json:"salt_length" yaml:"salt_length"`
+	NonceLength  int                     `json:"nonce_length" yaml:"nonce_length"`
+	CipherLength int                     `json:"ciphertext_length" yaml:"ciphertext_length"`
+	TagLength    int                     `json:"tag_length" yaml:"tag_length"`
+
+	// EncryptedMetadata is set for --encrypt-metadata backups, whose
+	// descriptive fields are only filled in once revealed
+	EncryptedMetadata bool `json:"encrypted_metadata,omitempty" yaml:"encrypted_metadata,omitempty"`
+	MetadataRevealed  bool `json:"metadata_revealed,omitempty" yaml:"metadata_revealed,omitempty"`
+
+	// Remote is the verify --compare-remote result: match, mismatch or absent
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+}
+
+// newBackupMetadata collects the metadata of an encrypted file
+func newBackupMetadata(path string, encFile *format.EncryptedFile, validationErr error) backupMetadata {
+	metadata := backupMetadata{
+		Path:              path,
+		Valid:             validationErr == nil,
+		Version:           encFile.Header.Version,
+		Algorithm:         encFile.Header.Algorithm,
+		KDF:               encFile.Header.KDF,
+		Iterations:        encFile.Header.Iterations,
+		MemoryMB:          encFile.Header.Memory,
+		Threads:           encFile.Header.Threads,
+		Strength:          encFile.Header.Strength,
+		Comment:           encFile.Header.Comment,
+		Hint:              encFile.Header.Hint,
+		KeyType:           encFile.Header.KeyType,
+		KeyRole:           encFile.Header.KeyRole,
+		Fingerprint:       encFile.Header.Fingerprint,
+		Certificate:       encFile.Header.Certificate,
+		OriginalName:      encFile.Header.OriginalName,
+		Hostname:          encFile.Header.Hostname,
+		Username:          encFile.Header.Username,
+		Members:           encFile.Header.Members,
+		Compression:       encFile.Header.Compression,
+		Share:             encFile.Header.Share,
+		FastMode:          encFile.Header.FastMode,
+		WeakKDF:           crypto.IsWeakKDF(encFile.Header.Iterations, encFile.Header.Memory),
+		FutureTime:        encFile.Header.TimestampInFuture(time.Now()),
+		ExpiresAt:         encFile.Header.ExpiresAt,
+		Expired:           encFile.Header.Expired(time.Now()),
+		EncryptedMetadata: encFile.Metadata != nil,
+		SaltLength:        len(encFile.Salt),
+		NonceLength:       len(encFile.Nonce),
+		CipherLength:      len(encFile.Ciphertext),
+		TagLength:         len(encFile.Tag),
+	}
+
+	// Backups made with --no-metadata carry a zero timestamp
+	if !encFile.Header.Timestamp.IsZero() {
+		created := encFile.Header.Timestamp
+		metadata.Created = &created
+	}
+
+	if validationErr != nil {
+		metadata.Error = validationErr.Error()
+	}
+
+	return metadata
+}
+
+// printFileInformation prints the header section of the text rendering,
+// wrapping long values to width columns (0 disables wrapping)
+func printFileInformation(m backupMetadata, width int) {
+	fmt.Println("File Information:")
+	printField("Version", m.Version, width)
+	printField("Algorithm", m.Algorithm, width)
+	printField("KDF", m.KDF, width)
+	printField("KDF Iterations", fmt.Sprint(m.Iterations), width)
+	printField("KDF Memory", fmt.Sprintf("%d MB", m.MemoryMB), width)
+	printField("KDF Threads", fmt.Sprint(m.Threads), width)
+	if m.Strength != "" {
+		strength := crypto.DescribeStrength(m.Strength, m.Iterations, m.MemoryMB)
+		// The hint is unauthenticated, so say when it no longer fits
+		if !crypto.MatchesStrength(m.Strength, m.Iterations, m.MemoryMB, m.Threads) {
+			strength += ", which does not match the stored parameters"
+		}
+		printField("KDF Strength", strength, width)
+	}
+	if m.Created != nil {
+		printField("Created", m.Created.Format("2006-01-02 15:04:05 UTC"), width)
+	} else if m.EncryptedMetadata && !m.MetadataRevealed {
+		printField("Created", "(encrypted metadata)", width)
+	} else {
+		printField("Created", "(not recorded)", width)
+	}
+
+	if m.EncryptedMetadata && m.MetadataRevealed {
+		printField("Metadata", "encrypted (revealed with passphrase)", width)
+	} else if m.EncryptedMetadata {
+		printField("Metadata", "(encrypted metadata)", width)
+	}
+
+	if m.Comment != "" {
+		printField("Comment", format.SanitizeComment(m.Comment), width)
+	}
+	if m.Hint != "" {
+		printField("Passphrase hint", format.SanitizeComment(m.Hint)+" (unencrypted)", width)
+	}
+	if m.KeyType != "" {
+		printField("Key type", m.KeyType, width)
+	}
+	if m.KeyRole != "" {
+		printField("Key role", m.KeyRole, width)
+	}
+	if m.Fingerprint != "" {
+		printField("Fingerprint", m.Fingerprint, width)
+	}
+	if m.Certificate != nil {
+		printCertificate(m.Certificate, width)
+	}
+	if m.OriginalName != "" {
+		printField("Original name", m.OriginalName, width)
+	}
+	if m.Hostname != "" {
+		printField("Hostname", m.Hostname, width)
+	}
+	if m.Username != "" {
+		printField("Username", m.Username, width)
+	}
+	if m.Members > 0 {
+		printField("Contents", fmt.Sprintf("bundle of %d files", m.Members), width)
+	}
+	if m.Compression != "" {
+		printField("Compression", m.Compression, width)
+	}
+	if m.Share != nil {
+		printField("Split", m.Share.String(), width)
+	}
+	if m.FastMode {
+		printField("Mode", "fast (development KDF parameters)", width)
+	}
+	if m.ExpiresAt != nil {
+		printField("Expires", m.ExpiresAt.Format("2006-01-02 15:04:05 UTC"), width)
+	}
+}
+
+// printCertificate prints the OpenSSH certificate recorded in a backup. Its
+// fields come from the unauthenticated header, so they are sanitized.
+func printCertificate(cert *format.CertificateInfo, width int) {
+	certificate := format.SanitizeComment(cert.Type) + " certificate"
+	if cert.KeyID != "" {
+		certificate += fmt.Sprintf(" %q", format.SanitizeComment(cert.KeyID))
+	}
+	if cert.Serial != 0 {
+		certificate += fmt.Sprintf(", serial %d", cert.Serial)
+	}
+	printField("Certificate", certificate, width)
+	printField("Principals", format.SanitizeComment(cert.PrincipalList()), width)
+
+	validity := cert.Validity()
+	if cert.Expired(time.Now()) {
+		validity += " (expired)"
+	}
+	printField("Valid", validity, width)
+	if cert.CA != "" {
+		printField("Signed by", format.SanitizeComment(cert.CA), width)
+	}
+}
+
+// printKDFWarning flags backups whose KDF parameters are too weak to
+// protect a real key, such as those made with --fast
+func printKDFWarning(m backupMetadata) {
+	if !m.WeakKDF && !m.FastMode {
+		return
+	}
+
+	fmt.Println()
+	if m.FastMode {
+		printWarning(fmt.Sprintf("WARNING: %s was created in fast mode and is weakly protected.", m.Path))
+	} else {
+		printWarning(fmt.Sprintf("WARNING: %s is weakly protected by its KDF parameters.", m.Path))
+	}
+	fmt.Printf("   %d iterations and %d MB are below the recommended minimum of %s.\n",
+		m.Iterations, m.MemoryMB, crypto.SecureKDFFloor)
+	fmt.Println("   Restore it and back it up again with stronger parameters (without --fast).")
+}
+
+// printClockWarning flags backups dated implausibly far in the future,
+// whose timestamp came from a machine with a wrong clock
+func printClockWarning(m backupMetadata) {
+	if !m.FutureTime {
+		return
+	}
+
+	fmt.Println()
+	printWarning(fmt.Sprintf("WARNING: %s is dated %s, which is in the future.", m.Path, m.Created.Format("2006-01-02 15:04:05 UTC")))
+	fmt.Println("   The machine that made it probably had a wrong clock; do not rely on its timestamp")
+	fmt.Println("   to decide which backup is newest.")
+}
+
+// printExpiryWarning flags backups whose --expires time has passed
+func printExpiryWarning(m backupMetadata) {
+	if !m.Expired {
+		return
+	}
+
+	fmt.Println()
+	printWarning(fmt.Sprintf("WARNING: %s expired on %s.", m.Path, m.ExpiresAt.Format("2006-01-02 15:04:05 UTC")))
+	fmt.Println("   Rotate the key and back up the new one; restore --strict refuses expired backups.")
+}
+
+// printField prints an indented "label: value" line. When width is set,
+// value is word-wrapped and continuation lines are aligned under it.
+func printField(label, value string, width int) {
+	prefix := fmt.Sprintf("  %s: ", label)
+	available := width - len(prefix)
+	if width <= 0 || available < minWrapColumns || len(prefix)+lipgloss.Width(value) <= width {
+		fmt.Printf("%s%s\n", prefix, value)
+		return
+	}
+
+	lines := strings.Split(lipgloss.NewStyle().Width(available).Render(value), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	fmt.Printf("%s%s\n", prefix, strings.Join(lines, "\n"+strings.Repeat(" ", len(prefix))))
+}
+
+// resolveWrapWidth returns the width to wrap human output at: the explicit
+// --wrap-width, else the terminal width, else 0 so logs stay single-line
+func resolveWrapWidth(wrapWidth int) int {
+	if wrapWidth > 0 {
+		return wrapWidth
+	}
+	if !isTerminal() {
+		return 0
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// printCryptoParameters prints the cryptographic section of the text rendering
+func printCryptoParameters(m backupMetadata) {
+	fmt.Println("Cryptographic Parameters:")
+	fmt.Printf("  Salt length: %d bytes\n", m.SaltLength)
+	fmt.Printf("  Nonce length: %d bytes\n", m.NonceLength)
+	fmt.Printf("  Ciphertext length: %d bytes\n", m.CipherLength)
+	fmt.Printf("  Authentication tag length: %d bytes\n", m.TagLength)
+}
+
+// resolveOutputFormat validates --format, with --json as a shorthand
+func resolveOutputFormat(outputFormat string, asJSON bool) (string, error) {
+	if asJSON {
+		if outputFormat != outputText && outputFormat != outputJSON {
+			return "", fmt.Errorf("--json cannot be combined with --format %s", outputFormat)
+		}
+		return outputJSON, nil
+	}
+
+	switch outputFormat {
+	case outputText, outputJSON, outputYAML:
+		return outputFormat, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s (use: text, json, yaml)", outputFormat)
+	}
+}
+
+// writeStructured renders v as JSON or YAML on stdout
+func writeStructured(outputFormat string, v interface{}) error {
+	switch outputFormat {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case outputYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported structured format: %s", outputFormat)
+	}
+}
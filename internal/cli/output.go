@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sshhades/sshhades/internal/github"
+)
+
+var (
+	// Styles for beautiful CLI
+	titleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#7C3AED")).
+			MarginBottom(1)
+
+	successStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#10B981")).
+			Bold(true)
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#EF4444")).
+			Bold(true)
+
+	infoStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#3B82F6"))
+
+	promptStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F59E0B")).
+			Bold(true)
+)
+
+// Styled output for the interactive commands. Errors and warnings pass
+// through github.Redact, as they often quote API responses.
+
+func printTitle(text string) {
+	fmt.Println(titleStyle.Render("🔐 " + text))
+}
+
+func printSuccess(text string) {
+	fmt.Println(successStyle.Render("✅ " + text))
+}
+
+func printError(text string) {
+	fmt.Println(errorStyle.Render("❌ " + github.Redact(text)))
+}
+
+func printWarning(text string) {
+	fmt.Println(errorStyle.Render("⚠️  " + github.Redact(text)))
+}
+
+func printInfo(text string) {
+	fmt.Println(infoStyle.Render("ℹ️  " + text))
+}
+
+func printPrompt(text string) {
+	fmt.Print(promptStyle.Render("❓ " + text + ": "))
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fileOwner is the resolved --owner of restored files
+type fileOwner struct {
+	spec string
+	uid  int
+	gid  int
+}
+
+// resolveOwner parses a "user[:group]" --owner value. Names and numeric IDs
+// are both accepted, and the group defaults to the user's primary group.
+// Only root can give files away, so for other users it warns and returns nil.
+func resolveOwner(spec string) (*fileOwner, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	userName, groupName, hasGroup := strings.Cut(spec, ":")
+	u, err := lookupUser(userName)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has non-numeric uid %s (--owner is not supported on this platform)", userName, u.Uid)
+	}
+
+	gidStr := u.Gid
+	if hasGroup {
+		g, err := lookupGroup(groupName)
+		if err != nil {
+			return nil, err
+		}
+		gidStr = g.Gid
+	}
+
+	gid, err := strconv.Atoi(gidStr)
+	if err != nil {
+		return nil, fmt.Errorf("group has non-numeric gid %s (--owner is not supported on this platform)", gidStr)
+	}
+
+	if os.Geteuid() != 0 {
+		fmt.Printf("⚠️  Warning: --owner %s ignored; changing file ownership requires root\n", spec)
+		return nil, nil
+	}
+
+	return &fileOwner{spec: spec, uid: uid, gid: gid}, nil
+}
+
+// lookupUser finds a user by name, falling back to a numeric uid
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, convErr := strconv.Atoi(name); convErr == nil {
+		if u, idErr := user.LookupId(name); idErr == nil {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown --owner user %q: %w", name, err)
+}
+
+// lookupGroup finds a group by name, falling back to a numeric gid
+func lookupGroup(name string) (*user.Group, error) {
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		return g, nil
+	}
+	if _, convErr := strconv.Atoi(name); convErr == nil {
+		if g, idErr := user.LookupGroupId(name); idErr == nil {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown --owner group %q: %w", name, err)
+}
+
+// missingDirs returns the directories above path that do not exist yet,
+// deepest first, which writing path creates
+func missingDirs(path string) []string {
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		dirs = append(dirs, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return dirs
+}
+
+// apply hands the given files and directories to the owner; a nil owner
+// leaves them alone
+func (o *fileOwner) apply(paths ...string) error {
+	if o == nil {
+		return nil
+	}
+
+	for _, path := range paths {
+		if err := os.Chown(path, o.uid, o.gid); err != nil {
+			return fmt.Errorf("failed to change owner of %s: %w", path, err)
+		}
+	}
+
+	fmt.Printf("  Owner: %s\n", o.spec)
+	return nil
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestMissingDirs(t *testing.T) {
+	home := t.TempDir()
+	output := filepath.Join(home, ".ssh", "keys", "id_ed25519")
+
+	want := []string{filepath.Join(home, ".ssh", "keys"), filepath.Join(home, ".ssh")}
+	if got := missingDirs(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("missingDirs() = %v, want %v", got, want)
+	}
+	if got := missingDirs(filepath.Join(home, "id_ed25519")); got != nil {
+		t.Errorf("missingDirs() = %v, want nothing for an existing directory", got)
+	}
+}
+
+func TestRestoreOwnerCoversCreatedDirs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	if _, err := lookupUser("nobody"); err != nil {
+		t.Skip("no nobody user")
+	}
+
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	backup := filepath.Join(dir, "id_ed25519.enc")
+	t.Setenv("TEST_PASSPHRASE", "correct horse battery staple")
+	if err := runTestCommand(t, append([]string{"backup", "-i", key, "-o", backup, "--passphrase-env", "TEST_PASSPHRASE"}, testKDFArgs...)...); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+
+	home := t.TempDir()
+	output := filepath.Join(home, ".ssh", "id_ed25519")
+	if err := runTestCommand(t, "restore", "-i", backup, "-o", output, "--passphrase-env", "TEST_PASSPHRASE", "--owner", "nobody"); err != nil {
+		t.Fatalf("restore --owner error = %v", err)
+	}
+
+	nobody, _ := lookupUser("nobody")
+	for _, path := range []string{output, filepath.Dir(output)} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid := info.Sys().(*syscall.Stat_t).Uid; fmt.Sprint(uid) != nobody.Uid {
+			t.Errorf("%s is owned by uid %d, want %s", path, uid, nobody.Uid)
+		}
+	}
+
+	// The home directory existed before and keeps its owner
+	info, _ := os.Stat(home)
+	if uid := info.Sys().(*syscall.Stat_t).Uid; fmt.Sprint(uid) == nobody.Uid {
+		t.Errorf("%s was given to --owner although restore did not create it", home)
+	}
+}
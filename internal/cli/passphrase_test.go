@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/pkg/format"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// issueSecrets makes every passphrase prompt answer passphrase for the rest
+// of the test and returns the slices handed out, so the test can check that
+// the code that asked for them wiped them
+func issueSecrets(t *testing.T, passphrase string) *[][]byte {
+	t.Helper()
+
+	var issued [][]byte
+	previous := secretReader
+	t.Cleanup(func() { secretReader = previous })
+	secretReader = func(prompt, hint string) ([]byte, error) {
+		secret := []byte(passphrase)
+		issued = append(issued, secret)
+		return secret, nil
+	}
+	return &issued
+}
+
+// checkWiped fails unless at least one passphrase was read and every one
+// read has been cleared
+func checkWiped(t *testing.T, issued [][]byte) {
+	t.Helper()
+
+	if len(issued) == 0 {
+		t.Fatal("no passphrase was read")
+	}
+	for i, secret := range issued {
+		for _, b := range secret {
+			if b != 0 {
+				t.Errorf("passphrase %d was not wiped: %q", i+1, secret)
+				break
+			}
+		}
+	}
+}
+
+// writeTestKey writes a fresh Ed25519 private key and returns its path
+func writeTestKey(t *testing.T, dir string) string {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := gossh.MarshalPrivateKey(priv, "test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runTestCommand runs a subcommand of the root command with args
+func runTestCommand(t *testing.T, args ...string) error {
+	t.Helper()
+
+	root := NewRootCommand("test", "", "")
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	root.SetArgs(args)
+	return root.Execute()
+}
+
+var testKDFArgs = []string{"--iterations", "1", "--memory", "8", "--threads", "1"}
+
+func TestEncryptWithNewPassphraseClearsPassphrase(t *testing.T) {
+	issued := issueSecrets(t, "correct horse battery staple")
+
+	params := crypto.KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32}
+	if _, err := encryptWithNewPassphrase([]byte("key data"), format.AlgorithmAESGCM, params); err != nil {
+		t.Fatalf("encryptWithNewPassphrase() error = %v", err)
+	}
+	checkWiped(t, *issued)
+}
+
+func TestBackupClearsPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"success", nil, false},
+		// The hook runs after the backup is written, so the passphrase
+		// was read long before the command fails
+		{"hook fails", []string{"--post-hook", "false", "--strict-hook"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued := issueSecrets(t, "correct horse battery staple")
+
+			output := filepath.Join(t.TempDir(), "id_ed25519.enc")
+			args := append(append([]string{"backup", "-i", key, "-o", output}, testKDFArgs...), tt.args...)
+			if err := runTestCommand(t, args...); (err != nil) != tt.wantErr {
+				t.Fatalf("backup error = %v, want error %v", err, tt.wantErr)
+			}
+			checkWiped(t, *issued)
+		})
+	}
+}
+
+func TestRestoreClearsPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	backup := filepath.Join(dir, "id_ed25519.enc")
+
+	issueSecrets(t, "correct horse battery staple")
+	if err := runTestCommand(t, append([]string{"backup", "-i", key, "-o", backup}, testKDFArgs...)...); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    bool
+	}{
+		{"success", "correct horse battery staple", false},
+		{"wrong passphrase", "incorrect horse", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued := issueSecrets(t, tt.passphrase)
+
+			output := filepath.Join(t.TempDir(), "id_ed25519")
+			if err := runTestCommand(t, "restore", "-i", backup, "-o", output); (err != nil) != tt.wantErr {
+				t.Fatalf("restore error = %v, want error %v", err, tt.wantErr)
+			}
+			checkWiped(t, *issued)
+		})
+	}
+}
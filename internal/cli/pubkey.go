@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/ssh"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+type pubkeyFlags struct {
+	input         string
+	passphraseEnv string
+	promptLabel   string
+	comment       string
+}
+
+func NewPubkeyCmd() *cobra.Command {
+	flags := &pubkeyFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "pubkey",
+		Short: "Print the public key of an encrypted backup",
+		Long: `Decrypt a backup in memory and print the public half of the private key in
+authorized_keys format. Nothing is written to disk, and the decrypted key is
+cleared once the public key has been derived.`,
+		Example: `  # Authorize a backed-up key on a new server
+  sshhades pubkey -i id_ed25519.enc >> ~/.ssh/authorized_keys`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPubkey(cmd.Context(), flags)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "Path to encrypted SSH key file (required)")
+	cmd.Flags().StringVar(&flags.passphraseEnv, "passphrase-env", "", "Environment variable containing passphrase")
+	cmd.Flags().StringVar(&flags.promptLabel, "prompt-label", "", "Name shown in the passphrase prompt (defaults to the input file name)")
+	cmd.Flags().StringVarP(&flags.comment, "comment", "c", "", "Comment to append to the key (defaults to the backup comment)")
+	cmd.MarkFlagRequired("input")
+
+	return cmd
+}
+
+func runPubkey(ctx context.Context, flags *pubkeyFlags) error {
+	if err := storage.ValidatePath(flags.input); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+
+	if err := format.ValidateComment(flags.comment); err != nil {
+		return fmt.Errorf("invalid --comment: %w", err)
+	}
+
+	if !storage.FileExists(flags.input) {
+		return newFileError(fs.ErrNotExist, flags.input, "encrypted file not found: %s", flags.input)
+	}
+
+	encFile, err := loadValidEncryptedFile(flags.input)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShare(flags.input, encFile); err != nil {
+		return err
+	}
+
+	passphrase, err := readPassphrase(flags.passphraseEnv, passphrasePrompt(flags.promptLabel, flags.input))
+	if err != nil {
+		return fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	defer crypto.ClearBytes(passphrase)
+
+	// The key data and sealed metadata share one derived key
+	keys := crypto.NewKeyCache()
+	defer keys.Clear()
+
+	keyData, err := crypto.DecryptCached(encFile, passphrase, keys)
+	if err != nil {
+		return fmt.Errorf("decryption failed: %w", err)
+	}
+	defer crypto.ClearBytes(keyData)
+	if err := checkDeadline(ctx); err != nil {
+		return err
+	}
+
+	if err := revealMetadata(encFile, passphrase, keys); err != nil {
+		return err
+	}
+
+	members, err := unpackMembers(encFile, keyData)
+	if err != nil {
+		return err
+	}
+	keyData = members[0].Data
+
+	comment := flags.comment
+	if comment == "" {
+		// A newline in a crafted header comment would otherwise start a
+		// second authorized_keys entry
+		comment = format.SanitizeComment(encFile.Header.Comment)
+	}
+
+	line, err := ssh.AuthorizedKey(keyData, comment)
+	if err != nil {
+		return fmt.Errorf("failed to derive public key: %w", err)
+	}
+
+	// Only the key goes to stdout so it can be redirected or piped
+	fmt.Fprintln(os.Stdout, line)
+	return nil
+}
@@ -1,11 +1,20 @@
 package cli
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/sshhades/sshhades/internal/config"
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/storage"
 	"golang.org/x/term"
 )
 
@@ -17,21 +26,152 @@ func NewRootCommand(version, buildTime, gitCommit string) *cobra.Command {
 		Long: `SSH Hades is a secure tool for encrypting and backing up SSH keys.
 It uses AES-256-GCM encryption with Argon2id key derivation to protect your SSH keys.`,
 		Version: fmt.Sprintf("%s (built: %s, commit: %s)", version, buildTime, gitCommit),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			silenceForJSON(cmd)
+			if err := startDeadline(cmd); err != nil {
+				return err
+			}
+			if err := config.ApplySizeLimits(); err != nil {
+				return err
+			}
+			return expandPathFlags(cmd)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			stopDeadline(cmd)
+		},
 	}
 
+	// ReportError prints every error, with tokens redacted; cobra's own
+	// "Error:" line would print the raw message first
+	rootCmd.SilenceErrors = true
+
+	rootCmd.PersistentFlags().Bool("json-errors", false, "On failure, print the error to stderr as a JSON object (code, message, path, exit_code)")
+	rootCmd.PersistentFlags().BoolVar(&passphraseStdin, "passphrase-stdin", false, "When stdin is not a terminal, read passphrases and tokens from it one line at a time")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to every confirmation prompt, for unattended runs (each answer is printed)")
+	rootCmd.PersistentFlags().Duration("deadline", 0, "Abort the command with a timeout error if it runs longer than this (e.g. 10m); default is no limit")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		silenceForJSON(cmd)
+		return &usageError{err: err}
+	})
+
 	// Add subcommands
 	rootCmd.AddCommand(NewBackupCmd())
 	rootCmd.AddCommand(NewRestoreCmd())
 	rootCmd.AddCommand(NewListCmd())
 	rootCmd.AddCommand(NewVerifyCmd())
+	rootCmd.AddCommand(NewInfoCmd())
+	rootCmd.AddCommand(NewDumpCmd())
+	rootCmd.AddCommand(NewIdentifyCmd())
+	rootCmd.AddCommand(NewPubkeyCmd())
+	rootCmd.AddCommand(NewManifestCmd())
 	rootCmd.AddCommand(NewInteractiveCmd())
 	rootCmd.AddCommand(NewGitHubCmd())
+	rootCmd.AddCommand(NewRecoverCmd())
+	rootCmd.AddCommand(NewConfigCmd())
+	rootCmd.AddCommand(NewKDFBenchCmd())
+	rootCmd.AddCommand(NewSelfTestCmd())
+	rootCmd.AddCommand(NewGenPassCmd())
+	rootCmd.AddCommand(NewVersionCmd(version, buildTime, gitCommit))
 
 	return rootCmd
+}
 
-	return rootCmd
+// pathFlags are the flags holding local file or directory paths
+var pathFlags = []string{"input", "output", "directory", "output-dir", "like", "dir", "use-for-backup", "shares"}
+
+// expandPathFlags expands ~, ~user and environment variables in the path
+// flags given to cmd, so quoted or programmatic paths work like typed ones.
+// Each path of a repeatable flag such as --shares is expanded on its own.
+func expandPathFlags(cmd *cobra.Command) error {
+	for _, name := range pathFlags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed {
+			continue
+		}
+
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			paths := slice.GetSlice()
+			expanded := make([]string, len(paths))
+			for i, path := range paths {
+				var err error
+				if expanded[i], err = storage.ExpandPath(path); err != nil {
+					return fmt.Errorf("--%s: %w", name, err)
+				}
+			}
+			if err := slice.Replace(expanded); err != nil {
+				return err
+			}
+			continue
+		}
+
+		expanded, err := storage.ExpandPath(flag.Value.String())
+		if err != nil {
+			return fmt.Errorf("--%s: %w", name, err)
+		}
+		if err := flag.Value.Set(expanded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// passphraseStdin is set by --passphrase-stdin, which allows secrets to be
+// read as plain lines when stdin is not a terminal
+var passphraseStdin bool
+
+// assumeYes is set by --yes, which answers every yes/no prompt with yes
+var assumeYes bool
+
+// stdinReader is shared by every line read from stdin, so input buffered
+// for one read is not lost to the next
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// errNoTerminal is returned when a secret must be typed but stdin is not a
+// terminal and --passphrase-stdin was not given
+var errNoTerminal = errors.New("stdin is not a terminal")
+
+// stdinIsTerminal reports whether secrets can be typed without echo. Tests
+// swap it to reach the prompts only a terminal gets.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(syscall.Stdin))
 }
 
+// readSecret prompts for a secret on the terminal without echo. When stdin
+// is not a terminal it reads one line instead if --passphrase-stdin allows
+// it, and otherwise fails with a message naming hint as the alternative.
+func readSecret(prompt, hint string) ([]byte, error) {
+	if !stdinIsTerminal() {
+		if !passphraseStdin {
+			return nil, fmt.Errorf("cannot prompt: %w; %s, or pass --passphrase-stdin to read it from stdin", errNoTerminal, hint)
+		}
+		line, err := readStdinLine()
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		return []byte(strings.TrimRight(line, "\r\n")), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	state, _ := term.GetState(int(syscall.Stdin))
+	done := awaitInput(state)
+	secret, err := term.ReadPassword(int(syscall.Stdin))
+	done()
+	fmt.Fprintln(os.Stderr) // New line after password input
+	return secret, err
+}
+
+// readStdinLine reads one line from stdinReader, which the --deadline
+// input watchdog ends if the deadline passes while it waits
+func readStdinLine() (string, error) {
+	defer awaitInput(nil)()
+	return stdinReader.ReadString('\n')
+}
+
+// secretReader reads every passphrase typed or piped in. It is always
+// readSecret in production; tests swap it to keep the slices they hand out
+// and check that callers wipe them.
+var secretReader = readSecret
+
 // readPassphrase reads a passphrase from the user or environment
 func readPassphrase(envVar string, prompt string) ([]byte, error) {
 	// Try environment variable first
@@ -42,11 +182,13 @@ func readPassphrase(envVar string, prompt string) ([]byte, error) {
 	}
 
 	// Prompt user interactively
-	fmt.Fprint(os.Stderr, prompt)
-	passphrase, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Fprintln(os.Stderr) // New line after password input
-	
+	passphrase, err := secretReader(prompt, "set the passphrase in an environment variable and pass --passphrase-env VAR")
+	if errors.Is(err, errNoTerminal) {
+		// Callers already say what failed to be read
+		return nil, err
+	}
 	if err != nil {
+		crypto.ClearBytes(passphrase)
 		return nil, fmt.Errorf("failed to read passphrase: %w", err)
 	}
 
@@ -55,4 +197,74 @@ func readPassphrase(envVar string, prompt string) ([]byte, error) {
 	}
 
 	return passphrase, nil
-}
\ No newline at end of file
+}
+
+// readNewPassphrase reads a passphrase for encryption. When it is typed
+// interactively it must be entered twice, so a typo cannot lock the user
+// out of the backup.
+func readNewPassphrase(envVar, prompt, confirmPrompt string) ([]byte, error) {
+	// There is nobody to catch a typo when the passphrase is piped in
+	if envVar != "" && os.Getenv(envVar) != "" || !stdinIsTerminal() {
+		return readPassphrase(envVar, prompt)
+	}
+
+	passphrase, err := readPassphrase("", prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation, err := readPassphrase("", confirmPrompt)
+	if err != nil {
+		crypto.ClearBytes(passphrase)
+		return nil, err
+	}
+	defer crypto.ClearBytes(confirmation)
+
+	if !crypto.Equal(passphrase, confirmation) {
+		crypto.ClearBytes(passphrase)
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+// passphrasePrompt builds the decryption prompt, naming the file (or the
+// --prompt-label override) so several restores in one session are told apart
+func passphrasePrompt(label, path string) string {
+	if label == "" {
+		label = filepath.Base(path)
+	}
+	return fmt.Sprintf("Enter passphrase for %s: ", label)
+}
+
+// confirm asks a yes/no question and reports whether the user answered yes
+func confirm(prompt string) bool {
+	if assumedYes(prompt) {
+		return true
+	}
+	response := strings.ToLower(readLine(prompt))
+	return response == "y" || response == "yes"
+}
+
+// assumedYes reports whether --yes answers prompt, and logs the prompt with
+// the assumed answer to stderr so unattended runs record what was confirmed
+// without mixing it into output meant for pipes
+func assumedYes(prompt string) bool {
+	if !assumeYes {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%syes (assumed by --yes)\n", prompt)
+	return true
+}
+
+// readLine prints a prompt and returns the trimmed line typed by the user
+func readLine(prompt string) string {
+	fmt.Print(prompt)
+	response, _ := readStdinLine()
+	return strings.TrimSpace(response)
+}
+
+// isTerminal reports whether stdout is attached to a terminal
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// parseSplit validates --split K-of-N and the flags it can be used with. A
+// split backup is encrypted with a random data key instead of a passphrase
+// and written as N local files, each holding the ciphertext and one share
+// of the data key.
+func parseSplit(flags *backupFlags) error {
+	k, n, ok := strings.Cut(strings.ToLower(flags.split), "-of-")
+	threshold, errK := strconv.Atoi(k)
+	total, errN := strconv.Atoi(n)
+	if !ok || errK != nil || errN != nil {
+		return fmt.Errorf("invalid --split %q: use K-of-N, e.g. 2-of-3", flags.split)
+	}
+	if threshold < 2 || total < threshold || total > crypto.MaxShares {
+		return fmt.Errorf("invalid --split %q: need 2 <= K <= N <= %d", flags.split, crypto.MaxShares)
+	}
+
+	switch {
+	case flags.bundle || flags.directory != "" || flags.fromAgent || flags.input == stdinInput:
+		return fmt.Errorf("--split backs up a single key file; it cannot be combined with --bundle, --directory, --from-agent or stdin input")
+	case flags.githubUpload || slices.ContainsFunc(backupTargets(flags), func(t string) bool { return t != "local" }):
+		return fmt.Errorf("--split writes local share files only; upload the shares to separate places yourself")
+	case flags.passphraseEnv != "" || flags.passphrase != nil || flags.hint != "":
+		return fmt.Errorf("--split encrypts with a random key; no passphrase (or --hint) is used")
+	case flags.shredSource || flags.base64 || flags.hook.command != "":
+		return fmt.Errorf("--split cannot be combined with --shred-source, --base64 or --post-hook")
+	}
+
+	flags.splitThreshold, flags.splitTotal = threshold, total
+	return nil
+}
+
+// shareFilePaths returns the files a split backup is written to, e.g.
+// id_ed25519.share1-of-3.enc for an --output of id_ed25519.enc
+func shareFilePaths(output string, total int) []string {
+	base := strings.TrimSuffix(output, ".enc")
+	paths := make([]string, total)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("%s.share%d-of-%d.enc", base, i+1, total)
+	}
+	return paths
+}
+
+// writeShareFiles splits the data key encFile was encrypted with and saves
+// one file per share next to job.output. plaintext is what encFile
+// decrypts to, for --verify-after-backup.
+func writeShareFiles(flags *backupFlags, job backupJob, encFile *format.EncryptedFile, dataKey, plaintext []byte) error {
+	shares, err := crypto.SplitSecret(dataKey, flags.splitThreshold, flags.splitTotal)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, share := range shares {
+			crypto.ClearBytes(share)
+		}
+	}()
+
+	setID, err := crypto.GenerateShareSetID()
+	if err != nil {
+		return err
+	}
+
+	keyCheck := crypto.KeyCheck(dataKey, setID)
+
+	paths := shareFilePaths(job.output, flags.splitTotal)
+	for i, path := range paths {
+		file := *encFile
+		file.Header.Version = format.ShareVersion
+		file.Header.Share = &format.ShareParams{
+			SetID:     setID,
+			Threshold: flags.splitThreshold,
+			Total:     flags.splitTotal,
+			Index:     i + 1,
+			KeyCheck:  keyCheck,
+		}
+		file.Share = shares[i]
+
+		if err := storage.SaveEncryptedFile(path, &file); err != nil {
+			return fmt.Errorf("failed to save share %d: %w", i+1, err)
+		}
+		fmt.Printf("✓ Saved share %d of %d to %s\n", i+1, flags.splitTotal, path)
+		recordBackup(path, file.Header, []string{"local"})
+	}
+
+	if flags.verifyAfter {
+		if err := verifyShareFiles(paths[:flags.splitThreshold], dataKey, plaintext); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ SSH key encrypted and split into %d shares; any %d of them restore it:\n", flags.splitTotal, flags.splitThreshold)
+	fmt.Printf("  sshhades restore --shares %s -o <key>\n", strings.Join(paths[:flags.splitThreshold], ","))
+	fmt.Printf("⚠️  Keep the shares in separate places: any %d together restore the key without a passphrase, and fewer than %d cannot restore it at all\n", flags.splitThreshold, flags.splitThreshold)
+	return nil
+}
+
+// verifyShareFiles re-reads the first threshold share files, as restore
+// would, and checks that together they decrypt to plaintext. The remaining
+// shares carry the same ciphertext and are checked by restore when used.
+func verifyShareFiles(paths []string, dataKey, plaintext []byte) error {
+	fmt.Println("Verifying the written shares...")
+	if err := restoreFromShares(paths, dataKey, plaintext); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ The share files were written but cannot be restored; do not rely on them")
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
+	fmt.Println("✓ Shares re-read, combined and decrypted")
+	return nil
+}
+
+// restoreFromShares checks that paths combine to dataKey and that the
+// backup they hold decrypts to plaintext
+func restoreFromShares(paths []string, dataKey, plaintext []byte) error {
+	_, combined, err := loadShareFiles(paths)
+	if err != nil {
+		return err
+	}
+	defer crypto.ClearBytes(combined)
+
+	if !crypto.Equal(combined, dataKey) {
+		return fmt.Errorf("shares do not combine to the key the backup was encrypted with")
+	}
+	return verifyWrittenBackup(paths[0], combined, plaintext)
+}
+
+// loadShareFiles loads the share files of a split backup, checks that they
+// belong to the same split and that there are enough of them, and returns
+// the backup together with the data key rebuilt from the shares
+func loadShareFiles(paths []string) (*format.EncryptedFile, []byte, error) {
+	var first *format.EncryptedFile
+	seen := make(map[int]string)
+	shares := make([][]byte, 0, len(paths))
+
+	for _, path := range paths {
+		if err := storage.ValidatePath(path); err != nil {
+			return nil, nil, fmt.Errorf("invalid share path: %w", err)
+		}
+		if !storage.FileExists(path) {
+			return nil, nil, newFileError(fs.ErrNotExist, path, "share file not found: %s", path)
+		}
+
+		fmt.Printf("Loading share from %s...\n", path)
+		encFile, err := loadValidEncryptedFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		share := encFile.Header.Share
+		if share == nil {
+			return nil, nil, fmt.Errorf("%s is not a share of a split backup; restore it with --input", path)
+		}
+
+		if first == nil {
+			first = encFile
+		} else if err := sameSplit(first, encFile); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if previous, ok := seen[share.Index]; ok {
+			return nil, nil, fmt.Errorf("%s and %s are the same share (%d of %d)", previous, path, share.Index, share.Total)
+		}
+		seen[share.Index] = path
+		shares = append(shares, encFile.Share)
+	}
+
+	if first == nil {
+		return nil, nil, fmt.Errorf("--shares needs at least one file")
+	}
+	params := first.Header.Share
+	if len(shares) < params.Threshold {
+		return nil, nil, fmt.Errorf("only %d share(s) given; this backup needs any %d of its %d shares", len(shares), params.Threshold, params.Total)
+	}
+
+	dataKey, err := crypto.CombineShares(shares)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to combine shares: %w", err)
+	}
+	if params.KeyCheck != "" && !crypto.VerifyKeyCheck(dataKey, params.SetID, params.KeyCheck) {
+		crypto.ClearBytes(dataKey)
+		return nil, nil, fmt.Errorf("the shares do not combine to the key this backup was split from; one of them is damaged or altered")
+	}
+	fmt.Printf("✓ Combined %d of %d shares\n", len(shares), params.Total)
+	return first, dataKey, nil
+}
+
+// sameSplit reports whether two share files come from the same split: the
+// same set, threshold, key check and encrypted key
+func sameSplit(a, b *format.EncryptedFile) error {
+	pa, pb := a.Header.Share, b.Header.Share
+	if pa.SetID != pb.SetID || pa.Threshold != pb.Threshold || pa.Total != pb.Total || pa.KeyCheck != pb.KeyCheck {
+		return fmt.Errorf("share belongs to a different split")
+	}
+	if !crypto.Equal(a.Salt, b.Salt) || !crypto.Equal(a.Nonce, b.Nonce) || !crypto.Equal(a.Ciphertext, b.Ciphertext) || !crypto.Equal(a.Tag, b.Tag) {
+		return fmt.Errorf("share holds a different ciphertext than the other shares")
+	}
+	return nil
+}
+
+// checkNotShare refuses to open a share file with a passphrase, which would
+// only fail with a confusing decryption error
+func checkNotShare(path string, encFile *format.EncryptedFile) error {
+	if share := encFile.Header.Share; share != nil {
+		return fmt.Errorf("%s is %s of a split backup; restore it with --shares and at least %d share files", path, share, share.Threshold)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+func TestParseSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     backupFlags
+		threshold int
+		total     int
+		wantErr   bool
+	}{
+		{"2 of 3", backupFlags{split: "2-of-3"}, 2, 3, false},
+		{"upper case", backupFlags{split: "3-OF-5"}, 3, 5, false},
+		{"all shares", backupFlags{split: "4-of-4"}, 4, 4, false},
+		{"threshold of one", backupFlags{split: "1-of-3"}, 0, 0, true},
+		{"threshold above total", backupFlags{split: "3-of-2"}, 0, 0, true},
+		{"too many shares", backupFlags{split: "2-of-256"}, 0, 0, true},
+		{"not a split", backupFlags{split: "2/3"}, 0, 0, true},
+		{"with bundle", backupFlags{split: "2-of-3", bundle: true}, 0, 0, true},
+		{"with upload", backupFlags{split: "2-of-3", githubUpload: true}, 0, 0, true},
+		{"with remote target", backupFlags{split: "2-of-3", targets: []string{"bitbucket"}}, 0, 0, true},
+		{"with local target", backupFlags{split: "2-of-3", targets: []string{"local"}}, 2, 3, false},
+		{"with passphrase", backupFlags{split: "2-of-3", passphraseEnv: "SP"}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := tt.flags
+			err := parseSplit(&flags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSplit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if flags.splitThreshold != tt.threshold || flags.splitTotal != tt.total {
+				t.Errorf("parseSplit() = %d of %d, want %d of %d", flags.splitThreshold, flags.splitTotal, tt.threshold, tt.total)
+			}
+		})
+	}
+}
+
+func TestShareFilePaths(t *testing.T) {
+	got := shareFilePaths("backups/id_ed25519.enc", 3)
+	want := []string{
+		"backups/id_ed25519.share1-of-3.enc",
+		"backups/id_ed25519.share2-of-3.enc",
+		"backups/id_ed25519.share3-of-3.enc",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("shareFilePaths() = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreSharesChecksKey(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir)
+	if err := runTestCommand(t, append([]string{"backup", "-i", key, "-o", filepath.Join(dir, "id_ed25519.enc"), "--split", "2-of-3"}, testKDFArgs...)...); err != nil {
+		t.Fatalf("backup error = %v", err)
+	}
+	paths := shareFilePaths(filepath.Join(dir, "id_ed25519.enc"), 3)
+
+	output := filepath.Join(dir, "restored")
+	if err := runTestCommand(t, "restore", "--shares", paths[0]+","+paths[2], "-o", output); err != nil {
+		t.Fatalf("restore error = %v", err)
+	}
+	want, _ := os.ReadFile(key)
+	if got, _ := os.ReadFile(output); string(got) != string(want) {
+		t.Fatal("restore --shares did not give back the key")
+	}
+
+	// A damaged share still combines, but to the wrong key
+	encFile, err := storage.LoadEncryptedFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	encFile.Share[0] ^= 1
+	if err := storage.SaveEncryptedFile(paths[1], encFile); err != nil {
+		t.Fatal(err)
+	}
+	err = runTestCommand(t, "restore", "--shares", paths[0]+","+paths[1], "-o", filepath.Join(dir, "damaged"))
+	if err == nil || !strings.Contains(err.Error(), "damaged or altered") {
+		t.Errorf("restore with a damaged share error = %v, want the key check to fail", err)
+	}
+}
+
+func TestExpandSharesPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSHHADES_TEST_SHARES", "/srv/shares")
+
+	cmd := NewRestoreCmd()
+	if err := cmd.Flags().Set("shares", "$SSHHADES_TEST_SHARES/k.share1-of-3.enc,~/k.share2-of-3.enc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := expandPathFlags(cmd); err != nil {
+		t.Fatalf("expandPathFlags() error = %v", err)
+	}
+
+	got, _ := cmd.Flags().GetStringSlice("shares")
+	want := []string{"/srv/shares/k.share1-of-3.enc", filepath.Join(home, "k.share2-of-3.enc")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("--shares = %v, want %v", got, want)
+	}
+}
@@ -1,16 +1,36 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
+	"github.com/sshhades/sshhades/internal/config"
 	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/github"
 	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
 )
 
+// defaultVerifyConcurrency bounds parallel file checks in directory mode
+const defaultVerifyConcurrency = 8
+
 type verifyFlags struct {
-	input string
+	input         string
+	directory     string
+	concurrency   int
+	keepGoing     bool
+	outputFormat  string
+	json          bool
+	wrapWidth     int
+	strict        bool
+	compareRemote bool
 }
 
 func NewVerifyCmd() *cobra.Command {
@@ -21,25 +41,69 @@ func NewVerifyCmd() *cobra.Command {
 		Short: "Verify encrypted file integrity",
 		Long: `Verify the integrity and format of an encrypted SSH key file.
 This command checks the file format, metadata, and cryptographic parameters
-without requiring the passphrase.`,
+without requiring the passphrase.
+
+With --directory, every .enc file in the directory is checked in parallel and
+results are reported in sorted order. Every file is checked and reported; the
+command fails if any file is invalid, naming each one.
+
+Backups past their --expires time are reported with a warning, or fail with
+--strict.
+
+With --compare-remote, the copy of the file in the configured GitHub
+repository is downloaded and compared byte for byte with the local file,
+which must match before the local copy is deleted. This needs token
+authentication.`,
 		Example: `  # Verify an encrypted file
   sshhades verify --input ~/backups/id_ed25519.enc
   
   # Verify multiple files
   sshhades verify -i file1.enc
-  sshhades verify -i file2.enc`,
+  sshhades verify -i file2.enc
+
+  # Verify a whole backup directory using 16 workers
+  sshhades verify -d ~/backups --concurrency 16
+
+  # Machine-readable result
+  sshhades verify -i file1.enc --format yaml
+
+  # Check that GitHub holds an identical copy
+  sshhades verify -i ~/backups/id_ed25519.enc --compare-remote`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runVerify(flags)
+			return runVerify(cmd.Context(), flags)
 		},
 	}
 
-	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "Path to encrypted file to verify (required)")
-	cmd.MarkFlagRequired("input")
+	cmd.Flags().StringVarP(&flags.input, "input", "i", "", "Path to encrypted file to verify")
+	cmd.Flags().StringVarP(&flags.directory, "directory", "d", "", "Verify all encrypted files in this directory")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", defaultVerifyConcurrency, "Number of files verified in parallel with --directory")
+	cmd.Flags().BoolVar(&flags.keepGoing, "keep-going", false, "With --directory, continue past invalid files and report them at the end")
+	cmd.Flags().StringVar(&flags.outputFormat, "format", outputText, "Output format: text, json or yaml")
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Output as JSON (same as --format json)")
+	cmd.Flags().BoolVar(&flags.strict, "strict", false, "Fail for backups past their --expires time instead of warning")
+	cmd.Flags().BoolVar(&flags.compareRemote, "compare-remote", false, "Compare the file with its copy in the GitHub repository")
+	cmd.Flags().IntVar(&flags.wrapWidth, "wrap-width", 0, "Wrap long values at this width (default: terminal width, no wrapping when not a terminal)")
 
 	return cmd
 }
 
-func runVerify(flags *verifyFlags) error {
+func runVerify(ctx context.Context, flags *verifyFlags) error {
+	outputFormat, err := resolveOutputFormat(flags.outputFormat, flags.json)
+	if err != nil {
+		return err
+	}
+
+	if flags.directory != "" {
+		if flags.input != "" || flags.compareRemote {
+			return fmt.Errorf("--directory cannot be combined with --input/--compare-remote")
+		}
+		return runVerifyDirectory(ctx, flags, outputFormat)
+	}
+
+	if flags.input == "" {
+		return fmt.Errorf("--input is required (or use --directory)")
+	}
+
 	// Validate input path
 	if err := storage.ValidatePath(flags.input); err != nil {
 		return fmt.Errorf("invalid input path: %w", err)
@@ -47,11 +111,9 @@ func runVerify(flags *verifyFlags) error {
 
 	// Check if file exists
 	if !storage.FileExists(flags.input) {
-		return fmt.Errorf("file not found: %s", flags.input)
+		return newFileError(fs.ErrNotExist, flags.input, "file not found: %s", flags.input)
 	}
 
-	fmt.Printf("Verifying encrypted file: %s\n\n", flags.input)
-
 	// Load encrypted file
 	encFile, err := storage.LoadEncryptedFile(flags.input)
 	if err != nil {
@@ -59,9 +121,33 @@ func runVerify(flags *verifyFlags) error {
 	}
 
 	// Validate encrypted file format
-	if err := crypto.ValidateEncryptedFile(encFile); err != nil {
-		fmt.Printf("❌ Validation failed: %v\n", err)
-		return nil
+	validationErr := crypto.ValidateEncryptedFile(encFile)
+	metadata := newBackupMetadata(flags.input, encFile, validationErr)
+
+	var remoteErr error
+	if flags.compareRemote {
+		metadata.Remote, remoteErr = compareRemote(ctx, flags.input, encFile.Header)
+		if metadata.Remote == "" {
+			return remoteErr
+		}
+	}
+
+	if outputFormat != outputText {
+		if err := writeStructured(outputFormat, metadata); err != nil {
+			return err
+		}
+		if remoteErr != nil {
+			return remoteErr
+		}
+		return strictExpiryError(flags, metadata)
+	}
+
+	fmt.Printf("Verifying encrypted file: %s\n\n", flags.input)
+
+	if validationErr != nil {
+		fmt.Printf("❌ Validation failed: %v\n", validationErr)
+		printRemoteComparison(metadata)
+		return remoteErr
 	}
 
 	// File is valid, show details
@@ -69,29 +155,226 @@ func runVerify(flags *verifyFlags) error {
 	fmt.Println()
 
 	// Display file information
-	fmt.Println("File Information:")
-	fmt.Printf("  Version: %s\n", encFile.Header.Version)
-	fmt.Printf("  Algorithm: %s\n", encFile.Header.Algorithm)
-	fmt.Printf("  KDF: %s\n", encFile.Header.KDF)
-	fmt.Printf("  KDF Iterations: %d\n", encFile.Header.Iterations)
-	fmt.Printf("  KDF Memory: %d MB\n", encFile.Header.Memory)
-	fmt.Printf("  KDF Threads: %d\n", encFile.Header.Threads)
-	fmt.Printf("  Created: %s\n", encFile.Header.Timestamp.Format("2006-01-02 15:04:05 UTC"))
-	
-	if encFile.Header.Comment != "" {
-		fmt.Printf("  Comment: %s\n", encFile.Header.Comment)
-	}
-
+	printFileInformation(metadata, resolveWrapWidth(flags.wrapWidth))
 	fmt.Println()
-	fmt.Println("Cryptographic Parameters:")
-	fmt.Printf("  Salt length: %d bytes\n", len(encFile.Salt))
-	fmt.Printf("  Nonce length: %d bytes\n", len(encFile.Nonce))
-	fmt.Printf("  Ciphertext length: %d bytes\n", len(encFile.Ciphertext))
-	fmt.Printf("  Authentication tag length: %d bytes\n", len(encFile.Tag))
+	printCryptoParameters(metadata)
 
 	// Get absolute path for display
 	absPath, _ := filepath.Abs(flags.input)
 	fmt.Printf("\n✓ File %s is a valid encrypted SSH key backup\n", absPath)
+	printKDFWarning(metadata)
+	printClockWarning(metadata)
+	printExpiryWarning(metadata)
+	printRemoteComparison(metadata)
+
+	if remoteErr != nil {
+		return remoteErr
+	}
+	return strictExpiryError(flags, metadata)
+}
 
+// compareRemote downloads the repository copy of the backup file and
+// compares it byte for byte with the local file. It returns "match",
+// "mismatch" or "absent", with an error for the latter two, or an empty
+// result when the comparison could not be made. Backups made with
+// --tag-host are looked up in their host directory.
+func compareRemote(ctx context.Context, file string, header format.Header) (string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.IsGitHubConfigured() && cfg.GetGitHubConfig().AuthMethod != "token" {
+		return "", fmt.Errorf("--compare-remote requires GitHub token authentication")
+	}
+
+	sink, err := github.NewSink(cfg, "")
+	if err != nil {
+		return "", err
+	}
+	sink.Context = ctx
+	if header.Hostname != "" {
+		sink.Dir = path.Join(github.DefaultBackupDir, sanitizeFilename(header.Hostname))
+	}
+
+	local, err := storage.ReadFileLimited(file, storage.MaxEncryptedFileSize, "encrypted file")
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(file)
+	remotePath := path.Join(sink.Dir, name)
+	remote, err := sink.Read(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "absent", newFileError(fs.ErrNotExist, file, "%s is not in the repository (looked for %s)", file, remotePath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch remote copy: %w", err)
+	}
+
+	if !crypto.Equal(local, remote) {
+		return "mismatch", newFileError(errRemoteMismatch, file, "%s differs from the repository copy at %s", file, remotePath)
+	}
+	return "match", nil
+}
+
+// printRemoteComparison reports a --compare-remote result
+func printRemoteComparison(m backupMetadata) {
+	switch m.Remote {
+	case "match":
+		fmt.Println("✓ Remote copy matches byte for byte")
+	case "mismatch":
+		fmt.Println("❌ Remote copy differs from the local file")
+	case "absent":
+		fmt.Println("❌ No remote copy found")
+	}
+}
+
+// strictExpiryError fails verify --strict for an expired backup
+func strictExpiryError(flags *verifyFlags, m backupMetadata) error {
+	if !flags.strict || !m.Expired {
+		return nil
+	}
+	return newFileError(errBackupExpired, m.Path, "%s expired on %s", m.Path, m.ExpiresAt.Format("2006-01-02 15:04:05 UTC"))
+}
+
+// runVerifyDirectory checks every encrypted file in a directory using a
+// bounded worker pool. Results are collected by index so the report keeps
+// the sorted file order regardless of which worker finishes first.
+func runVerifyDirectory(ctx context.Context, flags *verifyFlags, outputFormat string) error {
+	if flags.concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	if err := storage.ValidatePath(flags.directory); err != nil {
+		return fmt.Errorf("invalid directory path: %w", err)
+	}
+
+	inputs, err := findBackupFiles(flags.directory)
+	if err != nil {
+		return fmt.Errorf("failed to search for encrypted files: %w", err)
+	}
+
+	results := make([]backupMetadata, len(inputs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < flags.concurrency && w < len(inputs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = verifyFile(inputs[i])
+			}
+		}()
+	}
+
+	// Files not yet handed out once the deadline passes are not checked
+	for i := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := checkDeadline(ctx); err != nil {
+		return err
+	}
+
+	// With --strict an expired backup counts as invalid
+	if flags.strict {
+		for i, result := range results {
+			if result.Valid && result.Expired {
+				results[i].Valid = false
+				results[i].Error = fmt.Sprintf("expired on %s", result.ExpiresAt.Format("2006-01-02 15:04:05 UTC"))
+			}
+		}
+	}
+
+	// Without --keep-going the run stops at the first invalid file, so the
+	// report ends with it
+	first := -1
+	for i, result := range results {
+		if !result.Valid {
+			first = i
+			break
+		}
+	}
+	if first >= 0 && !flags.keepGoing {
+		results = results[:first+1]
+	}
+
+	if outputFormat != outputText {
+		if err := writeStructured(outputFormat, results); err != nil {
+			return err
+		}
+		if first >= 0 && !flags.keepGoing {
+			return fmt.Errorf("%s: %s", results[first].Path, results[first].Error)
+		}
+		return invalidFilesError(results)
+	}
+
+	fmt.Printf("Verifying %d encrypted file(s) in %s\n\n", len(inputs), flags.directory)
+	run := &batchRun{keepGoing: flags.keepGoing}
+	for _, result := range results {
+		if !result.Valid {
+			if err := run.fail(result.Path, errors.New(result.Error)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var warnings []string
+		if result.WeakKDF || result.FastMode {
+			warnings = append(warnings, fmt.Sprintf("weak KDF parameters: %d iterations, %d MB", result.Iterations, result.MemoryMB))
+		}
+		if result.FutureTime {
+			warnings = append(warnings, fmt.Sprintf("timestamp %s is in the future", result.Created.Format("2006-01-02 15:04:05 UTC")))
+		}
+		if result.Expired {
+			warnings = append(warnings, fmt.Sprintf("expired on %s", result.ExpiresAt.Format("2006-01-02 15:04:05 UTC")))
+		}
+
+		if len(warnings) > 0 {
+			fmt.Printf("⚠️  %s (%s)\n", result.Path, strings.Join(warnings, "; "))
+		} else {
+			fmt.Printf("✓ %s\n", result.Path)
+		}
+	}
+
+	if err := run.finish(len(results)); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ All %d file(s) are valid\n", len(results))
 	return nil
-}
\ No newline at end of file
+}
+
+// invalidFilesError names every invalid file in results, or returns nil
+// when all of them are valid
+func invalidFilesError(results []backupMetadata) error {
+	var invalid []string
+	for _, result := range results {
+		if !result.Valid {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", result.Path, result.Error))
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d file(s) invalid: %s", len(invalid), len(results), strings.Join(invalid, "; "))
+}
+
+// verifyFile loads and validates a single encrypted file, recording any
+// failure in the returned metadata instead of aborting
+func verifyFile(path string) backupMetadata {
+	encFile, err := storage.LoadEncryptedFile(path)
+	if err != nil {
+		return backupMetadata{
+			Path:  path,
+			Error: fmt.Sprintf("failed to load encrypted file: %v", err),
+		}
+	}
+
+	return newBackupMetadata(path, encFile, crypto.ValidateEncryptedFile(encFile))
+}
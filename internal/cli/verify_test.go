@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/internal/storage"
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// writeVerifyDirectory fills a directory with a valid backup, b.enc,
+// between two invalid files, a.enc and c.enc
+func writeVerifyDirectory(t *testing.T) string {
+	t.Helper()
+
+	params := crypto.KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32}
+	result, err := crypto.Encrypt([]byte("key data"), []byte("passphrase"), format.AlgorithmAESGCM, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := format.DefaultHeader()
+	header.Iterations, header.Memory, header.Threads = params.Iterations, params.Memory, params.Threads
+	encFile := &format.EncryptedFile{Header: header, Salt: result.Salt, Nonce: result.Nonce, Ciphertext: result.Ciphertext, Tag: result.Tag}
+
+	dir := t.TempDir()
+	if err := storage.SaveEncryptedFile(filepath.Join(dir, "b.enc"), encFile); err != nil {
+		t.Fatal(err)
+	}
+	// Invalid files sort before and after the valid one
+	for _, name := range []string{"a.enc", "c.enc"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("not a backup"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestVerifyDirectoryReportsEveryInvalidFile(t *testing.T) {
+	dir := writeVerifyDirectory(t)
+
+	for _, outputFormat := range []string{outputText, outputJSON, outputYAML} {
+		t.Run(outputFormat, func(t *testing.T) {
+			err := runVerifyDirectory(context.Background(), &verifyFlags{directory: dir, concurrency: 2, keepGoing: true}, outputFormat)
+			if err == nil {
+				t.Fatal("runVerifyDirectory() should fail for invalid files")
+			}
+			if !strings.Contains(err.Error(), "2 of 3") {
+				t.Errorf("runVerifyDirectory() error = %v, want both invalid files counted", err)
+			}
+			if outputFormat != outputText {
+				for _, name := range []string{"a.enc", "c.enc"} {
+					if !strings.Contains(err.Error(), filepath.Join(dir, name)) {
+						t.Errorf("runVerifyDirectory() error = %v, want it to name %s", err, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyDirectoryStopsAtFirstInvalidFile(t *testing.T) {
+	dir := writeVerifyDirectory(t)
+
+	for _, outputFormat := range []string{outputText, outputJSON} {
+		t.Run(outputFormat, func(t *testing.T) {
+			err := runVerifyDirectory(context.Background(), &verifyFlags{directory: dir, concurrency: 2}, outputFormat)
+			if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "a.enc")) {
+				t.Fatalf("runVerifyDirectory() error = %v, want it to name a.enc", err)
+			}
+			if strings.Contains(err.Error(), "c.enc") {
+				t.Errorf("runVerifyDirectory() error = %v, want it to stop before c.enc", err)
+			}
+		})
+	}
+}
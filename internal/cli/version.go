@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// versionInfo is the machine-readable build information
+type versionInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GitCommit string `json:"git_commit"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+func NewVersionCmd(version, buildTime, gitCommit string) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version information",
+		Long: `Show the sshhades version, build time and commit along with the Go runtime
+and platform. Use --json for output that scripts and bug reports can consume.`,
+		Example: `  # Human-readable version
+  sshhades version
+
+  # Machine-readable version
+  sshhades version --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := versionInfo{
+				Version:   version,
+				BuildTime: buildTime,
+				GitCommit: gitCommit,
+				GoVersion: runtime.Version(),
+				OS:        runtime.GOOS,
+				Arch:      runtime.GOARCH,
+			}
+
+			if asJSON {
+				data, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal version: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "sshhades %s (built: %s, commit: %s)\n", info.Version, info.BuildTime, info.GitCommit)
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s/%s\n", info.GoVersion, info.OS, info.Arch)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Output version information as JSON")
+
+	return cmd
+}
@@ -0,0 +1,18 @@
+package config
+
+// BitbucketConfig holds Bitbucket Cloud credentials and the backup repository
+type BitbucketConfig struct {
+	Workspace   string `json:"workspace"`
+	Repo        string `json:"repo"`
+	Username    string `json:"username"`
+	AppPassword string `json:"app_password,omitempty"`
+
+	// TimeoutSeconds bounds each API request; zero uses the default
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// IsBitbucketConfigured checks if Bitbucket is configured
+func (c *Config) IsBitbucketConfigured() bool {
+	b := c.Bitbucket
+	return b != nil && b.Workspace != "" && b.Repo != "" && b.Username != "" && b.AppPassword != ""
+}
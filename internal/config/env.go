@@ -0,0 +1,100 @@
+package config
+
+import "os"
+
+// Environment variables that supply GitHub settings without a config file.
+// Each one that is set takes precedence over the file.
+const (
+	EnvGitHubToken      = "SSHHADES_GITHUB_TOKEN"
+	EnvGitHubUsername   = "SSHHADES_GITHUB_USERNAME"
+	EnvGitHubRepo       = "SSHHADES_GITHUB_REPO"
+	EnvGitHubOwner      = "SSHHADES_GITHUB_OWNER"
+	EnvGitHubAuthMethod = "SSHHADES_GITHUB_AUTH_METHOD"
+	EnvGitHubBaseURL    = "SSHHADES_GITHUB_BASE_URL"
+)
+
+// githubEnvFields pairs each variable with the field it sets
+func githubEnvFields(g *GitHubConfig) []struct {
+	name  string
+	field *string
+} {
+	return []struct {
+		name  string
+		field *string
+	}{
+		{EnvGitHubToken, &g.Token},
+		{EnvGitHubUsername, &g.Username},
+		{EnvGitHubRepo, &g.RepoName},
+		{EnvGitHubOwner, &g.RepoOwner},
+		{EnvGitHubAuthMethod, &g.AuthMethod},
+		{EnvGitHubBaseURL, &g.BaseURL},
+	}
+}
+
+// applyGitHubEnv merges SSHHADES_GITHUB_* variables over the GitHub
+// section. A token from the environment implies token authentication
+// unless an auth method is given as well.
+func (c *Config) applyGitHubEnv() {
+	env := &GitHubConfig{}
+	set := false
+	for _, f := range githubEnvFields(env) {
+		if value := os.Getenv(f.name); value != "" {
+			*f.field = value
+			set = true
+		}
+	}
+	if !set {
+		return
+	}
+	if env.Token != "" && env.AuthMethod == "" {
+		env.AuthMethod = "token"
+	}
+
+	if c.GitHub == nil {
+		c.GitHub = &GitHubConfig{}
+	} else {
+		file := *c.GitHub
+		c.githubFile = &file
+	}
+	c.githubEnv = env
+
+	merged := githubEnvFields(c.GitHub)
+	for i, f := range githubEnvFields(env) {
+		if *f.field != "" {
+			*merged[i].field = *f.field
+		}
+	}
+}
+
+// withoutGitHubEnv returns a copy of the GitHub section with every field
+// that still holds its environment value reset to the value from the file,
+// or nil when nothing would be left of a section the file did not have
+func (c *Config) withoutGitHubEnv() *GitHubConfig {
+	github := *c.GitHub
+	if c.githubEnv == nil {
+		return &github
+	}
+
+	file := GitHubConfig{}
+	if c.githubFile != nil {
+		file = *c.githubFile
+	}
+
+	fileFields := githubEnvFields(&file)
+	envFields := githubEnvFields(c.githubEnv)
+	for i, f := range githubEnvFields(&github) {
+		if env := *envFields[i].field; env != "" && *f.field == env {
+			*f.field = *fileFields[i].field
+		}
+	}
+
+	if c.githubFile == nil && github == (GitHubConfig{}) {
+		return nil
+	}
+	return &github
+}
+
+// GitHubFromEnv reports whether any GitHub setting came from the environment
+func (c *Config) GitHubFromEnv() bool {
+	return c.githubEnv != nil
+}
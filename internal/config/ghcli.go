@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// TokenSourceGH is the GitHubConfig.TokenSource of a login that reuses the
+// token of the GitHub CLI instead of storing one
+const TokenSourceGH = "gh"
+
+// ErrGHNotInstalled is returned by GHToken when the gh command is not found
+var ErrGHNotInstalled = errors.New("the GitHub CLI (gh) is not installed")
+
+// ghTokenTimeout bounds the gh auth token run when the config is loaded,
+// which happens outside any command's --deadline
+const ghTokenTimeout = 10 * time.Second
+
+// GHToken returns the token the GitHub CLI is logged in with for the server
+// at baseURL (github.com when empty), as printed by gh auth token. gh is
+// stopped when ctx ends.
+func GHToken(ctx context.Context, baseURL string) (string, error) {
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		return "", ErrGHNotInstalled
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "auth", "token", "--hostname", ghHost(baseURL))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("gh auth token failed: %w", ctx.Err())
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("gh auth token failed: %s", message)
+		}
+		return "", fmt.Errorf("gh auth token failed: %w", err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", fmt.Errorf("the GitHub CLI is not logged in to %s (run 'gh auth login')", ghHost(baseURL))
+	}
+	return token, nil
+}
+
+// ghHost returns the host name gh knows the server at baseURL by
+func ghHost(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "github.com"
+}
+
+// loadGHToken fills in the token of a login that reuses the GitHub CLI. When
+// gh cannot provide one the token stays empty, so GitHub reads as not
+// configured instead of failing every command; GHTokenError explains why.
+func (c *Config) loadGHToken() {
+	if c.GitHub == nil || c.GitHub.TokenSource != TokenSourceGH {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ghTokenTimeout)
+	defer cancel()
+	token, err := GHToken(ctx, c.GitHub.BaseURL)
+	if err != nil {
+		c.ghTokenErr = err
+		return
+	}
+	c.GitHub.Token = token
+}
+
+// GHTokenError returns why the token of a GitHub CLI login could not be
+// read, or nil
+func (c *Config) GHTokenError() error {
+	return c.ghTokenErr
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeGH installs a gh script that runs body, returning its arguments file
+func fakeGH(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh is a shell script")
+	}
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + args + "\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	return args
+}
+
+func TestGHToken(t *testing.T) {
+	args := fakeGH(t, "echo gho_from_gh")
+
+	token, err := GHToken(context.Background(), "https://github.example.com/")
+	if err != nil {
+		t.Fatalf("GHToken() error = %v", err)
+	}
+	if token != "gho_from_gh" {
+		t.Errorf("GHToken() = %q, want gho_from_gh", token)
+	}
+	data, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "auth token --hostname github.example.com" {
+		t.Errorf("gh called with %q", got)
+	}
+}
+
+func TestGHTokenNotLoggedIn(t *testing.T) {
+	fakeGH(t, "echo 'no oauth token found for github.com' >&2; exit 1")
+
+	_, err := GHToken(context.Background(), "")
+	if err == nil || !strings.Contains(err.Error(), "no oauth token") {
+		t.Errorf("GHToken() error = %v, want gh's message", err)
+	}
+}
+
+func TestGHTokenNotInstalled(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, err := GHToken(context.Background(), ""); !errors.Is(err, ErrGHNotInstalled) {
+		t.Errorf("GHToken() error = %v, want ErrGHNotInstalled", err)
+	}
+}
+
+func TestGHTokenSourceIsNotSaved(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	fakeGH(t, "echo gho_from_gh")
+
+	cfg := &Config{GitHub: &GitHubConfig{Token: "gho_from_gh", Username: "octocat", AuthMethod: "token", TokenSource: TokenSourceGH}}
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	path, err := getConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "gho_from_gh") {
+		t.Error("token from gh was written to the config file")
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if loaded.GitHub.Token != "gho_from_gh" || !loaded.IsGitHubConfigured() {
+		t.Errorf("LoadConfig() token = %q, want the token from gh", loaded.GitHub.Token)
+	}
+
+	// Without gh the login reads as not configured, with the reason kept
+	t.Setenv("PATH", t.TempDir())
+	loaded, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if loaded.IsGitHubConfigured() || !errors.Is(loaded.GHTokenError(), ErrGHNotInstalled) {
+		t.Errorf("IsGitHubConfigured() = %v, GHTokenError() = %v", loaded.IsGitHubConfigured(), loaded.GHTokenError())
+	}
+}
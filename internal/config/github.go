@@ -3,8 +3,12 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+
+	"github.com/sshhades/sshhades/internal/storage"
 )
 
 // GitHubConfig holds GitHub authentication configuration
@@ -15,20 +19,104 @@ type GitHubConfig struct {
 	SSHKeyPath string `json:"ssh_key_path,omitempty"`
 	RepoName   string `json:"repo_name"`
 	RepoOwner  string `json:"repo_owner"`
+
+	// BaseURL is the address of a GitHub Enterprise Server, such as
+	// https://github.example.com/; empty means github.com
+	BaseURL string `json:"base_url,omitempty"`
+
+	// ProtectToken asks for Token to be stored encrypted with Windows DPAPI.
+	// It has no effect on other platforms.
+	ProtectToken bool `json:"protect_token,omitempty"`
+
+	// ProtectedToken is the DPAPI-protected Token as written to disk when
+	// ProtectToken is set; Token is then omitted from the file
+	ProtectedToken string `json:"protected_token,omitempty"`
+
+	// TokenSource is "gh" when the token is read from the GitHub CLI each
+	// time the config is loaded; Token is then never written to disk
+	TokenSource string `json:"token_source,omitempty"`
+
+	// CommitAuthorName and CommitAuthorEmail set the author and committer of
+	// commits sshhades makes, e.g. "sshhades-bot" for a shared service token.
+	// When unset GitHub attributes commits to the token owner.
+	CommitAuthorName  string `json:"commit_author_name,omitempty"`
+	CommitAuthorEmail string `json:"commit_author_email,omitempty"`
+
+	// LastSyncedSHA is the repository commit the last github sync --pull
+	// reached, the starting point of --since-commit last
+	LastSyncedSHA string `json:"last_synced_sha,omitempty"`
+}
+
+// ValidateBaseURL checks that a GitHub Enterprise address is an absolute
+// https URL. Plain http is refused because the token and the encrypted
+// backups would cross the network unprotected. An empty address
+// (github.com) is valid.
+func ValidateBaseURL(baseURL string) error {
+	if baseURL == "" {
+		return nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid GitHub Enterprise URL %q: use an address such as https://github.example.com", baseURL)
+	}
+	if u.Scheme == "http" {
+		return fmt.Errorf("GitHub Enterprise URL %q does not use https: the token would be sent in clear text", baseURL)
+	}
+	return nil
+}
+
+// ValidateCommitAuthor checks that the commit author name and email are
+// either both set or both empty, as GitHub needs both
+func (g *GitHubConfig) ValidateCommitAuthor() error {
+	if (g.CommitAuthorName == "") != (g.CommitAuthorEmail == "") {
+		return fmt.Errorf("commit_author_name and commit_author_email must be set together")
+	}
+	return nil
 }
 
 // Config holds application configuration
 type Config struct {
 	GitHub *GitHubConfig `json:"github,omitempty"`
+
+	Bitbucket *BitbucketConfig `json:"bitbucket,omitempty"`
+
+	// DefaultOutputDir is where backups go when no output path is given.
+	// It may contain ~ and environment variables.
+	DefaultOutputDir string `json:"default_output_dir,omitempty"`
+
+	// KDFProfiles holds per-algorithm KDF defaults, keyed by "aes" or "chacha20"
+	KDFProfiles map[string]KDFProfile `json:"kdf_profiles,omitempty"`
+
+	// githubEnv holds the GitHub fields taken from SSHHADES_GITHUB_*
+	// variables and githubFile the GitHub section as read from the file, so
+	// SaveConfig never writes environment values to disk
+	githubEnv  *GitHubConfig
+	githubFile *GitHubConfig
+
+	// ghTokenErr is why the GitHub CLI did not provide a token
+	ghTokenErr error
+}
+
+// Dir returns the sshhades configuration directory, creating it if needed
+func Dir() (string, error) {
+	return getConfigDir()
+}
+
+// DirPath returns the sshhades configuration directory without creating it
+func DirPath() (string, error) {
+	baseDir, err := configBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "sshhades"), nil
 }
 
 func getConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := DirPath()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
-	
-	configDir := filepath.Join(homeDir, ".config", "sshhades")
+
 	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return "", fmt.Errorf("failed to create config directory: %w", err)
 	}
@@ -36,16 +124,53 @@ func getConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// configBaseDir returns $XDG_CONFIG_HOME, or ~/.config when it is unset or
+// relative (which the XDG spec says to ignore). macOS and Windows always use
+// ~/.config so existing configs there are not orphaned.
+func configBaseDir() (string, error) {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+			return xdg, nil
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, ".config"), nil
+}
+
 func getConfigPath() (string, error) {
 	configDir, err := getConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "config.json"), nil
+	return filepath.Join(configDir, FileName), nil
 }
 
-// LoadConfig loads configuration from file
+// FileName is the name of the config file inside the config directory
+const FileName = "config.json"
+
+// MaxConfigFileSize bounds how much of the config file LoadConfig reads;
+// SSHHADES_MAX_CONFIG_SIZE overrides it
+var MaxConfigFileSize int64 = 256 << 10
+
+// LoadConfig loads configuration from file, then applies GitHub settings
+// from SSHHADES_GITHUB_* environment variables over it
 func LoadConfig() (*Config, error) {
+	config, err := loadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	config.applyGitHubEnv()
+	return config, nil
+}
+
+// loadConfigFile loads configuration from file only
+func loadConfigFile() (*Config, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return nil, err
@@ -56,7 +181,7 @@ func LoadConfig() (*Config, error) {
 		return &Config{}, nil
 	}
 	
-	data, err := os.ReadFile(configPath)
+	data, err := storage.ReadFileLimited(configPath, MaxConfigFileSize, "config file")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -65,6 +190,20 @@ func LoadConfig() (*Config, error) {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+
+	if err := config.validateKDFProfiles(); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+	if config.GitHub != nil {
+		if err := ValidateBaseURL(config.GitHub.BaseURL); err != nil {
+			return nil, fmt.Errorf("invalid config file: github.base_url: %w", err)
+		}
+	}
+
+	if err := config.unprotectGitHubToken(); err != nil {
+		return nil, err
+	}
+	config.loadGHToken()
 	
 	return &config, nil
 }
@@ -76,7 +215,12 @@ func (c *Config) SaveConfig() error {
 		return err
 	}
 	
-	data, err := json.MarshalIndent(c, "", "  ")
+	saved, err := c.savedForm()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -88,9 +232,61 @@ func (c *Config) SaveConfig() error {
 	return nil
 }
 
-// SetGitHubConfig sets GitHub configuration
+// savedForm returns the config as it is written to disk, without values
+// from the environment and with the GitHub token protected by DPAPI when
+// that was requested and is available
+func (c *Config) savedForm() (*Config, error) {
+	if c.GitHub == nil {
+		return c, nil
+	}
+
+	github := c.withoutGitHubEnv()
+	if github != nil && github.TokenSource == TokenSourceGH {
+		github.Token = ""
+	}
+	if github == nil || !TokenProtectionAvailable {
+		saved := *c
+		saved.GitHub = github
+		return &saved, nil
+	}
+
+	github.ProtectedToken = ""
+	if github.ProtectToken && github.Token != "" {
+		protected, err := protectToken(github.Token)
+		if err != nil {
+			return nil, err
+		}
+		github.Token = ""
+		github.ProtectedToken = protected
+	}
+
+	saved := *c
+	saved.GitHub = github
+	return &saved, nil
+}
+
+// unprotectGitHubToken restores a DPAPI-protected token into Token. Where
+// DPAPI is unavailable the token stays empty, so GitHub reads as not
+// configured instead of failing every command.
+func (c *Config) unprotectGitHubToken() error {
+	if c.GitHub == nil || c.GitHub.ProtectedToken == "" || !TokenProtectionAvailable {
+		return nil
+	}
+
+	token, err := unprotectToken(c.GitHub.ProtectedToken)
+	if err != nil {
+		return fmt.Errorf("failed to read GitHub token: %w", err)
+	}
+	c.GitHub.Token = token
+	return nil
+}
+
+// SetGitHubConfig sets GitHub configuration. The new section is saved as
+// given, including values that match the environment.
 func (c *Config) SetGitHubConfig(github *GitHubConfig) {
 	c.GitHub = github
+	c.githubEnv = nil
+	c.githubFile = nil
 }
 
 // GetGitHubConfig gets GitHub configuration
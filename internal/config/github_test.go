@@ -0,0 +1,243 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDirHonorsXDGConfigHome(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG_CONFIG_HOME is only honored on Linux and other Unix systems")
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if want := filepath.Join(xdg, "sshhades"); dir != want {
+		t.Errorf("Dir() = %q, want %q", dir, want)
+	}
+}
+
+func TestDirFallsBackToHomeConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, xdg := range []string{"", "relative/config"} {
+		t.Setenv("XDG_CONFIG_HOME", xdg)
+
+		dir, err := Dir()
+		if err != nil {
+			t.Fatalf("Dir() error = %v", err)
+		}
+		if want := filepath.Join(home, ".config", "sshhades"); dir != want {
+			t.Errorf("XDG_CONFIG_HOME=%q: Dir() = %q, want %q", xdg, dir, want)
+		}
+	}
+}
+
+func TestDirPathDoesNotCreateDir(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG_CONFIG_HOME is only honored on Linux and other Unix systems")
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	dir, err := DirPath()
+	if err != nil {
+		t.Fatalf("DirPath() error = %v", err)
+	}
+	if want := filepath.Join(xdg, "sshhades"); dir != want {
+		t.Errorf("DirPath() = %q, want %q", dir, want)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("DirPath() created %s (stat error = %v)", dir, err)
+	}
+}
+
+func TestProtectTokenRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg := &Config{GitHub: &GitHubConfig{Token: "ghp_secret", Username: "octocat", AuthMethod: "token", ProtectToken: true}}
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	path, err := getConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Elsewhere protection is a no-op and the token is kept as before
+	if stored := strings.Contains(string(data), "ghp_secret"); stored == TokenProtectionAvailable {
+		t.Errorf("token stored in plaintext = %v with DPAPI available = %v", stored, TokenProtectionAvailable)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if loaded.GitHub.Token != "ghp_secret" {
+		t.Errorf("LoadConfig() token = %q, want the original token", loaded.GitHub.Token)
+	}
+	if cfg.GitHub.Token != "ghp_secret" {
+		t.Error("SaveConfig() must not clear the in-memory token")
+	}
+}
+
+func TestValidateCommitAuthor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     GitHubConfig
+		wantErr bool
+	}{
+		{"unset", GitHubConfig{}, false},
+		{"both set", GitHubConfig{CommitAuthorName: "sshhades-bot", CommitAuthorEmail: "bot@example.com"}, false},
+		{"name only", GitHubConfig{CommitAuthorName: "sshhades-bot"}, true},
+		{"email only", GitHubConfig{CommitAuthorEmail: "bot@example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.ValidateCommitAuthor(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCommitAuthor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGitHubConfigFromEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(EnvGitHubToken, "ghp_from_env")
+	t.Setenv(EnvGitHubUsername, "octocat")
+	t.Setenv(EnvGitHubOwner, "octocat")
+	t.Setenv(EnvGitHubRepo, "ssh-backups")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !cfg.IsGitHubConfigured() {
+		t.Fatal("IsGitHubConfigured() = false with only environment variables set")
+	}
+	if got := cfg.GitHub.AuthMethod; got != "token" {
+		t.Errorf("AuthMethod = %q, want token", got)
+	}
+	if got := cfg.GitHub.RepoName; got != "ssh-backups" {
+		t.Errorf("RepoName = %q, want ssh-backups", got)
+	}
+}
+
+func TestGitHubEnvOverridesFileButIsNotSaved(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	file := &Config{GitHub: &GitHubConfig{Token: "ghp_file", Username: "file-user", AuthMethod: "token", RepoName: "backups"}}
+	if err := file.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	t.Setenv(EnvGitHubToken, "ghp_from_env")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.GitHub.Token != "ghp_from_env" || cfg.GitHub.Username != "file-user" {
+		t.Errorf("merged config = %+v, want the env token over the file settings", cfg.GitHub)
+	}
+
+	cfg.GitHub.CommitAuthorName = "sshhades-bot"
+	cfg.GitHub.CommitAuthorEmail = "bot@example.com"
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	path, err := getConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ghp_from_env") {
+		t.Error("SaveConfig() wrote the token from the environment to the file")
+	}
+	if !strings.Contains(string(data), "ghp_file") || !strings.Contains(string(data), "sshhades-bot") {
+		t.Errorf("SaveConfig() lost file settings or changes: %s", data)
+	}
+}
+
+func TestGitHubEnvOnlyIsNotSaved(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(EnvGitHubToken, "ghp_from_env")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg.DefaultOutputDir = "~/backups"
+	if err := cfg.SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	saved, err := loadConfigFile()
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	if saved.GitHub != nil {
+		t.Errorf("SaveConfig() wrote a GitHub section from the environment: %+v", saved.GitHub)
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := &Config{
+		GitHub:    &GitHubConfig{Token: "ghp_abcdefghijklmnop", Username: "octocat"},
+		Bitbucket: &BitbucketConfig{Username: "bb", AppPassword: "short"},
+	}
+
+	redacted := cfg.Redacted()
+	if got, want := redacted.GitHub.Token, "gh***REDACTED***"; got != want {
+		t.Errorf("redacted token = %q, want %q", got, want)
+	}
+	if got := redacted.Bitbucket.AppPassword; strings.Contains(got, "short") {
+		t.Errorf("redacted app password %q still contains the secret", got)
+	}
+	if redacted.GitHub.Username != "octocat" {
+		t.Errorf("redacted username = %q, want it kept", redacted.GitHub.Username)
+	}
+
+	// The original is left untouched
+	if cfg.GitHub.Token != "ghp_abcdefghijklmnop" || cfg.Bitbucket.AppPassword != "short" {
+		t.Error("Redacted() modified the original config")
+	}
+	if RedactSecret("") != "" {
+		t.Error("RedactSecret() of an empty secret should stay empty")
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	for _, valid := range []string{"", "https://github.example.com", "https://ghe.internal:8443/"} {
+		if err := ValidateBaseURL(valid); err != nil {
+			t.Errorf("ValidateBaseURL(%q) error = %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"github.example.com", "http://ghe.internal:8080/", "ftp://ghe.example.com", "https://", "://bad"} {
+		if err := ValidateBaseURL(invalid); err == nil {
+			t.Errorf("ValidateBaseURL(%q) accepted an invalid URL", invalid)
+		}
+	}
+}
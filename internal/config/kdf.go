@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// KDF profile names, matching the short names accepted by --algorithm
+const (
+	ProfileAES      = "aes"
+	ProfileChaCha20 = "chacha20"
+)
+
+// Bounds on profile parameters. Argon2 takes its memory in KB as a uint32,
+// so larger MB values would wrap around; the iteration limit matches what
+// kdf-bench ever recommends, and more threads than that only adds lanes
+// no machine runs in parallel.
+const (
+	minProfileMemory     = 8
+	maxProfileMemory     = math.MaxUint32 / 1024
+	maxProfileIterations = 1 << 20
+	maxProfileThreads    = 64
+)
+
+// KDFProfile holds default Argon2id parameters for one algorithm. Zero
+// fields fall back to the built-in defaults.
+type KDFProfile struct {
+	Iterations uint32 `json:"iterations,omitempty"`
+	Memory     uint32 `json:"memory,omitempty"`
+	Threads    uint8  `json:"threads,omitempty"`
+}
+
+// Validate checks that the profile parameters are usable
+func (p KDFProfile) Validate() error {
+	if p.Memory != 0 && p.Memory < minProfileMemory {
+		return fmt.Errorf("memory must be at least %d MB, got %d", minProfileMemory, p.Memory)
+	}
+	if p.Memory > maxProfileMemory {
+		return fmt.Errorf("memory must be at most %d MB, got %d", maxProfileMemory, p.Memory)
+	}
+	if p.Iterations > maxProfileIterations {
+		return fmt.Errorf("iterations must be at most %d, got %d", maxProfileIterations, p.Iterations)
+	}
+	if p.Threads > maxProfileThreads {
+		return fmt.Errorf("threads must be at most %d, got %d", maxProfileThreads, p.Threads)
+	}
+	return nil
+}
+
+// KDFProfileFor returns the configured profile for an algorithm, or a zero
+// profile when none is set
+func (c *Config) KDFProfileFor(name string) KDFProfile {
+	return c.KDFProfiles[name]
+}
+
+// SetKDFProfile stores the profile for an algorithm, replacing any
+// previous one
+func (c *Config) SetKDFProfile(name string, p KDFProfile) {
+	if c.KDFProfiles == nil {
+		c.KDFProfiles = make(map[string]KDFProfile)
+	}
+	c.KDFProfiles[name] = p
+}
+
+// validateKDFProfiles rejects unknown algorithms and invalid parameters
+func (c *Config) validateKDFProfiles() error {
+	names := make([]string, 0, len(c.KDFProfiles))
+	for name := range c.KDFProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name != ProfileAES && name != ProfileChaCha20 {
+			return fmt.Errorf("kdf_profiles: unknown algorithm %q (use: %s, %s)", name, ProfileAES, ProfileChaCha20)
+		}
+		if err := c.KDFProfiles[name].Validate(); err != nil {
+			return fmt.Errorf("kdf_profiles.%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestKDFProfileValidate(t *testing.T) {
+	for _, valid := range []KDFProfile{
+		{},
+		{Iterations: 3, Memory: 64, Threads: 4},
+		{Memory: minProfileMemory},
+		{Iterations: maxProfileIterations, Memory: maxProfileMemory, Threads: maxProfileThreads},
+	} {
+		if err := valid.Validate(); err != nil {
+			t.Errorf("Validate(%+v) error = %v", valid, err)
+		}
+	}
+
+	for _, invalid := range []KDFProfile{
+		{Memory: minProfileMemory - 1},
+		{Memory: maxProfileMemory + 1},
+		{Iterations: maxProfileIterations + 1},
+		{Threads: maxProfileThreads + 1},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted invalid parameters", invalid)
+		}
+	}
+}
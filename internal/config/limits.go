@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+// Environment variables that raise or lower the size limits on files
+// sshhades reads. The config file limit cannot live in the config file
+// itself, so both are set from the environment.
+const (
+	EnvMaxFileSize   = "SSHHADES_MAX_FILE_SIZE"
+	EnvMaxConfigSize = "SSHHADES_MAX_CONFIG_SIZE"
+)
+
+// maxSizeLimit caps both limits: files are read into memory whole
+const maxSizeLimit = 1 << 30
+
+// ApplySizeLimits sets storage.MaxEncryptedFileSize and MaxConfigFileSize
+// from SSHHADES_MAX_FILE_SIZE and SSHHADES_MAX_CONFIG_SIZE. Unset
+// variables keep the defaults.
+func ApplySizeLimits() error {
+	limits := []struct {
+		name  string
+		limit *int64
+	}{
+		{EnvMaxFileSize, &storage.MaxEncryptedFileSize},
+		{EnvMaxConfigSize, &MaxConfigFileSize},
+	}
+
+	for _, l := range limits {
+		value := os.Getenv(l.name)
+		if value == "" {
+			continue
+		}
+		size, err := ParseSize(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", l.name, err)
+		}
+		*l.limit = size
+	}
+	return nil
+}
+
+// ParseSize parses a byte count with an optional K, M or G suffix (powers
+// of 1024), e.g. "4M". The result must be between 1 byte and 1G.
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(s, "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a size (use bytes or a K, M or G suffix)", value)
+	}
+	if n < 1 || n > maxSizeLimit/multiplier {
+		return 0, fmt.Errorf("%q is out of range (1 byte to 1G)", value)
+	}
+	return n * multiplier, nil
+}
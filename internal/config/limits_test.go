@@ -0,0 +1,57 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/sshhades/sshhades/internal/storage"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"512", 512},
+		{"64K", 64 << 10},
+		{"4m", 4 << 20},
+		{"2MB", 2 << 20},
+		{"1G", 1 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "0", "-1", "2G", "lots", "1.5M"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("ParseSize(%q) should fail", value)
+		}
+	}
+}
+
+func TestApplySizeLimits(t *testing.T) {
+	defer func(file, config int64) {
+		storage.MaxEncryptedFileSize, MaxConfigFileSize = file, config
+	}(storage.MaxEncryptedFileSize, MaxConfigFileSize)
+
+	t.Setenv(EnvMaxFileSize, "8M")
+	t.Setenv(EnvMaxConfigSize, "")
+	defaultConfig := MaxConfigFileSize
+
+	if err := ApplySizeLimits(); err != nil {
+		t.Fatalf("ApplySizeLimits() error = %v", err)
+	}
+	if storage.MaxEncryptedFileSize != 8<<20 {
+		t.Errorf("MaxEncryptedFileSize = %d, want %d", storage.MaxEncryptedFileSize, 8<<20)
+	}
+	if MaxConfigFileSize != defaultConfig {
+		t.Errorf("MaxConfigFileSize = %d, want the default %d", MaxConfigFileSize, defaultConfig)
+	}
+
+	t.Setenv(EnvMaxConfigSize, "huge")
+	if err := ApplySizeLimits(); err == nil {
+		t.Error("ApplySizeLimits() should reject an invalid size")
+	}
+}
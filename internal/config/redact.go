@@ -0,0 +1,39 @@
+package config
+
+// redactedMarker replaces the hidden part of a secret in Redacted copies
+const redactedMarker = "***REDACTED***"
+
+// RedactSecret hides a secret for display. Long secrets keep their first
+// two characters, enough to tell e.g. a classic "ghp_" token from another
+// kind without revealing anything useful.
+func RedactSecret(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) < 12:
+		return redactedMarker
+	default:
+		return secret[:2] + redactedMarker
+	}
+}
+
+// Redacted returns a copy of the configuration with every token and
+// password replaced by RedactSecret, safe to print or attach to bug reports
+func (c *Config) Redacted() *Config {
+	redacted := *c
+
+	if c.GitHub != nil {
+		github := *c.GitHub
+		github.Token = RedactSecret(github.Token)
+		github.ProtectedToken = RedactSecret(github.ProtectedToken)
+		redacted.GitHub = &github
+	}
+
+	if c.Bitbucket != nil {
+		bitbucket := *c.Bitbucket
+		bitbucket.AppPassword = RedactSecret(bitbucket.AppPassword)
+		redacted.Bitbucket = &bitbucket
+	}
+
+	return &redacted
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package config
+
+import "errors"
+
+// TokenProtectionAvailable reports whether the GitHub token can be stored
+// protected by DPAPI on this platform
+const TokenProtectionAvailable = false
+
+var errTokenProtectionUnavailable = errors.New("DPAPI token protection is only available on Windows")
+
+func protectToken(token string) (string, error) {
+	return "", errTokenProtectionUnavailable
+}
+
+func unprotectToken(protected string) (string, error) {
+	return "", errTokenProtectionUnavailable
+}
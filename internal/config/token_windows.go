@@ -0,0 +1,63 @@
+//go:build windows
+
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// TokenProtectionAvailable reports whether the GitHub token can be stored
+// protected by DPAPI on this platform
+const TokenProtectionAvailable = true
+
+// protectToken encrypts a token with DPAPI for the current Windows user
+// and returns the blob base64-encoded
+func protectToken(token string) (string, error) {
+	in := []byte(token)
+	blob, err := cryptProtect(in, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to protect token with DPAPI: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(blob), nil
+}
+
+// unprotectToken reverses protectToken
+func unprotectToken(protected string) (string, error) {
+	blob, err := base64.StdEncoding.DecodeString(protected)
+	if err != nil {
+		return "", fmt.Errorf("invalid protected token: %w", err)
+	}
+	token, err := cryptProtect(blob, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to unprotect token with DPAPI (was it protected by another Windows user?): %w", err)
+	}
+	return string(token), nil
+}
+
+// cryptProtect runs CryptProtectData or CryptUnprotectData over data
+func cryptProtect(data []byte, protect bool) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
+
+	in := windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+	var out windows.DataBlob
+	var err error
+	if protect {
+		err = windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	} else {
+		err = windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	result := make([]byte, out.Size)
+	copy(result, unsafe.Slice(out.Data, out.Size))
+	return result, nil
+}
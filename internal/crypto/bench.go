@@ -0,0 +1,39 @@
+package crypto
+
+import (
+	"time"
+)
+
+// maxCalibrationIterations bounds the iteration count CalibrateKDF tries
+const maxCalibrationIterations = 1 << 20
+
+// BenchmarkKDF reports how long one key derivation with params takes
+func BenchmarkKDF(params KDFParams) time.Duration {
+	salt := make([]byte, 32)
+	start := time.Now()
+	key := DeriveKey([]byte("sshhades kdf benchmark"), salt, params)
+	elapsed := time.Since(start)
+	ClearBytes(key)
+	return elapsed
+}
+
+// CalibrateKDF finds the iteration count at which a derivation with the
+// memory and threads of base takes about target on this machine. It returns
+// the calibrated parameters and the measured time of one derivation.
+func CalibrateKDF(base KDFParams, target time.Duration) (KDFParams, time.Duration) {
+	params := base
+	params.Iterations = 1
+
+	// Double until a run is long enough to extrapolate from reliably
+	elapsed := BenchmarkKDF(params)
+	for elapsed < target/4 && params.Iterations < maxCalibrationIterations {
+		params.Iterations *= 2
+		elapsed = BenchmarkKDF(params)
+	}
+
+	// Argon2 time grows linearly with iterations
+	scaled := uint64(params.Iterations) * uint64(target) / uint64(max(elapsed, 1))
+	params.Iterations = uint32(min(max(scaled, 1), maxCalibrationIterations))
+
+	return params, BenchmarkKDF(params)
+}
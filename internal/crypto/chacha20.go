@@ -1,7 +1,6 @@
 package crypto
 
 import (
-	"crypto/rand"
 	"fmt"
 
 	"golang.org/x/crypto/chacha20poly1305"
@@ -25,9 +24,9 @@ func EncryptChaCha20(data []byte, passphrase []byte, params KDFParams) (*Encrypt
 		return nil, fmt.Errorf("failed to create ChaCha20-Poly1305 cipher: %w", err)
 	}
 
-	// Generate nonce (ChaCha20-Poly1305 uses 12-byte nonces)
-	nonce := make([]byte, aead.NonceSize())
-	if _, err := rand.Read(nonce); err != nil {
+	// Generate nonce (ChaCha20-Poly1305 uses 12-byte nonces, like AES-GCM)
+	nonce, err := GenerateNonce()
+	if err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
@@ -50,27 +49,3 @@ func EncryptChaCha20(data []byte, passphrase []byte, params KDFParams) (*Encrypt
 		Tag:        tag,
 	}, nil
 }
-
-// DecryptChaCha20 decrypts data using ChaCha20-Poly1305
-func DecryptChaCha20(salt, nonce, ciphertext, tag []byte, passphrase []byte, params KDFParams) ([]byte, error) {
-	// Derive key from passphrase
-	key := DeriveKey(passphrase, salt, params)
-	defer ClearBytes(key) // Clear key from memory when done
-
-	// Create ChaCha20-Poly1305 AEAD
-	aead, err := chacha20poly1305.New(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ChaCha20-Poly1305 cipher: %w", err)
-	}
-
-	// Reconstruct full ciphertext with tag
-	fullCiphertext := append(ciphertext, tag...)
-
-	// Decrypt data
-	plaintext, err := aead.Open(nil, nonce, fullCiphertext, nil)
-	if err != nil {
-		return nil, fmt.Errorf("decryption failed (wrong passphrase?): %w", err)
-	}
-
-	return plaintext, nil
-}
\ No newline at end of file
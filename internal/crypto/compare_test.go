@@ -0,0 +1,21 @@
+package crypto
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"correct horse", "correct horse", true},
+		{"correct horse", "correct house", false},
+		{"short", "shorter", false},
+		{"", "", true},
+	}
+
+	for _, tc := range testCases {
+		if got := Equal([]byte(tc.a), []byte(tc.b)); got != tc.expected {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
@@ -3,11 +3,16 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"errors"
 	"fmt"
 
 	"github.com/sshhades/sshhades/pkg/format"
 )
 
+// ErrDecryptionFailed is returned when the ciphertext does not authenticate,
+// which almost always means the passphrase is wrong
+var ErrDecryptionFailed = errors.New("decryption failed (wrong passphrase?)")
+
 // EncryptionResult holds the result of encryption operation
 type EncryptionResult struct {
 	Salt       []byte
@@ -18,14 +23,50 @@ type EncryptionResult struct {
 
 // Encrypt encrypts data using the specified algorithm with Argon2id key derivation
 func Encrypt(data []byte, passphrase []byte, algorithm string, params KDFParams) (*EncryptionResult, error) {
+	return EncryptCached(data, passphrase, algorithm, params, nil)
+}
+
+// EncryptCached is Encrypt leaving the derived key in cache, so sealing the
+// metadata of the same file does not run Argon2 again. cache may be nil.
+func EncryptCached(data []byte, passphrase []byte, algorithm string, params KDFParams, cache *KeyCache) (*EncryptionResult, error) {
+	if err := checkVariant(params.Variant); err != nil {
+		return nil, err
+	}
+
 	switch algorithm {
-	case format.AlgorithmAESGCM:
-		return EncryptAES(data, passphrase, params)
-	case format.AlgorithmChaCha20:
-		return EncryptChaCha20(data, passphrase, params)
+	case format.AlgorithmAESGCM, format.AlgorithmChaCha20:
 	default:
 		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
 	}
+
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, release := cache.derive(passphrase, salt, params)
+	defer release()
+
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// Split the ciphertext and the tag the AEAD appends to it
+	ciphertext := aead.Seal(nil, nonce, data, nil)
+	tagSize := aead.Overhead()
+
+	return &EncryptionResult{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: ciphertext[:len(ciphertext)-tagSize],
+		Tag:        ciphertext[len(ciphertext)-tagSize:],
+	}, nil
 }
 
 // EncryptAES encrypts data using AES-256-GCM with Argon2id key derivation
@@ -80,59 +121,61 @@ func EncryptAES(data []byte, passphrase []byte, params KDFParams) (*EncryptionRe
 
 // Decrypt decrypts data using the algorithm specified in the encrypted file
 func Decrypt(encFile *format.EncryptedFile, passphrase []byte) ([]byte, error) {
-	// Extract KDF parameters from header
-	params := KDFParams{
-		Iterations: encFile.Header.Iterations,
-		Memory:     encFile.Header.Memory,
-		Threads:    encFile.Header.Threads,
-		KeyLength:  32, // Both AES-256 and ChaCha20 use 32-byte keys
+	return DecryptCached(encFile, passphrase, nil)
+}
+
+// DecryptCached is Decrypt taking the derived key from cache when an
+// earlier file had the same salt and KDF parameters. cache may be nil.
+func DecryptCached(encFile *format.EncryptedFile, passphrase []byte, cache *KeyCache) ([]byte, error) {
+	// Extract KDF parameters from header; both AES-256 and ChaCha20 use
+	// 32-byte keys
+	params := headerKDFParams(encFile.Header)
+
+	// An unknown algorithm in a newer file means this build is too old
+	if err := format.CheckVersion(encFile.Header.Version); errors.Is(err, format.ErrNewerVersion) {
+		return nil, err
+	}
+
+	if err := checkVariant(params.Variant); err != nil {
+		return nil, err
 	}
 
+	// Check the algorithm before paying for key derivation
 	switch encFile.Header.Algorithm {
-	case format.AlgorithmAESGCM:
-		return DecryptAES(encFile, passphrase, params)
-	case format.AlgorithmChaCha20:
-		return DecryptChaCha20(encFile.Salt, encFile.Nonce, encFile.Ciphertext, encFile.Tag, passphrase, params)
+	case format.AlgorithmAESGCM, format.AlgorithmChaCha20:
 	default:
 		return nil, fmt.Errorf("unsupported algorithm: %s", encFile.Header.Algorithm)
 	}
-}
-
-// DecryptAES decrypts data using AES-256-GCM with Argon2id key derivation
-func DecryptAES(encFile *format.EncryptedFile, passphrase []byte, params KDFParams) ([]byte, error) {
 
-	// Derive AES key from passphrase
-	key := DeriveKey(passphrase, encFile.Salt, params)
-	defer ClearBytes(key) // Clear key from memory when done
+	key, release := cache.derive(passphrase, encFile.Salt, params)
+	defer release()
 
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
+	aead, err := newAEAD(encFile.Header.Algorithm, key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+		return nil, err
 	}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM mode: %w", err)
-	}
+	// Reconstruct full ciphertext with tag, without writing into the
+	// spare capacity of encFile.Ciphertext
+	fullCiphertext := append(encFile.Ciphertext[:len(encFile.Ciphertext):len(encFile.Ciphertext)], encFile.Tag...)
 
-	// Reconstruct full ciphertext with tag
-	fullCiphertext := append(encFile.Ciphertext, encFile.Tag...)
-
-	// Decrypt data
-	plaintext, err := gcm.Open(nil, encFile.Nonce, fullCiphertext, nil)
+	plaintext, err := aead.Open(nil, encFile.Nonce, fullCiphertext, nil)
 	if err != nil {
-		return nil, fmt.Errorf("decryption failed (wrong passphrase?): %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+	if encFile.Header.Compression == "" {
+		return plaintext, err
 	}
 
-	return plaintext, nil
+	// The compressed form is only an intermediate copy of the key
+	defer ClearBytes(plaintext)
+	return format.Decompress(plaintext, encFile.Header.Compression)
 }
 
 // ValidateEncryptedFile validates the structure and format of an encrypted file
 func ValidateEncryptedFile(encFile *format.EncryptedFile) error {
-	if encFile.Header.Version != format.Version {
-		return fmt.Errorf("unsupported file version: %s", encFile.Header.Version)
+	if err := format.CheckVersion(encFile.Header.Version); err != nil {
+		return err
 	}
 
 	// Check if algorithm is supported
@@ -143,10 +186,29 @@ func ValidateEncryptedFile(encFile *format.EncryptedFile) error {
 		return fmt.Errorf("unsupported algorithm: %s", encFile.Header.Algorithm)
 	}
 
-	if encFile.Header.KDF != "Argon2id" {
+	switch encFile.Header.KDF {
+	case format.KDFArgon2id, format.KDFArgon2i:
+		// Valid Argon2 variants
+	default:
 		return fmt.Errorf("unsupported KDF: %s", encFile.Header.KDF)
 	}
 
+	switch encFile.Header.Compression {
+	case "", format.CompressionGzip:
+		// Stored as is, or compressed with a known method
+	default:
+		return fmt.Errorf("unsupported compression: %s", encFile.Header.Compression)
+	}
+
+	if (encFile.Header.Share == nil) != (len(encFile.Share) == 0) {
+		return fmt.Errorf("share data and share header must appear together")
+	}
+	if encFile.Header.Share != nil {
+		if err := encFile.Header.Share.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if len(encFile.Salt) != 32 {
 		return fmt.Errorf("invalid salt length: expected 32, got %d", len(encFile.Salt))
 	}
@@ -163,5 +225,17 @@ func ValidateEncryptedFile(encFile *format.EncryptedFile) error {
 		return fmt.Errorf("empty ciphertext")
 	}
 
+	if m := encFile.Metadata; m != nil {
+		if len(m.Nonce) != 12 {
+			return fmt.Errorf("invalid metadata nonce length: expected 12, got %d", len(m.Nonce))
+		}
+		if len(m.Ciphertext) < 16 {
+			return fmt.Errorf("encrypted metadata too short")
+		}
+		if Equal(m.Nonce, encFile.Nonce) {
+			return fmt.Errorf("metadata nonce reuses the key data nonce")
+		}
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -2,7 +2,10 @@ package crypto
 
 import (
 	"bytes"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sshhades/sshhades/pkg/format"
 )
@@ -27,6 +30,36 @@ func TestDefaultKDFParams(t *testing.T) {
 	}
 }
 
+func TestIsWeakKDF(t *testing.T) {
+	fast := FastKDFParams()
+	if !IsWeakKDF(fast.Iterations, fast.Memory) {
+		t.Error("Fast KDF parameters should be reported as weak")
+	}
+
+	defaults := DefaultKDFParams()
+	if IsWeakKDF(defaults.Iterations, defaults.Memory) {
+		t.Error("Default KDF parameters should not be reported as weak")
+	}
+
+	tests := []struct {
+		iterations, memory uint32
+		weak               bool
+	}{
+		{2, 19, false},
+		{3, 64, false},
+		{1, 46, false},
+		{1, 45, true},
+		{2, 18, true},
+		{100000, 8, true},
+		{0, 64, true},
+	}
+	for _, tt := range tests {
+		if got := IsWeakKDF(tt.iterations, tt.memory); got != tt.weak {
+			t.Errorf("IsWeakKDF(%d, %d MB) = %v, want %v", tt.iterations, tt.memory, got, tt.weak)
+		}
+	}
+}
+
 func TestGenerateSalt(t *testing.T) {
 	salt1, err := GenerateSalt()
 	if err != nil {
@@ -98,6 +131,149 @@ func TestDeriveKey(t *testing.T) {
 	}
 }
 
+func TestArgon2Variants(t *testing.T) {
+	passphrase := []byte("test passphrase")
+	params := KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32}
+
+	salt, err := GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idKey := DeriveKey(passphrase, salt, params)
+	params.Variant = format.KDFArgon2i
+	iKey := DeriveKey(passphrase, salt, params)
+	if bytes.Equal(idKey, iKey) {
+		t.Error("Argon2i and Argon2id should derive different keys")
+	}
+
+	result, err := Encrypt([]byte("secret"), passphrase, format.AlgorithmChaCha20, params)
+	if err != nil {
+		t.Fatalf("Encrypt() with Argon2i error = %v", err)
+	}
+	header := format.DefaultHeader()
+	header.Algorithm = format.AlgorithmChaCha20
+	header.KDF = format.KDFArgon2i
+	header.Iterations, header.Memory, header.Threads = params.Iterations, params.Memory, params.Threads
+	encFile := &format.EncryptedFile{Header: header, Salt: result.Salt, Nonce: result.Nonce, Ciphertext: result.Ciphertext, Tag: result.Tag}
+
+	if err := ValidateEncryptedFile(encFile); err != nil {
+		t.Errorf("ValidateEncryptedFile() with Argon2i error = %v", err)
+	}
+	if plaintext, err := Decrypt(encFile, passphrase); err != nil || string(plaintext) != "secret" {
+		t.Errorf("Decrypt() with Argon2i = %q, %v", plaintext, err)
+	}
+
+	// A file claiming the wrong variant must not decrypt
+	encFile.Header.KDF = format.KDFArgon2id
+	if _, err := Decrypt(encFile, passphrase); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Decrypt() with mismatched variant error = %v, want ErrDecryptionFailed", err)
+	}
+
+	encFile.Header.KDF = "Argon2d"
+	if err := ValidateEncryptedFile(encFile); err == nil {
+		t.Error("ValidateEncryptedFile() should reject Argon2d")
+	}
+	if _, err := Encrypt([]byte("secret"), passphrase, format.AlgorithmAESGCM, KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32, Variant: "Argon2d"}); err == nil {
+		t.Error("Encrypt() should reject Argon2d")
+	}
+}
+
+func TestStrengthLevels(t *testing.T) {
+	var previous KDFParams
+	for _, name := range StrengthLevels() {
+		params, err := StrengthParams(name)
+		if err != nil {
+			t.Fatalf("StrengthParams(%q) error = %v", name, err)
+		}
+		if IsWeakKDF(params.Iterations, params.Memory) {
+			t.Errorf("strength level %q is below the secure thresholds", name)
+		}
+		if params.Iterations < previous.Iterations || params.Memory < previous.Memory {
+			t.Errorf("strength level %q is weaker than the level before it", name)
+		}
+		if !MatchesStrength(name, params.Iterations, params.Memory, params.Threads) {
+			t.Errorf("MatchesStrength(%q) = false for its own parameters", name)
+		}
+		previous = params
+	}
+
+	// libsodium's crypto_pwhash limits of the same names
+	libsodium := map[string][2]uint32{
+		StrengthInteractive: {2, 64},
+		StrengthModerate:    {3, 256},
+		StrengthSensitive:   {4, 1024},
+	}
+	for name, want := range libsodium {
+		params, _ := StrengthParams(name)
+		if params.Iterations != want[0] || params.Memory != want[1] {
+			t.Errorf("StrengthParams(%s) = t=%d/%d MB, want t=%d/%d MB", name, params.Iterations, params.Memory, want[0], want[1])
+		}
+	}
+
+	moderate, err := StrengthParams("Moderate")
+	if err != nil {
+		t.Errorf("StrengthParams(Moderate) error = %v", err)
+	}
+	if _, err := StrengthParams("paranoid"); err == nil {
+		t.Error("StrengthParams(paranoid) should fail")
+	}
+	if MatchesStrength(StrengthModerate, moderate.Iterations, moderate.Memory*2, moderate.Threads) {
+		t.Error("MatchesStrength should fail for overridden parameters")
+	}
+
+	if got := DescribeStrength(StrengthModerate, 3, 256); got != "moderate (3/256MB)" {
+		t.Errorf("DescribeStrength() = %q", got)
+	}
+	if got := DescribeStrength(StrengthModerate, 100000, 64); got != "moderate (100k/64MB)" {
+		t.Errorf("DescribeStrength() = %q", got)
+	}
+	if got := DescribeStrength(StrengthModerate, 1500, 64); got != "moderate (1500/64MB)" {
+		t.Errorf("DescribeStrength() = %q", got)
+	}
+}
+
+func TestStrengthLevelsDerive(t *testing.T) {
+	if testing.Short() {
+		t.Skip("derives with up to 1 GB of memory")
+	}
+
+	// Generous bounds: each level must stay usable, not match a benchmark
+	bounds := map[string]time.Duration{
+		StrengthInteractive: 5 * time.Second,
+		StrengthModerate:    15 * time.Second,
+		StrengthSensitive:   60 * time.Second,
+	}
+	for _, name := range StrengthLevels() {
+		params, _ := StrengthParams(name)
+		if elapsed := BenchmarkKDF(params); elapsed > bounds[name] {
+			t.Errorf("Deriving with %s took %s, over %s", name, elapsed, bounds[name])
+		} else {
+			t.Logf("%s: %s", name, elapsed)
+		}
+	}
+}
+
+func TestParseKDFVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", format.KDFArgon2id, false},
+		{"argon2id", format.KDFArgon2id, false},
+		{"Argon2i", format.KDFArgon2i, false},
+		{"argon2d", "", true},
+		{"scrypt", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKDFVariant(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseKDFVariant(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
 func TestClearBytes(t *testing.T) {
 	data := []byte("sensitive data")
 	original := make([]byte, len(data))
@@ -130,7 +306,7 @@ func TestEncryptDecryptRoundTrip(t *testing.T) {
 	}
 	
 	// Encrypt
-	result, err := Encrypt(originalData, passphrase, params)
+	result, err := Encrypt(originalData, passphrase, format.AlgorithmAESGCM, params)
 	if err != nil {
 		t.Fatalf("Encryption failed: %v", err)
 	}
@@ -178,6 +354,43 @@ func TestEncryptDecryptRoundTrip(t *testing.T) {
 	}
 }
 
+func TestDecryptCompressed(t *testing.T) {
+	originalData := bytes.Repeat([]byte("compressible key material "), 20)
+	passphrase := []byte("strong passphrase for testing")
+	params := KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32}
+
+	compressed, err := format.Compress(originalData)
+	if err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	result, err := Encrypt(compressed, passphrase, format.AlgorithmAESGCM, params)
+	if err != nil {
+		t.Fatalf("Encryption failed: %v", err)
+	}
+
+	header := format.DefaultHeader()
+	header.Version = format.CompressionVersion
+	header.Iterations, header.Memory, header.Threads = params.Iterations, params.Memory, params.Threads
+	header.Compression = format.CompressionGzip
+	encFile := &format.EncryptedFile{Header: header, Salt: result.Salt, Nonce: result.Nonce, Ciphertext: result.Ciphertext, Tag: result.Tag}
+
+	if err := ValidateEncryptedFile(encFile); err != nil {
+		t.Fatalf("ValidateEncryptedFile() error = %v", err)
+	}
+	decrypted, err := Decrypt(encFile, passphrase)
+	if err != nil {
+		t.Fatalf("Decryption failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, originalData) {
+		t.Error("Decrypt() did not decompress to the original data")
+	}
+
+	encFile.Header.Compression = "zstd"
+	if err := ValidateEncryptedFile(encFile); err == nil {
+		t.Error("ValidateEncryptedFile() accepted an unknown compression method")
+	}
+}
+
 func TestDecryptWithWrongPassphrase(t *testing.T) {
 	// Encrypt with one passphrase
 	originalData := []byte("secret data")
@@ -191,7 +404,7 @@ func TestDecryptWithWrongPassphrase(t *testing.T) {
 		KeyLength:  32,
 	}
 	
-	result, err := Encrypt(originalData, correctPassphrase, params)
+	result, err := Encrypt(originalData, correctPassphrase, format.AlgorithmAESGCM, params)
 	if err != nil {
 		t.Fatalf("Encryption failed: %v", err)
 	}
@@ -213,6 +426,8 @@ func TestDecryptWithWrongPassphrase(t *testing.T) {
 	_, err = Decrypt(encFile, wrongPassphrase)
 	if err == nil {
 		t.Error("Decryption should fail with wrong passphrase")
+	} else if !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Decrypt() error = %v, want ErrDecryptionFailed", err)
 	}
 }
 
@@ -315,4 +530,317 @@ func TestValidateEncryptedFile(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestValidateEncryptedFileNewerVersion(t *testing.T) {
+	newFile := func(version, algorithm string) *format.EncryptedFile {
+		return &format.EncryptedFile{
+			Header:     format.Header{Version: version, Algorithm: algorithm, KDF: "Argon2id"},
+			Salt:       make([]byte, 32),
+			Nonce:      make([]byte, 12),
+			Ciphertext: []byte("test"),
+			Tag:        make([]byte, 16),
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		file      *format.EncryptedFile
+		wantNewer bool
+	}{
+		{"newer major", newFile("2.0", "AES-256-GCM"), true},
+		{"newer minor", newFile("1.4", "AES-256-GCM"), true},
+		{"bundle version", newFile(format.BundleVersion, "XChaCha20-Poly1305"), false},
+		{"compression version", newFile(format.CompressionVersion, "XChaCha20-Poly1305"), false},
+		{"share version", newFile(format.ShareVersion, "XChaCha20-Poly1305"), false},
+		{"newer with unknown algorithm", newFile("1.10", "XChaCha20-Poly1305"), true},
+		{"older version", newFile("0.9", "AES-256-GCM"), false},
+		{"malformed version", newFile("v1", "AES-256-GCM"), false},
+		{"unknown algorithm", newFile(format.Version, "XChaCha20-Poly1305"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateEncryptedFile(tc.file)
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if got := errors.Is(err, format.ErrNewerVersion); got != tc.wantNewer {
+				t.Errorf("errors.Is(err, ErrNewerVersion) = %v, want %v (err: %v)", got, tc.wantNewer, err)
+			}
+		})
+	}
+
+	// Decrypt gives the upgrade hint before rejecting the algorithm
+	_, err := Decrypt(newFile("2.0", "XChaCha20-Poly1305"), []byte("passphrase"))
+	if !errors.Is(err, format.ErrNewerVersion) {
+		t.Errorf("Decrypt() error = %v, want ErrNewerVersion", err)
+	}
+}
+
+func TestRecoverAlgorithm(t *testing.T) {
+	originalData := []byte("This is a test SSH private key content")
+	passphrase := []byte("strong passphrase for testing")
+	// Minimal parameters keep the repeated derivations cheap
+	params := KDFParams{
+		Iterations: 1,
+		Memory:     8,
+		Threads:    1,
+		KeyLength:  32,
+	}
+
+	for _, algorithm := range SupportedAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			result, err := Encrypt(originalData, passphrase, algorithm, params)
+			if err != nil {
+				t.Fatalf("Encryption failed: %v", err)
+			}
+
+			// Simulate a corrupted header
+			header := format.FastHeader()
+			header.Algorithm = "garbage"
+			header.Iterations = params.Iterations
+			header.Memory = params.Memory
+			header.Threads = params.Threads
+
+			encFile := &format.EncryptedFile{
+				Header:     header,
+				Salt:       result.Salt,
+				Nonce:      result.Nonce,
+				Ciphertext: result.Ciphertext,
+				Tag:        result.Tag,
+			}
+
+			recovered, err := RecoverAlgorithm(encFile, passphrase)
+			if err != nil {
+				t.Fatalf("RecoverAlgorithm failed: %v", err)
+			}
+			if recovered != algorithm {
+				t.Errorf("RecoverAlgorithm = %s, want %s", recovered, algorithm)
+			}
+
+			if _, err := RecoverAlgorithm(encFile, []byte("wrong passphrase")); err == nil {
+				t.Error("RecoverAlgorithm should fail with wrong passphrase")
+			}
+		})
+	}
+}
+
+func TestSealOpenMetadata(t *testing.T) {
+	// Minimal parameters keep the repeated key derivations quick
+	params := KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32}
+	passphrase := []byte("metadata passphrase")
+
+	for _, algorithm := range SupportedAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			result, err := Encrypt([]byte("key data"), passphrase, algorithm, params)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+
+			header := format.FastHeader()
+			header.Algorithm = algorithm
+			header.Iterations = params.Iterations
+			encFile := &format.EncryptedFile{
+				Header:     header,
+				Salt:       result.Salt,
+				Nonce:      result.Nonce,
+				Ciphertext: result.Ciphertext,
+				Tag:        result.Tag,
+			}
+
+			want := format.Metadata{Comment: "secret comment", KeyType: "ed25519", OriginalName: "id_ed25519"}
+			if err := SealMetadata(encFile, want, passphrase); err != nil {
+				t.Fatalf("SealMetadata() error = %v", err)
+			}
+
+			if err := ValidateEncryptedFile(encFile); err != nil {
+				t.Fatalf("ValidateEncryptedFile() error = %v", err)
+			}
+			if bytes.Equal(encFile.Metadata.Nonce, encFile.Nonce) {
+				t.Error("Sealed metadata reuses the key data nonce")
+			}
+			if bytes.Contains(encFile.Metadata.Ciphertext, []byte(want.Comment)) {
+				t.Error("Sealed metadata contains the plaintext comment")
+			}
+
+			got, err := OpenMetadata(encFile, passphrase)
+			if err != nil {
+				t.Fatalf("OpenMetadata() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("OpenMetadata() = %+v, want %+v", got, want)
+			}
+
+			if _, err := OpenMetadata(encFile, []byte("wrong passphrase")); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("OpenMetadata() with the wrong passphrase error = %v, want ErrDecryptionFailed", err)
+			}
+
+			// The key data still decrypts independently of the metadata
+			if _, err := Decrypt(encFile, passphrase); err != nil {
+				t.Errorf("Decrypt() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCalibrateKDF(t *testing.T) {
+	base := KDFParams{Iterations: 99, Memory: 8, Threads: 1, KeyLength: 32}
+
+	params, elapsed := CalibrateKDF(base, 20*time.Millisecond)
+	if params.Iterations < 1 {
+		t.Errorf("CalibrateKDF() iterations = %d, want at least 1", params.Iterations)
+	}
+	if params.Memory != base.Memory || params.Threads != base.Threads || params.KeyLength != base.KeyLength {
+		t.Errorf("CalibrateKDF() changed fixed parameters: %+v", params)
+	}
+	if elapsed <= 0 {
+		t.Errorf("CalibrateKDF() elapsed = %v", elapsed)
+	}
+}
+
+func TestCalibratedProfileIsNotWeak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("calibrates for a full second")
+	}
+
+	// What kdf-bench --save stores with its default memory and threads
+	defaults := DefaultKDFParams()
+	base := KDFParams{Memory: defaults.Memory, Threads: defaults.Threads, KeyLength: 32}
+
+	params, elapsed := CalibrateKDF(base, time.Second)
+	if IsWeakKDF(params.Iterations, params.Memory) {
+		t.Errorf("Calibrated profile %d iterations, %d MB (%s) is reported as weak", params.Iterations, params.Memory, elapsed)
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	header := format.DefaultHeader()
+	header.Iterations, header.Memory, header.Threads = 1, 8, 1
+	encFile := &format.EncryptedFile{Header: header, Salt: bytes.Repeat([]byte{1}, 32)}
+
+	first := KeyFingerprint(encFile, []byte("passphrase"))
+	if first != KeyFingerprint(encFile, []byte("passphrase")) {
+		t.Error("KeyFingerprint() should be deterministic")
+	}
+	if first == KeyFingerprint(encFile, []byte("other")) {
+		t.Error("KeyFingerprint() should differ for another passphrase")
+	}
+
+	other := *encFile
+	other.Salt = bytes.Repeat([]byte{2}, 32)
+	if first == KeyFingerprint(&other, []byte("passphrase")) {
+		t.Error("KeyFingerprint() should differ for another salt")
+	}
+	if SaltFingerprint(encFile) == SaltFingerprint(&other) {
+		t.Error("SaltFingerprint() should differ for another salt")
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	checks := SelfTest()
+	if len(checks) != 4 {
+		t.Fatalf("SelfTest() ran %d checks, want 4", len(checks))
+	}
+	for _, check := range checks {
+		if check.Err != nil {
+			t.Errorf("%s: %v", check.Name, check.Err)
+		}
+	}
+}
+
+func TestValidateRejectsReusedMetadataNonce(t *testing.T) {
+	nonce := bytes.Repeat([]byte{7}, 12)
+	encFile := &format.EncryptedFile{
+		Header:     format.FastHeader(),
+		Salt:       make([]byte, 32),
+		Nonce:      nonce,
+		Ciphertext: []byte("ciphertext"),
+		Tag:        make([]byte, 16),
+		Metadata: &format.SealedMetadata{
+			Nonce:      append([]byte{}, nonce...),
+			Ciphertext: make([]byte, 32),
+		},
+	}
+
+	err := ValidateEncryptedFile(encFile)
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("ValidateEncryptedFile() error = %v, want a nonce reuse error", err)
+	}
+
+	encFile.Metadata.Nonce[0] ^= 1
+	if err := ValidateEncryptedFile(encFile); err != nil {
+		t.Errorf("ValidateEncryptedFile() with distinct nonces error = %v", err)
+	}
+}
+
+func TestWordlist(t *testing.T) {
+	words := wordlist()
+	if len(words) != 7776 {
+		t.Fatalf("Expected 7776 words, got %d", len(words))
+	}
+
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if word == "" || seen[word] {
+			t.Fatalf("Wordlist has an empty or duplicate entry: %q", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestGenerateWordPassphrase(t *testing.T) {
+	passphrase, err := GenerateWordPassphrase(6, "-")
+	if err != nil {
+		t.Fatalf("GenerateWordPassphrase failed: %v", err)
+	}
+
+	words := strings.Split(string(passphrase), "-")
+	if len(words) != 6 {
+		t.Fatalf("Expected 6 words, got %q", passphrase)
+	}
+	known := make(map[string]bool)
+	for _, word := range wordlist() {
+		known[word] = true
+	}
+	for _, word := range words {
+		if !known[word] {
+			t.Errorf("Word %q is not in the wordlist", word)
+		}
+	}
+
+	if _, err := GenerateWordPassphrase(0, " "); err == nil {
+		t.Error("Expected an error for zero words")
+	}
+}
+
+func TestGenerateRandomPassphrase(t *testing.T) {
+	passphrase, err := GenerateRandomPassphrase(32, "hex")
+	if err != nil {
+		t.Fatalf("GenerateRandomPassphrase failed: %v", err)
+	}
+	if len(passphrase) != 32 {
+		t.Fatalf("Expected 32 characters, got %d", len(passphrase))
+	}
+	for _, c := range passphrase {
+		if !strings.ContainsRune("0123456789abcdef", rune(c)) {
+			t.Errorf("Character %q is not in the hex charset", c)
+		}
+	}
+
+	if _, err := GenerateRandomPassphrase(32, "emoji"); err == nil {
+		t.Error("Expected an error for an unknown charset")
+	}
+	if _, err := GenerateRandomPassphrase(0, "alnum"); err == nil {
+		t.Error("Expected an error for zero length")
+	}
+}
+
+func TestPassphraseEntropy(t *testing.T) {
+	if got := RandomPassphraseEntropy(32, "hex"); got != 128 {
+		t.Errorf("Expected 128 bits for 32 hex characters, got %v", got)
+	}
+	if got := WordPassphraseEntropy(6); got < 77.5 || got > 77.6 {
+		t.Errorf("Expected about 77.5 bits for 6 words, got %v", got)
+	}
+}
@@ -0,0 +1,30 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// KeyFingerprint derives the key for encFile from passphrase and returns
+// the SHA-256 of the derived key, never the key itself. It is a debugging
+// aid: two attempts on the same file match only with the same passphrase,
+// and files match only when salt and KDF parameters are also the same.
+func KeyFingerprint(encFile *format.EncryptedFile, passphrase []byte) string {
+	key := DeriveKey(passphrase, encFile.Salt, headerKDFParams(encFile.Header))
+	defer ClearBytes(key)
+
+	return fingerprint(key)
+}
+
+// SaltFingerprint returns the SHA-256 of the file's salt, so files can be
+// told apart by salt without printing it
+func SaltFingerprint(encFile *format.EncryptedFile) string {
+	return fingerprint(encFile.Salt)
+}
+
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
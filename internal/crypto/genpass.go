@@ -0,0 +1,126 @@
+package crypto
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxGeneratedLength bounds the number of words or characters a generated
+// passphrase may have
+const MaxGeneratedLength = 1024
+
+// effLargeWordlist is the EFF large diceware wordlist
+// (https://www.eff.org/dice), licensed CC BY 3.0 US. Each line holds five
+// dice digits and a word separated by a tab.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// wordlist returns the words of the bundled wordlist
+var wordlist = sync.OnceValue(func() []string {
+	lines := strings.Split(strings.TrimSpace(effLargeWordlist), "\n")
+	words := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if _, word, ok := strings.Cut(line, "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// charsets are the character sets GenerateRandomPassphrase accepts by name
+var charsets = map[string]string{
+	"alnum":  "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"alpha":  "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"lower":  "abcdefghijklmnopqrstuvwxyz",
+	"digits": "0123456789",
+	"hex":    "0123456789abcdef",
+	"ascii":  "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// CharsetNames returns the names accepted by GenerateRandomPassphrase
+func CharsetNames() []string {
+	names := make([]string, 0, len(charsets))
+	for name := range charsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateWordPassphrase returns count words chosen uniformly at random
+// from the bundled diceware wordlist, joined by separator
+func GenerateWordPassphrase(count int, separator string) ([]byte, error) {
+	if count < 1 || count > MaxGeneratedLength {
+		return nil, fmt.Errorf("word count must be between 1 and %d", MaxGeneratedLength)
+	}
+
+	words := wordlist()
+	var passphrase []byte
+	for i := 0; i < count; i++ {
+		n, err := randomIndex(len(words))
+		if err != nil {
+			ClearBytes(passphrase)
+			return nil, err
+		}
+		if i > 0 {
+			passphrase = append(passphrase, separator...)
+		}
+		passphrase = append(passphrase, words[n]...)
+	}
+	return passphrase, nil
+}
+
+// GenerateRandomPassphrase returns length characters chosen uniformly at
+// random from the named character set
+func GenerateRandomPassphrase(length int, charset string) ([]byte, error) {
+	chars, ok := charsets[charset]
+	if !ok {
+		return nil, fmt.Errorf("unknown charset: %s (use: %s)", charset, strings.Join(CharsetNames(), ", "))
+	}
+	if length < 1 || length > MaxGeneratedLength {
+		return nil, fmt.Errorf("length must be between 1 and %d", MaxGeneratedLength)
+	}
+
+	passphrase := make([]byte, length)
+	for i := range passphrase {
+		n, err := randomIndex(len(chars))
+		if err != nil {
+			ClearBytes(passphrase)
+			return nil, err
+		}
+		passphrase[i] = chars[n]
+	}
+	return passphrase, nil
+}
+
+// WordPassphraseEntropy returns the entropy in bits of a passphrase of
+// count words from GenerateWordPassphrase
+func WordPassphraseEntropy(count int) float64 {
+	return float64(count) * math.Log2(float64(len(wordlist())))
+}
+
+// RandomPassphraseEntropy returns the entropy in bits of a passphrase of
+// length characters from GenerateRandomPassphrase, or 0 for an unknown charset
+func RandomPassphraseEntropy(length int, charset string) float64 {
+	chars, ok := charsets[charset]
+	if !ok {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(len(chars)))
+}
+
+// randomIndex returns a uniformly random integer in [0, n)
+func randomIndex(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return int(v.Int64()), nil
+}
@@ -2,17 +2,22 @@ package crypto
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
+	"io"
+	"strings"
 
+	"github.com/sshhades/sshhades/pkg/format"
 	"golang.org/x/crypto/argon2"
 )
 
-// KDFParams holds parameters for Argon2id key derivation
+// KDFParams holds parameters for Argon2 key derivation
 type KDFParams struct {
 	Iterations uint32 // Number of iterations
 	Memory     uint32 // Memory usage in MB
 	Threads    uint8  // Number of threads
 	KeyLength  uint32 // Derived key length in bytes
+	Variant    string // format.KDFArgon2id (default when empty) or format.KDFArgon2i
 }
 
 // DefaultKDFParams returns secure default parameters for Argon2id
@@ -25,6 +30,29 @@ func DefaultKDFParams() KDFParams {
 	}
 }
 
+// Floors below which Argon2 parameters are reported as weak, after the
+// OWASP password storage minimums: 19 MB with at least two passes, or 46 MB
+// with one. Each Argon2 pass fills the whole memory, so the cost of a guess
+// grows with memory and passes together; a high pass count cannot make up
+// for too little memory. Fast mode falls below both.
+const (
+	MinSecureMemory           uint32 = 19 // MB, with MinSecureIterations or more
+	MinSecureIterations       uint32 = 2
+	MinSecureSinglePassMemory uint32 = 46 // MB, with a single pass
+)
+
+// SecureKDFFloor describes the floors for messages
+var SecureKDFFloor = fmt.Sprintf("%d MB with %d or more iterations, or %d MB with 1",
+	MinSecureMemory, MinSecureIterations, MinSecureSinglePassMemory)
+
+// IsWeakKDF reports whether stored KDF parameters are below the secure floors
+func IsWeakKDF(iterations, memory uint32) bool {
+	if iterations >= MinSecureIterations {
+		return memory < MinSecureMemory
+	}
+	return iterations == 0 || memory < MinSecureSinglePassMemory
+}
+
 // FastKDFParams returns faster parameters for development/testing
 func FastKDFParams() KDFParams {
 	return KDFParams{
@@ -35,8 +63,104 @@ func FastKDFParams() KDFParams {
 	}
 }
 
-// DeriveKey derives an AES key from a passphrase using Argon2id
+// Strength levels name vetted KDF parameter sets, so users can pick one
+// with --strength instead of choosing raw numbers
+const (
+	StrengthInteractive = "interactive"
+	StrengthModerate    = "moderate"
+	StrengthSensitive   = "sensitive"
+)
+
+// strengthLevels are ordered from weakest to strongest. Passes and memory
+// are libsodium's crypto_pwhash OPSLIMIT/MEMLIMIT pairs of the same names.
+var strengthLevels = []struct {
+	name   string
+	params KDFParams
+}{
+	{StrengthInteractive, KDFParams{Iterations: 2, Memory: 64, Threads: 4, KeyLength: 32}},
+	{StrengthModerate, KDFParams{Iterations: 3, Memory: 256, Threads: 4, KeyLength: 32}},
+	{StrengthSensitive, KDFParams{Iterations: 4, Memory: 1024, Threads: 4, KeyLength: 32}},
+}
+
+// StrengthLevels returns the names of the strength levels, weakest first
+func StrengthLevels() []string {
+	names := make([]string, len(strengthLevels))
+	for i, level := range strengthLevels {
+		names[i] = level.name
+	}
+	return names
+}
+
+// StrengthParams returns the KDF parameters of a named strength level
+func StrengthParams(name string) (KDFParams, error) {
+	for _, level := range strengthLevels {
+		if level.name == strings.ToLower(name) {
+			return level.params, nil
+		}
+	}
+	return KDFParams{}, fmt.Errorf("unknown strength level: %s (use: %s)", name, strings.Join(StrengthLevels(), ", "))
+}
+
+// MatchesStrength reports whether iterations, memory and threads are
+// exactly the parameters of the named strength level
+func MatchesStrength(name string, iterations, memory uint32, threads uint8) bool {
+	params, err := StrengthParams(name)
+	if err != nil {
+		return false
+	}
+	return params.Iterations == iterations && params.Memory == memory && params.Threads == threads
+}
+
+// DescribeStrength renders a strength hint with the stored parameters,
+// e.g. "moderate (3/256MB)". The hint comes from the unauthenticated
+// header, so the numbers shown are always the ones the file really uses.
+func DescribeStrength(name string, iterations, memory uint32) string {
+	count := fmt.Sprint(iterations)
+	if iterations >= 1000 && iterations%1000 == 0 {
+		count = fmt.Sprintf("%dk", iterations/1000)
+	}
+	return fmt.Sprintf("%s (%s/%dMB)", name, count, memory)
+}
+
+// ParseKDFVariant maps an Argon2 variant name given by the user, such as
+// "argon2i", to the name recorded in the header
+func ParseKDFVariant(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "argon2id", "id":
+		return format.KDFArgon2id, nil
+	case "argon2i", "i":
+		return format.KDFArgon2i, nil
+	case "argon2d", "d":
+		return "", fmt.Errorf("argon2d is not supported: golang.org/x/crypto/argon2 only implements argon2i and argon2id")
+	default:
+		return "", fmt.Errorf("unsupported Argon2 variant: %s (use: argon2id, argon2i)", name)
+	}
+}
+
+// checkVariant rejects KDF variants that DeriveKey cannot compute
+func checkVariant(variant string) error {
+	switch variant {
+	case "", format.KDFArgon2id, format.KDFArgon2i:
+		return nil
+	default:
+		return fmt.Errorf("unsupported KDF: %s", variant)
+	}
+}
+
+// DeriveKey derives an AES key from a passphrase using the Argon2 variant
+// in params, Argon2id unless Argon2i is requested
 func DeriveKey(passphrase []byte, salt []byte, params KDFParams) []byte {
+	if params.Variant == format.KDFArgon2i {
+		return argon2.Key(
+			passphrase,
+			salt,
+			params.Iterations,
+			params.Memory*1024, // Convert MB to KB
+			params.Threads,
+			params.KeyLength,
+		)
+	}
+
 	return argon2.IDKey(
 		passphrase,
 		salt,
@@ -47,10 +171,14 @@ func DeriveKey(passphrase []byte, salt []byte, params KDFParams) []byte {
 	)
 }
 
+// randReader supplies every salt and nonce. It is always crypto/rand in
+// production; tests swap in a deterministic reader to pin exact ciphertexts.
+var randReader io.Reader = rand.Reader
+
 // GenerateSalt creates a cryptographically secure random salt
 func GenerateSalt() ([]byte, error) {
 	salt := make([]byte, 32) // 256-bit salt
-	_, err := rand.Read(salt)
+	_, err := io.ReadFull(randReader, salt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate salt: %w", err)
 	}
@@ -60,13 +188,19 @@ func GenerateSalt() ([]byte, error) {
 // GenerateNonce creates a cryptographically secure random nonce for AES-GCM
 func GenerateNonce() ([]byte, error) {
 	nonce := make([]byte, 12) // 96-bit nonce for AES-GCM
-	_, err := rand.Read(nonce)
+	_, err := io.ReadFull(randReader, nonce)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 	return nonce, nil
 }
 
+// Equal reports whether two secrets are identical in constant time, so the
+// comparison does not leak how many leading bytes matched
+func Equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
 // ClearBytes securely clears sensitive data from memory
 func ClearBytes(data []byte) {
 	for i := range data {
@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// KeyCache remembers the keys derived from one passphrase during a batch,
+// so files that share a salt and KDF parameters pay for Argon2 only once.
+// That covers a file and its --encrypt-metadata section, and sets of files
+// produced with the same salt. A cache must only be used with a single
+// passphrase, and Clear must be called when the batch is done.
+type KeyCache struct {
+	keys map[string][]byte
+	hits int
+}
+
+// NewKeyCache returns an empty cache
+func NewKeyCache() *KeyCache {
+	return &KeyCache{keys: make(map[string][]byte)}
+}
+
+// derive returns the key for salt and params, deriving it on a miss. The
+// returned release function clears keys that were not cached, so callers
+// can always defer it. A nil cache derives every time.
+func (c *KeyCache) derive(passphrase, salt []byte, params KDFParams) ([]byte, func()) {
+	if c == nil {
+		key := DeriveKey(passphrase, salt, params)
+		return key, func() { ClearBytes(key) }
+	}
+
+	id := fmt.Sprintf("%s/%d/%d/%d/%d/%s", params.Variant, params.Iterations, params.Memory, params.Threads, params.KeyLength, hex.EncodeToString(salt))
+	if key, ok := c.keys[id]; ok {
+		c.hits++
+		return key, func() {}
+	}
+
+	key := DeriveKey(passphrase, salt, params)
+	c.keys[id] = key
+	return key, func() {}
+}
+
+// Hits returns how many derivations the cache has saved
+func (c *KeyCache) Hits() int {
+	if c == nil {
+		return 0
+	}
+	return c.hits
+}
+
+// Clear wipes every cached key. The cache can be reused afterwards.
+func (c *KeyCache) Clear() {
+	if c == nil {
+		return
+	}
+	for id, key := range c.keys {
+		ClearBytes(key)
+		delete(c.keys, id)
+	}
+}
@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+func TestKeyCache(t *testing.T) {
+	params := KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32}
+	passphrase := []byte("batch passphrase")
+
+	encryptFile := func(plaintext string) *format.EncryptedFile {
+		t.Helper()
+		result, err := Encrypt([]byte(plaintext), passphrase, format.AlgorithmChaCha20, params)
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		header := format.DefaultHeader()
+		header.Algorithm = format.AlgorithmChaCha20
+		header.Iterations, header.Memory, header.Threads = params.Iterations, params.Memory, params.Threads
+		return &format.EncryptedFile{Header: header, Salt: result.Salt, Nonce: result.Nonce, Ciphertext: result.Ciphertext, Tag: result.Tag}
+	}
+
+	// Two files produced with the same salt, and one with its own
+	withDeterministicRand(t)
+	first := encryptFile("first")
+	withDeterministicRand(t)
+	second := encryptFile("second")
+	randReader = &countingReader{next: 100}
+	other := encryptFile("other")
+	if !bytes.Equal(first.Salt, second.Salt) || bytes.Equal(first.Salt, other.Salt) {
+		t.Fatal("test files do not have the intended salts")
+	}
+
+	if err := SealMetadata(first, format.Metadata{Comment: "sealed"}, passphrase); err != nil {
+		t.Fatalf("SealMetadata() error = %v", err)
+	}
+
+	cache := NewKeyCache()
+	for _, tt := range []struct {
+		file *format.EncryptedFile
+		want string
+		hits int
+	}{
+		{first, "first", 0},
+		{second, "second", 1},
+		{other, "other", 1},
+	} {
+		plaintext, err := DecryptCached(tt.file, passphrase, cache)
+		if err != nil || string(plaintext) != tt.want {
+			t.Fatalf("DecryptCached() = %q, %v; want %q", plaintext, err, tt.want)
+		}
+		if cache.Hits() != tt.hits {
+			t.Errorf("after %q Hits() = %d, want %d", tt.want, cache.Hits(), tt.hits)
+		}
+	}
+
+	metadata, err := OpenMetadataCached(first, passphrase, cache)
+	if err != nil || metadata.Comment != "sealed" {
+		t.Fatalf("OpenMetadataCached() = %+v, %v", metadata, err)
+	}
+	if cache.Hits() != 2 {
+		t.Errorf("metadata did not reuse the cached key: Hits() = %d", cache.Hits())
+	}
+
+	var cached [][]byte
+	for _, key := range cache.keys {
+		cached = append(cached, key)
+	}
+	cache.Clear()
+	for _, key := range cached {
+		if !bytes.Equal(key, make([]byte, len(key))) {
+			t.Error("Clear() left a key in memory")
+		}
+	}
+	if len(cache.keys) != 0 {
+		t.Error("Clear() left entries in the cache")
+	}
+
+	// A nil cache derives every time
+	var none *KeyCache
+	if plaintext, err := DecryptCached(other, passphrase, none); err != nil || string(plaintext) != "other" {
+		t.Errorf("DecryptCached() with nil cache = %q, %v", plaintext, err)
+	}
+	none.Clear()
+}
+
+func TestSealMetadataReusesEncryptionKey(t *testing.T) {
+	params := KDFParams{Iterations: 1, Memory: 8, Threads: 1, KeyLength: 32, Variant: format.KDFArgon2id}
+	passphrase := []byte("backup passphrase")
+
+	cache := NewKeyCache()
+	defer cache.Clear()
+	result, err := EncryptCached([]byte("key"), passphrase, format.AlgorithmAESGCM, params, cache)
+	if err != nil {
+		t.Fatalf("EncryptCached() error = %v", err)
+	}
+
+	header := format.DefaultHeader()
+	header.Iterations, header.Memory, header.Threads = params.Iterations, params.Memory, params.Threads
+	encFile := &format.EncryptedFile{Header: header, Salt: result.Salt, Nonce: result.Nonce, Ciphertext: result.Ciphertext, Tag: result.Tag}
+	if err := SealMetadataCached(encFile, format.Metadata{Comment: "sealed"}, passphrase, cache); err != nil {
+		t.Fatalf("SealMetadataCached() error = %v", err)
+	}
+	if cache.Hits() != 1 {
+		t.Errorf("SealMetadataCached() derived the key again: Hits() = %d", cache.Hits())
+	}
+
+	if plaintext, err := Decrypt(encFile, passphrase); err != nil || string(plaintext) != "key" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+	if metadata, err := OpenMetadata(encFile, passphrase); err != nil || metadata.Comment != "sealed" {
+		t.Errorf("OpenMetadata() = %+v, %v", metadata, err)
+	}
+}
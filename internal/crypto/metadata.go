@@ -0,0 +1,98 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+// metadataAD binds sealed metadata to its purpose, so the metadata section
+// and the key data can never be swapped for one another
+var metadataAD = []byte("sshhades metadata")
+
+// SealMetadata encrypts metadata into encFile.Metadata with the key derived
+// from the passphrase and the file's own salt and KDF parameters. encFile
+// must already hold the encrypted key data.
+func SealMetadata(encFile *format.EncryptedFile, metadata format.Metadata, passphrase []byte) error {
+	return SealMetadataCached(encFile, metadata, passphrase, nil)
+}
+
+// SealMetadataCached is SealMetadata reusing the key cached when the key
+// data was encrypted with EncryptCached. cache may be nil.
+func SealMetadataCached(encFile *format.EncryptedFile, metadata format.Metadata, passphrase []byte, cache *KeyCache) error {
+	plaintext, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to serialize metadata: %w", err)
+	}
+	defer ClearBytes(plaintext)
+
+	key, release := cache.derive(passphrase, encFile.Salt, headerKDFParams(encFile.Header))
+	defer release()
+
+	aead, err := newAEAD(encFile.Header.Algorithm, key)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return err
+	}
+	// The metadata shares its key with the key data, so reusing the data
+	// nonce would break both. A repeat of a random 96-bit nonce means the
+	// random source is broken, so refuse rather than retry.
+	if Equal(nonce, encFile.Nonce) {
+		return fmt.Errorf("nonce reuse: metadata nonce equals the key data nonce")
+	}
+
+	encFile.Metadata = &format.SealedMetadata{
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, metadataAD),
+	}
+	return nil
+}
+
+// OpenMetadata decrypts the metadata sealed by SealMetadata
+func OpenMetadata(encFile *format.EncryptedFile, passphrase []byte) (format.Metadata, error) {
+	return OpenMetadataCached(encFile, passphrase, nil)
+}
+
+// OpenMetadataCached is OpenMetadata reusing the key cached when the key
+// data of the same file was decrypted. cache may be nil.
+func OpenMetadataCached(encFile *format.EncryptedFile, passphrase []byte, cache *KeyCache) (format.Metadata, error) {
+	var metadata format.Metadata
+	if encFile.Metadata == nil {
+		return metadata, fmt.Errorf("file has no encrypted metadata")
+	}
+
+	key, release := cache.derive(passphrase, encFile.Salt, headerKDFParams(encFile.Header))
+	defer release()
+
+	aead, err := newAEAD(encFile.Header.Algorithm, key)
+	if err != nil {
+		return metadata, err
+	}
+
+	plaintext, err := aead.Open(nil, encFile.Metadata.Nonce, encFile.Metadata.Ciphertext, metadataAD)
+	if err != nil {
+		return metadata, fmt.Errorf("failed to decrypt metadata: %w: %w", ErrDecryptionFailed, err)
+	}
+	defer ClearBytes(plaintext)
+
+	if err := json.Unmarshal(plaintext, &metadata); err != nil {
+		return metadata, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// headerKDFParams returns the KDF parameters recorded in a header
+func headerKDFParams(header format.Header) KDFParams {
+	return KDFParams{
+		Iterations: header.Iterations,
+		Memory:     header.Memory,
+		Threads:    header.Threads,
+		KeyLength:  32,
+		Variant:    header.KDF,
+	}
+}
@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/sshhades/sshhades/pkg/format"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// SupportedAlgorithms lists every algorithm that Decrypt understands
+var SupportedAlgorithms = []string{format.AlgorithmAESGCM, format.AlgorithmChaCha20}
+
+// RecoverAlgorithm determines which algorithm produced a file whose header
+// algorithm is missing or corrupted. Both AES-256-GCM and ChaCha20-Poly1305
+// use 32-byte keys, so the key is derived once and each AEAD is tried in turn;
+// authentication guarantees at most one of them succeeds.
+func RecoverAlgorithm(encFile *format.EncryptedFile, passphrase []byte) (string, error) {
+	// Check the remaining structure as if the algorithm were valid
+	probe := *encFile
+	probe.Header.Algorithm = format.AlgorithmAESGCM
+	if err := ValidateEncryptedFile(&probe); err != nil {
+		return "", fmt.Errorf("file cannot be recovered: %w", err)
+	}
+
+	params := KDFParams{
+		Iterations: encFile.Header.Iterations,
+		Memory:     encFile.Header.Memory,
+		Threads:    encFile.Header.Threads,
+		KeyLength:  32,
+		Variant:    encFile.Header.KDF,
+	}
+
+	key := DeriveKey(passphrase, encFile.Salt, params)
+	defer ClearBytes(key)
+
+	fullCiphertext := make([]byte, 0, len(encFile.Ciphertext)+len(encFile.Tag))
+	fullCiphertext = append(fullCiphertext, encFile.Ciphertext...)
+	fullCiphertext = append(fullCiphertext, encFile.Tag...)
+
+	for _, algorithm := range SupportedAlgorithms {
+		aead, err := newAEAD(algorithm, key)
+		if err != nil {
+			return "", err
+		}
+
+		plaintext, err := aead.Open(nil, encFile.Nonce, fullCiphertext, nil)
+		if err == nil {
+			ClearBytes(plaintext)
+			return algorithm, nil
+		}
+	}
+
+	return "", fmt.Errorf("no supported algorithm authenticates the file (wrong passphrase or corrupted data?)")
+}
+
+// newAEAD creates the AEAD cipher for an algorithm
+func newAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case format.AlgorithmAESGCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+		}
+		return cipher.NewGCM(block)
+	case format.AlgorithmChaCha20:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
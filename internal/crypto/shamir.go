@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/sshhades/sshhades/internal/third_party/shamir"
+)
+
+// Split backups share their data key with Shamir's secret sharing as
+// implemented by HashiCorp Vault (internal/third_party/shamir). Only the
+// argument checks and the key check below are specific to sshhades.
+
+// MaxShares is the most shares a secret can be split into: x-coordinates
+// are the non-zero elements of GF(2^8)
+const MaxShares = 255
+
+// DataKeyLength is the length of the random key a split backup is
+// encrypted with in place of a passphrase
+const DataKeyLength = 32
+
+// keyCheckLength is how many bytes of the HMAC a key check keeps
+const keyCheckLength = 16
+
+// GenerateDataKey returns a random key for a split backup
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, DataKeyLength)
+	if _, err := io.ReadFull(randReader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return key, nil
+}
+
+// GenerateShareSetID returns a random identifier that ties together the
+// shares of one split, so shares of different splits are not mixed
+func GenerateShareSetID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := io.ReadFull(randReader, id); err != nil {
+		return "", fmt.Errorf("failed to generate share set ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// SplitSecret splits secret into total shares, any threshold of which
+// recover it with CombineShares. Each share is one byte longer than the
+// secret; its last byte is the share's x-coordinate.
+func SplitSecret(secret []byte, threshold, total int) ([][]byte, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, fmt.Errorf("cannot split an empty secret")
+	case threshold < 2:
+		return nil, fmt.Errorf("threshold must be at least 2, got %d", threshold)
+	case total < threshold:
+		return nil, fmt.Errorf("cannot make %d shares with a threshold of %d", total, threshold)
+	case total > MaxShares:
+		return nil, fmt.Errorf("at most %d shares can be made, got %d", MaxShares, total)
+	}
+	return shamir.Split(secret, total, threshold)
+}
+
+// CombineShares recovers the secret from shares made by SplitSecret. With
+// fewer shares than the threshold, or a damaged share, the result is a
+// wrong secret rather than an error; check it with VerifyKeyCheck.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	return shamir.Combine(shares)
+}
+
+// KeyCheck returns a hex HMAC-SHA256 of the data key of the split setID,
+// stored in every share so a wrong combination is caught before decrypting.
+// The key is random and 256 bits long, so the check reveals nothing
+// usable about it.
+func KeyCheck(dataKey []byte, setID string) string {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte("sshhades share key check\x00" + setID))
+	return hex.EncodeToString(mac.Sum(nil)[:keyCheckLength])
+}
+
+// VerifyKeyCheck reports whether dataKey matches a check made by KeyCheck
+func VerifyKeyCheck(dataKey []byte, setID, check string) bool {
+	return hmac.Equal([]byte(KeyCheck(dataKey, setID)), []byte(check))
+}
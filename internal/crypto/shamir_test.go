@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitAndCombine(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	shares, err := SplitSecret(secret, 3, 5)
+	if err != nil {
+		t.Fatalf("SplitSecret() error = %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("got %d shares, want 5", len(shares))
+	}
+	xs := make(map[byte]bool)
+	for i, share := range shares {
+		x := share[len(share)-1]
+		if len(share) != len(secret)+1 || x == 0 || xs[x] {
+			t.Fatalf("share %d is malformed", i+1)
+		}
+		xs[x] = true
+	}
+
+	// Every choice of three shares, in any order, recovers the secret
+	for a := 0; a < 5; a++ {
+		for b := 0; b < 5; b++ {
+			for c := 0; c < 5; c++ {
+				if a == b || b == c || a == c {
+					continue
+				}
+				got, err := CombineShares([][]byte{shares[a], shares[b], shares[c]})
+				if err != nil {
+					t.Fatalf("CombineShares(%d,%d,%d) error = %v", a+1, b+1, c+1, err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("CombineShares(%d,%d,%d) = %q, want the secret", a+1, b+1, c+1, got)
+				}
+			}
+		}
+	}
+
+	// More than the threshold works too; fewer does not recover it
+	if got, _ := CombineShares(shares); !bytes.Equal(got, secret) {
+		t.Error("CombineShares() with all shares did not recover the secret")
+	}
+	if got, _ := CombineShares(shares[:2]); bytes.Equal(got, secret) {
+		t.Error("CombineShares() recovered the secret from fewer shares than the threshold")
+	}
+}
+
+func TestSplitSecretErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		secret           []byte
+		threshold, total int
+	}{
+		{"empty secret", nil, 2, 3},
+		{"threshold of one", []byte("s"), 1, 3},
+		{"fewer shares than threshold", []byte("s"), 3, 2},
+		{"too many shares", []byte("s"), 2, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SplitSecret(tt.secret, tt.threshold, tt.total); err == nil {
+				t.Error("SplitSecret() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCombineSharesErrors(t *testing.T) {
+	shares, err := SplitSecret([]byte("secret"), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"single share", shares[:1]},
+		{"duplicate share", [][]byte{shares[0], shares[0]}},
+		{"different lengths", [][]byte{shares[0], shares[1][1:]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CombineShares(tt.shares); err == nil {
+				t.Error("CombineShares() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestKeyCheck(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	check := KeyCheck(key, "set")
+
+	if len(check) != 2*keyCheckLength {
+		t.Fatalf("KeyCheck() = %q, want %d hex digits", check, 2*keyCheckLength)
+	}
+	if !VerifyKeyCheck(key, "set", check) {
+		t.Error("VerifyKeyCheck() rejected the key the check was made from")
+	}
+
+	other := append([]byte{}, key...)
+	other[0] ^= 1
+	if VerifyKeyCheck(other, "set", check) {
+		t.Error("VerifyKeyCheck() accepted a different key")
+	}
+	if VerifyKeyCheck(key, "other set", check) {
+		t.Error("VerifyKeyCheck() accepted the check of a different split")
+	}
+	if VerifyKeyCheck(key, "set", "") {
+		t.Error("VerifyKeyCheck() accepted an empty check")
+	}
+}
@@ -2,41 +2,24 @@ package github
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/charmbracelet/lipgloss"
 	"github.com/google/go-github/v57/github"
 	"github.com/sshhades/sshhades/internal/config"
 	"golang.org/x/oauth2"
 )
 
-var (
-	// Styles for beautiful CLI
-	titleStyle = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#7C3AED")).
-		MarginBottom(1)
-
-	successStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#10B981")).
-		Bold(true)
-
-	errorStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#EF4444")).
-		Bold(true)
-
-	infoStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#3B82F6"))
-
-	promptStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#F59E0B")).
-		Bold(true)
-)
-
 // AuthenticatedClient holds GitHub client and config
 type AuthenticatedClient struct {
 	Client *github.Client
@@ -52,6 +35,7 @@ func NewAuthenticatedClient(cfg *config.GitHubConfig) (*AuthenticatedClient, err
 		if cfg.Token == "" {
 			return nil, fmt.Errorf("GitHub token is required")
 		}
+		RegisterSecret(cfg.Token)
 		
 		ctx := context.Background()
 		ts := oauth2.StaticTokenSource(
@@ -68,18 +52,72 @@ func NewAuthenticatedClient(cfg *config.GitHubConfig) (*AuthenticatedClient, err
 		return nil, fmt.Errorf("unsupported authentication method: %s", cfg.AuthMethod)
 	}
 
+	client, err := withBaseURL(client, cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ValidateCommitAuthor(); err != nil {
+		return nil, err
+	}
+
 	return &AuthenticatedClient{
 		Client: client,
 		Config: cfg,
 	}, nil
 }
 
-// ValidateToken validates GitHub token
-func ValidateToken(token string) (*github.User, error) {
+// withBaseURL points client at the GitHub Enterprise server baseURL, or
+// leaves it on github.com when baseURL is empty
+func withBaseURL(client *github.Client, baseURL string) (*github.Client, error) {
+	if baseURL == "" {
+		return client, nil
+	}
+	if err := config.ValidateBaseURL(baseURL); err != nil {
+		return nil, err
+	}
+
+	// The API lives under /api/v3/ and uploads under /api/uploads/, which
+	// WithEnterpriseURLs appends
+	client, err := client.WithEnterpriseURLs(baseURL, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub Enterprise URL %s: %w", baseURL, err)
+	}
+	return client, nil
+}
+
+// SSHHost returns the host git connects to over SSH: github.com, or the
+// host of the GitHub Enterprise server baseURL
+func SSHHost(baseURL string) string {
+	if baseURL == "" {
+		return "github.com"
+	}
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "github.com"
+}
+
+// WebURL returns the address of the GitHub web interface for baseURL
+func WebURL(baseURL string) string {
+	if baseURL == "" {
+		return "https://github.com"
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// ValidateToken validates a GitHub token against github.com, or against
+// the GitHub Enterprise server baseURL when it is set
+func ValidateToken(token, baseURL string) (*github.User, error) {
+	RegisterSecret(token)
+
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	client, err := withBaseURL(github.NewClient(tc), baseURL)
+	if err != nil {
+		return nil, err
+	}
 
 	user, _, err := client.Users.Get(ctx, "")
 	if err != nil {
@@ -127,33 +165,99 @@ func FindSSHKeys() ([]string, error) {
 	return sshKeys, nil
 }
 
-// TestSSHConnection tests SSH connection to GitHub
-func TestSSHConnection(sshKeyPath string) error {
-	cmd := exec.Command("ssh", "-T", "-i", sshKeyPath, "-o", "StrictHostKeyChecking=no", "git@github.com")
+// sshTestAttempts is how many times TestSSHConnection tries to reach GitHub
+const sshTestAttempts = 3
+
+// sshTestBackoff is the delay before the first retry; it doubles each time
+const sshTestBackoff = time.Second
+
+// transientSSHErrors are ssh client messages for failures worth retrying
+var transientSSHErrors = []string{
+	"Connection timed out",
+	"Operation timed out",
+	"Connection refused",
+	"Connection reset",
+	"Connection closed",
+	"Could not resolve hostname",
+	"Network is unreachable",
+	"kex_exchange_identification",
+}
+
+// TestSSHConnection tests SSH connection to GitHub, or to the host of the
+// GitHub Enterprise server baseURL when it is set. Network failures are
+// retried with a short backoff; authentication failures are returned at once.
+// ssh is stopped when ctx ends.
+func TestSSHConnection(ctx context.Context, sshKeyPath, baseURL string) error {
+	backoff := sshTestBackoff
+	var err error
+
+	for attempt := 1; attempt <= sshTestAttempts; attempt++ {
+		var transient bool
+		transient, err = trySSHConnection(ctx, sshKeyPath, baseURL)
+		if err == nil || !transient {
+			return err
+		}
+
+		if attempt < sshTestAttempts {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("SSH connection test stopped: %w", ctx.Err())
+			}
+			backoff *= 2
+		}
+	}
+
+	return fmt.Errorf("%w (gave up after %d attempts)", err, sshTestAttempts)
+}
+
+// trySSHConnection runs a single ssh -T probe and reports whether a failure
+// looks transient
+func trySSHConnection(ctx context.Context, sshKeyPath, baseURL string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "ssh", "-T", "-i", sshKeyPath, "-o", "StrictHostKeyChecking=no", "-o", "ConnectTimeout=10", "git@"+SSHHost(baseURL))
 	output, _ := cmd.CombinedOutput()
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("SSH connection test stopped: %w", err)
+	}
 	
 	// GitHub SSH test returns exit code 1 but with success message
 	outputStr := string(output)
 	if strings.Contains(outputStr, "successfully authenticated") {
-		return nil
+		return false, nil
 	}
 
-	return fmt.Errorf("SSH connection failed: %s", outputStr)
+	if strings.Contains(outputStr, "Permission denied") {
+		return false, fmt.Errorf("SSH authentication failed: %s", strings.TrimSpace(outputStr))
+	}
+
+	for _, message := range transientSSHErrors {
+		if strings.Contains(outputStr, message) {
+			return true, fmt.Errorf("SSH connection failed: %s", strings.TrimSpace(outputStr))
+		}
+	}
+
+	return false, fmt.Errorf("SSH connection failed: %s", outputStr)
 }
 
-// GetGitHubUsername extracts username from SSH test output or API
-func GetGitHubUsername(authMethod, token, sshKeyPath string) (string, error) {
+// GetGitHubUsername extracts username from SSH test output or API. ssh is
+// stopped when ctx ends.
+func GetGitHubUsername(ctx context.Context, authMethod, token, sshKeyPath, baseURL string) (string, error) {
 	switch authMethod {
 	case "token":
-		user, err := ValidateToken(token)
+		user, err := ValidateToken(token, baseURL)
 		if err != nil {
 			return "", err
 		}
 		return user.GetLogin(), nil
 
 	case "ssh":
-		cmd := exec.Command("ssh", "-T", "-i", sshKeyPath, "-o", "StrictHostKeyChecking=no", "git@github.com")
+		cmd := exec.CommandContext(ctx, "ssh", "-T", "-i", sshKeyPath, "-o", "StrictHostKeyChecking=no", "-o", "ConnectTimeout=10", "git@"+SSHHost(baseURL))
 		output, _ := cmd.CombinedOutput()
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("failed to get username from SSH: %w", err)
+		}
 		outputStr := string(output)
 
 		// Parse username from SSH output: "Hi username! You've successfully authenticated..."
@@ -172,8 +276,9 @@ func GetGitHubUsername(authMethod, token, sshKeyPath string) (string, error) {
 	}
 }
 
-// CreateRepository creates a new GitHub repository
-func (ac *AuthenticatedClient) CreateRepository(ctx context.Context, name, description string, private bool) (*github.Repository, error) {
+// CreateRepository creates a new GitHub repository, owned by the
+// organization org or, when org is empty, by the authenticated user
+func (ac *AuthenticatedClient) CreateRepository(ctx context.Context, org, name, description string, private bool) (*github.Repository, error) {
 	repo := &github.Repository{
 		Name:        github.String(name),
 		Description: github.String(description),
@@ -181,7 +286,7 @@ func (ac *AuthenticatedClient) CreateRepository(ctx context.Context, name, descr
 		AutoInit:    github.Bool(true),
 	}
 
-	createdRepo, _, err := ac.Client.Repositories.Create(ctx, "", repo)
+	createdRepo, _, err := ac.Client.Repositories.Create(ctx, org, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository: %w", err)
 	}
@@ -223,11 +328,25 @@ func (ac *AuthenticatedClient) GetRepository(ctx context.Context, owner, name st
 	return repo, nil
 }
 
+// commitAuthor returns the configured author for commits, or nil to let
+// GitHub use the token owner
+func (ac *AuthenticatedClient) commitAuthor() *github.CommitAuthor {
+	if ac.Config.CommitAuthorName == "" || ac.Config.CommitAuthorEmail == "" {
+		return nil
+	}
+	return &github.CommitAuthor{
+		Name:  github.String(ac.Config.CommitAuthorName),
+		Email: github.String(ac.Config.CommitAuthorEmail),
+	}
+}
+
 // UploadFile uploads a file to GitHub repository
 func (ac *AuthenticatedClient) UploadFile(ctx context.Context, owner, repo, path string, content []byte, message string) error {
 	opts := &github.RepositoryContentFileOptions{
-		Message: github.String(message),
-		Content: content,
+		Message:   github.String(message),
+		Content:   content,
+		Author:    ac.commitAuthor(),
+		Committer: ac.commitAuthor(),
 	}
 
 	_, _, err := ac.Client.Repositories.CreateFile(ctx, owner, repo, path, opts)
@@ -253,23 +372,156 @@ func (ac *AuthenticatedClient) UploadFile(ctx context.Context, owner, repo, path
 	return nil
 }
 
-// PrettyPrint utilities for better CLI experience
-func PrintTitle(text string) {
-	fmt.Println(titleStyle.Render("🔐 " + text))
+// RemoteFile is a file in a repository directory
+type RemoteFile struct {
+	Name string
+	// SHA is the git blob hash of the content (see BlobSHA)
+	SHA  string
+	Size int
+}
+
+// ListFiles lists the files directly inside dir of a repository. A missing
+// directory yields an empty list.
+func (ac *AuthenticatedClient) ListFiles(ctx context.Context, owner, repo, dir string) ([]RemoteFile, error) {
+	_, entries, resp, err := ac.Client.Repositories.GetContents(ctx, owner, repo, dir, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	var files []RemoteFile
+	for _, entry := range entries {
+		if entry.GetType() != "file" {
+			continue
+		}
+		files = append(files, RemoteFile{Name: entry.GetName(), SHA: entry.GetSHA(), Size: entry.GetSize()})
+	}
+	return files, nil
 }
 
-func PrintSuccess(text string) {
-	fmt.Println(successStyle.Render("✅ " + text))
+// DownloadFile returns the content of a file in a repository. A missing
+// file yields an error wrapping fs.ErrNotExist.
+func (ac *AuthenticatedClient) DownloadFile(ctx context.Context, owner, repo, path string) ([]byte, error) {
+	file, _, resp, err := ac.Client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %w", path, fs.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to get %s: %w", path, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return []byte(content), nil
 }
 
-func PrintError(text string) {
-	fmt.Println(errorStyle.Render("❌ " + text))
+// HeadCommit returns the SHA of the latest commit on the default branch
+func (ac *AuthenticatedClient) HeadCommit(ctx context.Context, owner, repo string) (string, error) {
+	commits, _, err := ac.Client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest commit: %w", err)
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("repository %s/%s has no commits", owner, repo)
+	}
+	return commits[0].GetSHA(), nil
 }
 
-func PrintInfo(text string) {
-	fmt.Println(infoStyle.Render("ℹ️  " + text))
+// CommitTime returns when a commit, given by full or abbreviated SHA, was
+// committed
+func (ac *AuthenticatedClient) CommitTime(ctx context.Context, owner, repo, sha string) (time.Time, error) {
+	commit, resp, err := ac.Client.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity) {
+			return time.Time{}, fmt.Errorf("commit %s: %w", sha, fs.ErrNotExist)
+		}
+		return time.Time{}, fmt.Errorf("failed to get commit %s: %w", sha, err)
+	}
+	return commit.GetCommit().GetCommitter().GetDate().Time, nil
 }
 
-func PrintPrompt(text string) {
-	fmt.Print(promptStyle.Render("❓ " + text + ": "))
-}
\ No newline at end of file
+// ChangedFiles returns the names of the files directly inside dir that
+// commits at or after since added or modified and that still exist. It
+// walks the commit history of dir, so files untouched since then cost no
+// requests.
+func (ac *AuthenticatedClient) ChangedFiles(ctx context.Context, owner, repo, dir string, since time.Time) (map[string]bool, error) {
+	opts := &github.CommitsListOptions{
+		Path:        dir,
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var shas []string
+	for {
+		commits, resp, err := ac.Client.Repositories.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list commits of %s: %w", dir, err)
+		}
+		for _, commit := range commits {
+			shas = append(shas, commit.GetSHA())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// Commits are newest first, so the first status seen for a file is
+	// its current one
+	changed := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, sha := range shas {
+		commit, _, err := ac.Client.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", sha, err)
+		}
+		for _, file := range commit.Files {
+			name := file.GetFilename()
+			if seen[name] || path.Dir(name) != dir {
+				continue
+			}
+			seen[name] = true
+			if file.GetStatus() != "removed" {
+				changed[path.Base(name)] = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// BlobSHA returns the git blob hash of data, which is how GitHub identifies
+// file content, so local files can be compared without downloading
+func BlobSHA(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DeleteFile removes a file from a GitHub repository
+func (ac *AuthenticatedClient) DeleteFile(ctx context.Context, owner, repo, path, message string) error {
+	existingFile, _, _, err := ac.Client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get existing file: %w", err)
+	}
+
+	opts := &github.RepositoryContentFileOptions{
+		Message:   github.String(message),
+		SHA:       existingFile.SHA,
+		Author:    ac.commitAuthor(),
+		Committer: ac.commitAuthor(),
+	}
+
+	if _, _, err := ac.Client.Repositories.DeleteFile(ctx, owner, repo, path, opts); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
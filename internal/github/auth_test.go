@@ -0,0 +1,48 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestSSHHost(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"", "github.com"},
+		{"https://github.example.com/", "github.example.com"},
+		{"https://ghe.internal:8443", "ghe.internal"},
+	}
+
+	for _, tt := range tests {
+		if got := SSHHost(tt.baseURL); got != tt.want {
+			t.Errorf("SSHHost(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	client, err := withBaseURL(github.NewClient(nil), "")
+	if err != nil {
+		t.Fatalf("withBaseURL() error = %v", err)
+	}
+	if got := client.BaseURL.String(); got != "https://api.github.com/" {
+		t.Errorf("default BaseURL = %q, want github.com", got)
+	}
+
+	client, err = withBaseURL(github.NewClient(nil), "https://github.example.com")
+	if err != nil {
+		t.Fatalf("withBaseURL() error = %v", err)
+	}
+	if got, want := client.BaseURL.String(), "https://github.example.com/api/v3/"; got != want {
+		t.Errorf("enterprise BaseURL = %q, want %q", got, want)
+	}
+	if got, want := client.UploadURL.String(), "https://github.example.com/api/uploads/"; got != want {
+		t.Errorf("enterprise UploadURL = %q, want %q", got, want)
+	}
+	if _, err := withBaseURL(github.NewClient(nil), "http://github.example.com"); err == nil {
+		t.Error("withBaseURL() accepted a plain http address")
+	}
+}
@@ -38,6 +38,8 @@ func NewClient(token, repository string) (*Client, error) {
 		return nil, fmt.Errorf("invalid repository format, expected owner/repo")
 	}
 
+	RegisterSecret(token)
+
 	// Create OAuth2 token source
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
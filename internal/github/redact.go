@@ -0,0 +1,77 @@
+package github
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sshhades/sshhades/internal/config"
+)
+
+// tokenPattern matches GitHub token formats: classic, OAuth, user-to-server,
+// server-to-server and refresh tokens (ghp_, gho_, ghu_, ghs_, ghr_) and
+// fine-grained personal access tokens (github_pat_)
+var tokenPattern = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`)
+
+// minSecretLength keeps RegisterSecret from scrubbing short strings that
+// would match ordinary text
+const minSecretLength = 8
+
+var (
+	secretsMu sync.Mutex
+	secrets   []string
+)
+
+// RegisterSecret adds a credential that Redact scrubs from then on. Clients
+// register their token when they are created, so it can never appear in an
+// error message even if it does not look like a GitHub token.
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// Redact replaces registered secrets and anything that looks like a GitHub
+// token in text with config.RedactSecret
+func Redact(text string) string {
+	secretsMu.Lock()
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, config.RedactSecret(secret))
+	}
+	secretsMu.Unlock()
+
+	return tokenPattern.ReplaceAllStringFunc(text, config.RedactSecret)
+}
+
+// redactedError carries a scrubbed message while keeping the original error
+// available to errors.Is and errors.As
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactError returns err with Redact applied to its message, or err itself
+// when there was nothing to scrub
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	if redacted := Redact(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
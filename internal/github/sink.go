@@ -0,0 +1,200 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/sshhades/sshhades/internal/config"
+)
+
+// uploadTimeout bounds a single backup upload
+const uploadTimeout = 30 * time.Second
+
+// uploadAttempts is how many times Write tries an upload that GitHub turned
+// down because of a concurrent commit or a secondary rate limit
+const uploadAttempts = 3
+
+// uploadBackoff is the delay before the first retry; it doubles each time
+const uploadBackoff = time.Second
+
+// maxRetryAfter is the longest Write waits when GitHub asks it to slow down
+const maxRetryAfter = time.Minute
+
+// historyTimeout bounds walking the commit history of the backup directory,
+// which takes one request per commit
+const historyTimeout = 2 * time.Minute
+
+// DefaultBackupDir is the repository directory backups are uploaded into
+const DefaultBackupDir = "ssh-keys"
+
+// Sink uploads encrypted backups into the ssh-keys/ directory of the
+// configured repository. It satisfies storage.Sink.
+type Sink struct {
+	// Dir is the repository directory backups are written into
+	Dir string
+
+	// Context, when set, bounds every request in addition to the per-request
+	// timeouts, e.g. to the deadline of the whole command
+	Context context.Context
+
+	client  *AuthenticatedClient
+	config  *config.GitHubConfig
+	comment string
+}
+
+// NewSink creates a GitHub sink from the stored configuration
+func NewSink(cfg *config.Config, comment string) (*Sink, error) {
+	if !cfg.IsGitHubConfigured() {
+		return nil, fmt.Errorf("GitHub is not configured. Run 'sshhades github login' first")
+	}
+
+	githubCfg := cfg.GetGitHubConfig()
+
+	// Create authenticated client
+	client, err := NewAuthenticatedClient(githubCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+
+	return &Sink{
+		Dir:     DefaultBackupDir,
+		client:  client,
+		config:  githubCfg,
+		comment: comment,
+	}, nil
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string {
+	return "github"
+}
+
+// List returns the files in Dir
+func (s *Sink) List() ([]RemoteFile, error) {
+	ctx, cancel := context.WithTimeout(s.context(), uploadTimeout)
+	defer cancel()
+
+	return s.client.ListFiles(ctx, s.config.RepoOwner, s.config.RepoName, s.Dir)
+}
+
+// Head returns the SHA of the latest commit in the repository
+func (s *Sink) Head() (string, error) {
+	ctx, cancel := context.WithTimeout(s.context(), uploadTimeout)
+	defer cancel()
+
+	return s.client.HeadCommit(ctx, s.config.RepoOwner, s.config.RepoName)
+}
+
+// CommitTime returns when the commit sha was made
+func (s *Sink) CommitTime(sha string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(s.context(), uploadTimeout)
+	defer cancel()
+
+	return s.client.CommitTime(ctx, s.config.RepoOwner, s.config.RepoName, sha)
+}
+
+// ChangedSince returns the names of the files in Dir added or modified by
+// commits at or after since
+func (s *Sink) ChangedSince(since time.Time) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(s.context(), historyTimeout)
+	defer cancel()
+
+	return s.client.ChangedFiles(ctx, s.config.RepoOwner, s.config.RepoName, s.Dir, since)
+}
+
+// Read downloads <Dir>/<name>. It satisfies storage.Source.
+func (s *Sink) Read(name string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(s.context(), uploadTimeout)
+	defer cancel()
+
+	return s.client.DownloadFile(ctx, s.config.RepoOwner, s.config.RepoName, path.Join(s.Dir, name))
+}
+
+// Exists reports whether <Dir>/<name> is already in the repository
+func (s *Sink) Exists(name string) (bool, error) {
+	if _, err := s.Read(name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Write uploads data as <Dir>/<name>
+func (s *Sink) Write(name string, data []byte) error {
+	remotePath := path.Join(s.Dir, name)
+
+	commitMessage := fmt.Sprintf("Backup SSH key: %s", name)
+	if s.comment != "" {
+		commitMessage = fmt.Sprintf("Backup SSH key: %s - %s", name, s.comment)
+	}
+
+	backoff := uploadBackoff
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(s.context(), uploadTimeout)
+		err = s.client.UploadFile(ctx, s.config.RepoOwner, s.config.RepoName, remotePath, data, commitMessage)
+		cancel()
+
+		delay, retry := uploadRetryDelay(err, backoff)
+		if !retry {
+			return err
+		}
+		if attempt < uploadAttempts {
+			if err := sleepContext(s.context(), delay); err != nil {
+				return err
+			}
+			backoff *= 2
+		}
+	}
+
+	return fmt.Errorf("%w (gave up after %d attempts)", err, uploadAttempts)
+}
+
+// context returns the context requests are bounded by
+func (s *Sink) context() context.Context {
+	if s.Context == nil {
+		return context.Background()
+	}
+	return s.Context
+}
+
+// sleepContext waits for delay, or returns the error of ctx once it ends
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// uploadRetryDelay reports whether a failed upload is worth retrying and how
+// long to wait first. Commits that raced another commit to the branch (409
+// Conflict) and secondary rate limits are retried; the primary rate limit
+// resets up to an hour later, so it is returned at once like other errors.
+func uploadRetryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var abuse *github.AbuseRateLimitError
+	if errors.As(err, &abuse) {
+		if retryAfter := abuse.GetRetryAfter(); retryAfter > 0 {
+			return retryAfter, retryAfter <= maxRetryAfter
+		}
+		return backoff, true
+	}
+
+	var response *github.ErrorResponse
+	if errors.As(err, &response) && response.Response != nil && response.Response.StatusCode == http.StatusConflict {
+		return backoff, true
+	}
+	return 0, false
+}
@@ -0,0 +1,45 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+func TestUploadRetryDelay(t *testing.T) {
+	status := func(code int) error {
+		return fmt.Errorf("failed to create file: %w", &github.ErrorResponse{Response: &http.Response{StatusCode: code}})
+	}
+	after := func(d time.Duration) error {
+		return fmt.Errorf("failed to create file: %w", &github.AbuseRateLimitError{RetryAfter: &d})
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{"success", nil, 0, false},
+		{"conflict", status(http.StatusConflict), time.Second, true},
+		{"not found", status(http.StatusNotFound), 0, false},
+		{"secondary rate limit", after(5 * time.Second), 5 * time.Second, true},
+		{"secondary rate limit without delay", &github.AbuseRateLimitError{}, time.Second, true},
+		{"secondary rate limit too long", after(time.Hour), time.Hour, false},
+		{"primary rate limit", &github.RateLimitError{}, 0, false},
+		{"other error", errors.New("connection reset"), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := uploadRetryDelay(tt.err, time.Second)
+			if delay != tt.wantDelay || retry != tt.wantRetry {
+				t.Errorf("uploadRetryDelay() = %v, %v, want %v, %v", delay, retry, tt.wantDelay, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sshhades/sshhades/internal/config"
+)
+
+// FileName is the name of the manifest inside the config directory
+const FileName = "manifest.jsonl"
+
+// Entry records one successful backup
+type Entry struct {
+	Time        time.Time `json:"time" yaml:"time"`
+	File        string    `json:"file" yaml:"file"`
+	Fingerprint string    `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	Algorithm   string    `json:"algorithm" yaml:"algorithm"`
+	Targets     []string  `json:"targets,omitempty" yaml:"targets,omitempty"`
+
+	// PrevHash is the SHA-256 of the previous manifest line, chaining the
+	// entries so edits or deletions of earlier lines can be detected
+	PrevHash string `json:"prev_hash,omitempty" yaml:"prev_hash,omitempty"`
+}
+
+// DefaultPath returns the manifest location under the config directory
+func DefaultPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Append adds an entry to the manifest at path, chaining it to the last line
+func Append(path string, entry Entry) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	last, err := lastLine(file)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if last != nil {
+		entry.PrevHash = hashLine(last)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest entry: %w", err)
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return file.Sync()
+}
+
+// Read returns every entry in the manifest. A missing manifest is empty.
+func Read(path string) ([]Entry, error) {
+	var entries []Entry
+	err := scan(path, func(_ int, line []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// Verify checks the hash chain and reports the first line that breaks it
+func Verify(path string) error {
+	var prev []byte
+	return scan(path, func(number int, line []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+
+		expected := ""
+		if prev != nil {
+			expected = hashLine(prev)
+		}
+		if entry.PrevHash != expected {
+			return fmt.Errorf("hash chain broken: previous line was modified, removed or reordered")
+		}
+
+		prev = append(prev[:0], line...)
+		return nil
+	})
+}
+
+// scan calls fn for every non-empty line, numbering lines from 1
+func scan(path string, fn func(number int, line []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(number, line); err != nil {
+			return fmt.Errorf("manifest line %d: %w", number, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// lastLine returns the final non-empty line of the file, or nil if empty
+func lastLine(file *os.File) ([]byte, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return bytes.TrimSpace(data), nil
+}
+
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendEntries(t *testing.T, path string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		entry := Entry{Time: time.Now().UTC(), File: file, Algorithm: "AES-256-GCM"}
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	appendEntries(t, path, "a.enc", "b.enc", "c.enc")
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	if entries[0].PrevHash != "" {
+		t.Error("First entry should not have a previous hash")
+	}
+
+	for i, entry := range entries[1:] {
+		if entry.PrevHash == "" {
+			t.Errorf("Entry %d is not chained", i+1)
+		}
+	}
+
+	if err := Verify(path); err != nil {
+		t.Errorf("Verify failed on untouched manifest: %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	appendEntries(t, path, "a.enc", "b.enc", "c.enc")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := strings.Replace(string(data), "b.enc", "x.enc", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Verify(path)
+	if err == nil {
+		t.Fatal("Verify should detect a modified entry")
+	}
+
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Error should point at the line after the edit: %v", err)
+	}
+}
+
+func TestReadMissingManifest(t *testing.T) {
+	entries, err := Read(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Missing manifest should read as empty: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries, got %d", len(entries))
+	}
+}
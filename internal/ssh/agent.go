@@ -0,0 +1,118 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ValidateAgentLifetime checks that a key lifetime fits the agent protocol,
+// which counts whole seconds in 32 bits. Zero means no lifetime.
+func ValidateAgentLifetime(lifetime time.Duration) error {
+	if lifetime == 0 {
+		return nil
+	}
+	if lifetime < time.Second {
+		return fmt.Errorf("agent lifetime %s is too short: must be at least 1s", lifetime)
+	}
+	if lifetime/time.Second > math.MaxUint32 {
+		return fmt.Errorf("agent lifetime %s is too long: must be at most %ds", lifetime, uint32(math.MaxUint32))
+	}
+	return nil
+}
+
+// AgentIdentity describes a key held by a running ssh-agent
+type AgentIdentity struct {
+	Type        string
+	Comment     string
+	Fingerprint string
+}
+
+// ListAgentIdentities enumerates the keys of the agent listening on
+// $SSH_AUTH_SOCK. The ssh-agent protocol only exposes public keys and
+// signing operations; it has no request that returns private key material.
+func ListAgentIdentities() ([]AgentIdentity, error) {
+	conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent identities: %w", err)
+	}
+
+	identities := make([]AgentIdentity, 0, len(keys))
+	for _, key := range keys {
+		identities = append(identities, AgentIdentity{
+			Type:        key.Format,
+			Comment:     key.Comment,
+			Fingerprint: gossh.FingerprintSHA256(key),
+		})
+	}
+
+	return identities, nil
+}
+
+// AddToAgent loads a decrypted private key into the agent at $SSH_AUTH_SOCK.
+// A non-zero lifetime makes the agent forget the key after that duration.
+func AddToAgent(keyData []byte, comment string, lifetime time.Duration) error {
+	if err := ValidateAgentLifetime(lifetime); err != nil {
+		return err
+	}
+
+	privateKey, err := gossh.ParseRawPrivateKey(keyData)
+	if err != nil {
+		var missing *gossh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return fmt.Errorf("key is protected by its own passphrase; add it with ssh-add instead")
+		}
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	// Best-effort wipe of the parsed copy once the agent has it
+	if key, ok := privateKey.(*ed25519.PrivateKey); ok {
+		defer clear(*key)
+	}
+
+	conn, err := dialAgent()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	addedKey := agent.AddedKey{
+		PrivateKey:   privateKey,
+		Comment:      comment,
+		LifetimeSecs: uint32(lifetime / time.Second),
+	}
+
+	if err := agent.NewClient(conn).Add(addedKey); err != nil {
+		return fmt.Errorf("ssh-agent refused the key: %w", err)
+	}
+
+	return nil
+}
+
+// dialAgent connects to the agent socket named by $SSH_AUTH_SOCK
+func dialAgent() (net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set; is ssh-agent running?")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+
+	return conn, nil
+}
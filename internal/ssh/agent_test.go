@@ -0,0 +1,37 @@
+package ssh
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestValidateAgentLifetime(t *testing.T) {
+	tests := []struct {
+		lifetime time.Duration
+		valid    bool
+	}{
+		{0, true},
+		{time.Second, true},
+		{8 * time.Hour, true},
+		{math.MaxUint32 * time.Second, true},
+		{500 * time.Millisecond, false},
+		{-time.Second, false},
+		{(math.MaxUint32 + 1) * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateAgentLifetime(tt.lifetime)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateAgentLifetime(%s) error = %v, want valid %v", tt.lifetime, err, tt.valid)
+		}
+	}
+}
+
+func TestAddToAgentRejectsLifetime(t *testing.T) {
+	// The lifetime is checked before the key or the agent socket
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if err := AddToAgent(nil, "", 100*time.Millisecond); err == nil || err.Error() != "agent lifetime 100ms is too short: must be at least 1s" {
+		t.Errorf("AddToAgent() error = %v, want the lifetime rejected", err)
+	}
+}
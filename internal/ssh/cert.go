@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/sshhades/sshhades/internal/crypto"
+	"github.com/sshhades/sshhades/pkg/format"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// certSuffix ends the key type of every OpenSSH certificate, e.g.
+// ssh-ed25519-cert-v01@openssh.com
+const certSuffix = "-cert-v01@openssh.com"
+
+// IsCertificate reports whether data is an OpenSSH certificate line
+func IsCertificate(data []byte) bool {
+	fields := bytes.Fields(data)
+	return len(fields) > 0 && bytes.HasSuffix(fields[0], []byte(certSuffix))
+}
+
+// ParseCertificate parses an OpenSSH certificate line and describes it
+func ParseCertificate(data []byte) (*format.CertificateInfo, error) {
+	cert, err := parseCertificate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &format.CertificateInfo{
+		Type:       RoleUser,
+		KeyID:      cert.KeyId,
+		Serial:     cert.Serial,
+		Principals: cert.ValidPrincipals,
+		CA:         gossh.FingerprintSHA256(cert.SignatureKey),
+	}
+	if cert.CertType == gossh.HostCert {
+		info.Type = RoleHost
+	}
+	if cert.ValidAfter != 0 {
+		after := certTime(cert.ValidAfter)
+		info.ValidAfter = &after
+	}
+	if cert.ValidBefore != gossh.CertTimeInfinity {
+		before := certTime(cert.ValidBefore)
+		info.ValidBefore = &before
+	}
+	return info, nil
+}
+
+// CertifiesKey reports whether the certificate in cert is for the key in
+// data, private or public
+func CertifiesKey(cert, data []byte) bool {
+	c, err := parseCertificate(cert)
+	if err != nil {
+		return false
+	}
+	pub, err := ParsePublicKey(data)
+	if err != nil {
+		return false
+	}
+	return crypto.Equal(c.Key.Marshal(), pub.Marshal())
+}
+
+// parseCertificate parses an OpenSSH certificate line
+func parseCertificate(data []byte) (*gossh.Certificate, error) {
+	pub, _, _, _, err := gossh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	cert, ok := pub.(*gossh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("not an OpenSSH certificate")
+	}
+	return cert, nil
+}
+
+// certTime converts a certificate validity bound, in seconds since the
+// epoch, to a time
+func certTime(seconds uint64) time.Time {
+	if seconds > uint64(1<<62) {
+		seconds = 1 << 62
+	}
+	return time.Unix(int64(seconds), 0).UTC()
+}
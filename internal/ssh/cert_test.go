@@ -0,0 +1,163 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// newTestCertificate returns a private key and a user certificate for it
+// signed by a fresh CA, in the formats ssh-keygen writes them
+func newTestCertificate(t *testing.T, validBefore uint64) (privateKey, certificate []byte) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sshPub, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert public key: %v", err)
+	}
+	block, err := gossh.MarshalPrivateKey(priv, "alice@example.com")
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+
+	_, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	signer, err := gossh.NewSignerFromKey(caPriv)
+	if err != nil {
+		t.Fatalf("Failed to create CA signer: %v", err)
+	}
+
+	cert := &gossh.Certificate{
+		Key:             sshPub,
+		Serial:          42,
+		CertType:        gossh.UserCert,
+		KeyId:           "alice@corp",
+		ValidPrincipals: []string{"alice", "deploy"},
+		ValidAfter:      1700000000,
+		ValidBefore:     validBefore,
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		t.Fatalf("Failed to sign certificate: %v", err)
+	}
+
+	return pem.EncodeToMemory(block), gossh.MarshalAuthorizedKey(cert)
+}
+
+func TestParseCertificate(t *testing.T) {
+	_, certData := newTestCertificate(t, 1800000000)
+
+	if !IsCertificate(certData) {
+		t.Fatal("IsCertificate() = false for a certificate")
+	}
+	if !IsValidSSHKey(certData) {
+		t.Error("IsValidSSHKey() = false for a certificate")
+	}
+	if got := DetectKeyType(certData); got != "ed25519-cert" {
+		t.Errorf("DetectKeyType() = %q, want ed25519-cert", got)
+	}
+
+	cert, err := ParseCertificate(certData)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	if cert.Type != RoleUser || cert.KeyID != "alice@corp" || cert.Serial != 42 {
+		t.Errorf("ParseCertificate() = %+v, want user certificate alice@corp with serial 42", cert)
+	}
+	if cert.PrincipalList() != "alice, deploy" {
+		t.Errorf("PrincipalList() = %q, want %q", cert.PrincipalList(), "alice, deploy")
+	}
+	if cert.ValidAfter == nil || !cert.ValidAfter.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("ValidAfter = %v, want %v", cert.ValidAfter, time.Unix(1700000000, 0).UTC())
+	}
+	if !cert.Expired(time.Unix(1800000000, 0)) || cert.Expired(time.Unix(1799999999, 0)) {
+		t.Error("Expired() does not end at ValidBefore")
+	}
+}
+
+func TestParseCertificateForever(t *testing.T) {
+	_, certData := newTestCertificate(t, gossh.CertTimeInfinity)
+
+	cert, err := ParseCertificate(certData)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	if cert.ValidBefore != nil {
+		t.Errorf("ValidBefore = %v, want nil for a certificate valid forever", cert.ValidBefore)
+	}
+	if cert.Expired(time.Now()) {
+		t.Error("A certificate valid forever should not expire")
+	}
+}
+
+func TestParseCertificateRejectsPlainKey(t *testing.T) {
+	keyData, _ := newTestCertificate(t, gossh.CertTimeInfinity)
+	pub, err := ParsePublicKey(keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := gossh.MarshalAuthorizedKey(pub)
+	if IsCertificate(plain) {
+		t.Error("IsCertificate() = true for a plain public key")
+	}
+	if _, err := ParseCertificate(plain); err == nil {
+		t.Error("ParseCertificate() should fail for a plain public key")
+	}
+}
+
+func TestCertifiesKey(t *testing.T) {
+	keyData, certData := newTestCertificate(t, gossh.CertTimeInfinity)
+	otherKey, _ := newTestCertificate(t, gossh.CertTimeInfinity)
+
+	if !CertifiesKey(certData, keyData) {
+		t.Error("CertifiesKey() = false for the certified private key")
+	}
+	pub, err := ParsePublicKey(keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CertifiesKey(certData, gossh.MarshalAuthorizedKey(pub)) {
+		t.Error("CertifiesKey() = false for the certified public key")
+	}
+	if CertifiesKey(certData, otherKey) {
+		t.Error("CertifiesKey() = true for another key")
+	}
+}
+
+func TestScanSSHKeysCertificate(t *testing.T) {
+	keyData, certData := newTestCertificate(t, gossh.CertTimeInfinity)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "id_ed25519"), keyData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	certPath := CertificatePath(filepath.Join(dir, "id_ed25519"))
+	if err := os.WriteFile(certPath, certData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ScanSSHKeys(dir)
+	if err != nil {
+		t.Fatalf("ScanSSHKeys() error = %v", err)
+	}
+	if len(result.Keys) != 2 {
+		t.Fatalf("ScanSSHKeys() found %d keys, want the key and its certificate", len(result.Keys))
+	}
+	for _, key := range result.Keys {
+		if (key.Path == certPath) != (key.Certificate != nil) {
+			t.Errorf("%s: Certificate = %v", key.Path, key.Certificate)
+		}
+	}
+}
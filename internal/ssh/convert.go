@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sshhades/sshhades/internal/crypto"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Private key formats restore --key-format can write
+const (
+	// KeyFormatOpenSSH is the format of ssh-keygen since OpenSSH 7.8
+	// (BEGIN OPENSSH PRIVATE KEY)
+	KeyFormatOpenSSH = "openssh"
+
+	// KeyFormatPKCS8 is PEM-encoded PKCS#8 (BEGIN PRIVATE KEY)
+	KeyFormatPKCS8 = "pkcs8"
+
+	// KeyFormatPEM is the traditional PEM format: PKCS#1 for RSA keys
+	// (BEGIN RSA PRIVATE KEY) and SEC 1 for ECDSA keys (BEGIN EC PRIVATE
+	// KEY). Ed25519 keys have no such format.
+	KeyFormatPEM = "pem"
+)
+
+// KeyFormats lists the formats ConvertPrivateKey accepts
+var KeyFormats = []string{KeyFormatOpenSSH, KeyFormatPKCS8, KeyFormatPEM}
+
+// ValidateKeyFormat checks that name is one of KeyFormats
+func ValidateKeyFormat(name string) error {
+	for _, f := range KeyFormats {
+		if name == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key format %q (use %s)", name, strings.Join(KeyFormats, ", "))
+}
+
+// PrivateKeyFormat returns the format of an unencrypted private key, or ""
+// when data is not one ConvertPrivateKey produces
+func PrivateKeyFormat(data []byte) string {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return ""
+	}
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		return KeyFormatOpenSSH
+	case "PRIVATE KEY":
+		return KeyFormatPKCS8
+	case "RSA PRIVATE KEY", "EC PRIVATE KEY":
+		if _, encrypted := block.Headers["DEK-Info"]; encrypted {
+			return ""
+		}
+		return KeyFormatPEM
+	}
+	return ""
+}
+
+// ConvertPrivateKey re-encodes a private key in another format. Keys
+// protected by their own passphrase, public keys and combinations a format
+// cannot hold (such as Ed25519 in traditional PEM) are an error. The OpenSSH
+// format stores a comment, which the other formats do not carry, so a
+// converted OpenSSH key has none.
+func ConvertPrivateKey(data []byte, keyFormat string) ([]byte, error) {
+	if err := ValidateKeyFormat(keyFormat); err != nil {
+		return nil, err
+	}
+	if !IsPrivateKey(data) {
+		return nil, fmt.Errorf("not a private key; only private keys can be converted")
+	}
+
+	key, err := gossh.ParseRawPrivateKey(data)
+	if err != nil {
+		var missing *gossh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("the key is protected by its own passphrase and cannot be converted")
+		}
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	// OpenSSH Ed25519 keys parse to a pointer, which the encoders reject
+	if p, ok := key.(*ed25519.PrivateKey); ok {
+		key = *p
+	}
+
+	var block *pem.Block
+	switch keyFormat {
+	case KeyFormatOpenSSH:
+		block, err = gossh.MarshalPrivateKey(key, "")
+	case KeyFormatPKCS8:
+		var der []byte
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	case KeyFormatPEM:
+		block, err = traditionalPEM(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode the key as %s: %w", keyFormat, err)
+	}
+
+	encoded := pem.EncodeToMemory(block)
+	crypto.ClearBytes(block.Bytes)
+	return encoded, nil
+}
+
+// traditionalPEM encodes a key in its pre-PKCS#8 PEM format
+func traditionalPEM(key interface{}) (*pem.Block, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}, nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+	}
+	return nil, fmt.Errorf("traditional PEM holds only RSA and ECDSA keys (use %s)", KeyFormatPKCS8)
+}
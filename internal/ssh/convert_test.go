@@ -0,0 +1,106 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestConvertPrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []struct {
+		name string
+		key  crypto.PrivateKey
+	}{
+		{"rsa", rsaKey},
+		{"ecdsa", ecKey},
+		{"ed25519", edKey},
+	}
+
+	for _, k := range keys {
+		block, err := gossh.MarshalPrivateKey(k.key, "test@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := pem.EncodeToMemory(block)
+		want, err := Fingerprint(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, keyFormat := range KeyFormats {
+			t.Run(k.name+"/"+keyFormat, func(t *testing.T) {
+				converted, err := ConvertPrivateKey(data, keyFormat)
+				if k.name == "ed25519" && keyFormat == KeyFormatPEM {
+					if err == nil {
+						t.Fatal("Ed25519 has no traditional PEM format; expected an error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ConvertPrivateKey() error = %v", err)
+				}
+
+				if got := PrivateKeyFormat(converted); got != keyFormat {
+					t.Errorf("PrivateKeyFormat() = %q, want %q", got, keyFormat)
+				}
+				if got, _ := Fingerprint(converted); got != want {
+					t.Errorf("Converted key has fingerprint %s, want %s", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestConvertPrivateKeyRejects(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected, err := gossh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		keyFormat string
+	}{
+		{"passphrase-protected key", pem.EncodeToMemory(protected), KeyFormatPKCS8},
+		{"public key", gossh.MarshalAuthorizedKey(sshPub), KeyFormatOpenSSH},
+		{"unknown format", pem.EncodeToMemory(protected), "der"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ConvertPrivateKey(tt.data, tt.keyFormat); err == nil {
+				t.Error("ConvertPrivateKey() should fail")
+			}
+		})
+	}
+}
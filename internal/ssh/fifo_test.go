@@ -0,0 +1,71 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWriteKeyFileNamedPipe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := syscall.Mkfifo(path, 0640); err != nil {
+		t.Skipf("cannot create a named pipe: %v", err)
+	}
+	if !IsNamedPipe(path) {
+		t.Fatal("IsNamedPipe() = false for a FIFO")
+	}
+
+	received := make(chan []byte, 1)
+	go func() {
+		pipe, err := os.Open(path)
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer pipe.Close()
+		data, _ := io.ReadAll(pipe)
+		received <- data
+	}()
+
+	if err := WriteKeyFile(path, []byte("key material"), true); err != nil {
+		t.Fatalf("WriteKeyFile() error = %v", err)
+	}
+	if got := <-received; string(got) != "key material" {
+		t.Errorf("reader got %q, want %q", got, "key material")
+	}
+
+	// The pipe is neither replaced by a regular file nor chmodded
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("%s is no longer a named pipe", path)
+	}
+	if perm := info.Mode().Perm(); perm != 0640 {
+		t.Errorf("pipe mode changed to %o, want 640", perm)
+	}
+}
+
+func TestWriteKeyFileRefusesSpecialFiles(t *testing.T) {
+	if _, err := os.Stat(os.DevNull); err != nil {
+		t.Skip("no null device")
+	}
+	if IsNamedPipe(os.DevNull) {
+		t.Errorf("IsNamedPipe(%s) = true", os.DevNull)
+	}
+
+	err := WriteKeyFile(os.DevNull, []byte("key"), true)
+	if !errors.Is(err, ErrSpecialFile) {
+		t.Errorf("WriteKeyFile(%s) error = %v, want ErrSpecialFile", os.DevNull, err)
+	}
+
+	if err := WriteKeyFile(t.TempDir(), []byte("key"), true); err == nil {
+		t.Error("WriteKeyFile() to a directory succeeded")
+	}
+}
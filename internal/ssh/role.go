@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Key roles reported by ClassifyKeyRole
+const (
+	RoleUser = "user"
+	RoleHost = "host"
+	RoleCA   = "ca"
+)
+
+// ClassifyKeyRole labels a key as a user key, host key or SSH certificate
+// authority key. path is the key's file path (or empty for stdin) and data
+// its content. Evidence is taken, strongest first, from an OpenSSH
+// certificate (the key's own, or a <path>-cert.pub next to it), a
+// cert-authority option on the public key line, the key comment (for a
+// private key, from <path>.pub), and the file name. Keys with no evidence
+// are user keys.
+func ClassifyKeyRole(path string, data []byte) string {
+	if role := certificateRole(data); role != "" {
+		return role
+	}
+	if path != "" {
+		if cert, err := os.ReadFile(CertificatePath(path)); err == nil {
+			if role := certificateRole(cert); role != "" {
+				return role
+			}
+		}
+	}
+
+	// A private key's comment and options are read from its .pub sibling
+	public := data
+	if IsPrivateKey(data) {
+		public = nil
+		if path != "" {
+			public, _ = os.ReadFile(path + ".pub")
+		}
+	}
+
+	comment := ""
+	if pub, c, options, _, err := gossh.ParseAuthorizedKey(public); err == nil && pub != nil {
+		comment = c
+		for _, option := range options {
+			if option == "cert-authority" {
+				return RoleCA
+			}
+		}
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(public)), "@cert-authority") {
+		return RoleCA
+	}
+
+	if role := commentRole(comment); role != "" {
+		return role
+	}
+	if path != "" {
+		if role := nameRole(filepath.Base(path)); role != "" {
+			return role
+		}
+	}
+	return RoleUser
+}
+
+// CertificatePath returns where ssh-keygen -s writes the certificate for
+// the key at path, private or public
+func CertificatePath(path string) string {
+	return strings.TrimSuffix(path, ".pub") + "-cert.pub"
+}
+
+// certificateRole returns the role stated by an OpenSSH certificate, or ""
+// when data is not one
+func certificateRole(data []byte) string {
+	if IsPrivateKey(data) {
+		return ""
+	}
+	pub, _, _, _, err := gossh.ParseAuthorizedKey(data)
+	if err != nil {
+		return ""
+	}
+	cert, ok := pub.(*gossh.Certificate)
+	if !ok {
+		return ""
+	}
+	if cert.CertType == gossh.HostCert {
+		return RoleHost
+	}
+	return RoleUser
+}
+
+// commentRole recognizes comments such as "user CA" or "host key"
+func commentRole(comment string) string {
+	lower := strings.ToLower(comment)
+	if strings.Contains(lower, "certificate authority") || strings.Contains(lower, "cert-authority") {
+		return RoleCA
+	}
+	for _, word := range strings.FieldsFunc(lower, isNameSeparator) {
+		if word == "ca" {
+			return RoleCA
+		}
+	}
+	if strings.Contains(lower, "host key") {
+		return RoleHost
+	}
+	return ""
+}
+
+// nameRole recognizes the file names sshd and common CA setups use, such as
+// ssh_host_ed25519_key and user_ca
+func nameRole(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".pub")
+	if strings.HasPrefix(name, "ssh_host_") {
+		return RoleHost
+	}
+	for _, word := range strings.FieldsFunc(name, isNameSeparator) {
+		if word == "ca" {
+			return RoleCA
+		}
+	}
+	return ""
+}
+
+// isNameSeparator splits comments and file names into words
+func isNameSeparator(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Sink is a destination that encrypted backups can be written to
+type Sink interface {
+	// Name identifies the sink in progress and error messages
+	Name() string
+
+	// Write stores data under the given backup file name
+	Write(name string, data []byte) error
+}
+
+// Source is a location that encrypted backups can be fetched from
+type Source interface {
+	// Name identifies the source in progress and error messages
+	Name() string
+
+	// Read returns the backup stored under the given file name
+	Read(name string) ([]byte, error)
+}
+
+// WriteResult reports the outcome of writing to a single sink
+type WriteResult struct {
+	Sink string
+	Err  error
+}
+
+// LocalSink writes backups into a directory on the local filesystem
+type LocalSink struct {
+	Dir string
+
+	// Context, when set, is checked before writing, so a command past its
+	// deadline leaves no new file behind
+	Context context.Context
+}
+
+// NewLocalSink creates a sink that writes into dir
+func NewLocalSink(dir string) *LocalSink {
+	return &LocalSink{Dir: dir}
+}
+
+// Name returns the sink name
+func (s *LocalSink) Name() string {
+	return "local"
+}
+
+// Write saves data to Dir/name with restrictive permissions
+func (s *LocalSink) Write(name string, data []byte) error {
+	if s.Context != nil {
+		if err := s.Context.Err(); err != nil {
+			return err
+		}
+	}
+	return writeFile(filepath.Join(s.Dir, name), data)
+}
+
+// WriteAll writes data to every sink, continuing past failures so that one
+// unreachable target does not prevent the others from receiving the backup.
+// The returned error joins every sink failure.
+func WriteAll(sinks []Sink, name string, data []byte) ([]WriteResult, error) {
+	results := make([]WriteResult, 0, len(sinks))
+	var errs []error
+
+	for _, sink := range sinks {
+		err := sink.Write(name, data)
+		results = append(results, WriteResult{Sink: sink.Name(), Err: err})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// writeFile creates parent directories and writes data with 0600 permissions
+// through WriteFileAtomic. New directories are 0700, matching
+// ssh.WriteKeyFile, so a backup directory is never left traversable by
+// other users.
+func writeFile(path string, data []byte) error {
+	// Ensure directory exists
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to file with restrictive permissions
+	if err := WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write encrypted file: %w", err)
+	}
+
+	return nil
+}
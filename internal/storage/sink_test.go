@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeSink struct {
+	name    string
+	err     error
+	written map[string][]byte
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Write(name string, data []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.written[name] = data
+	return nil
+}
+
+func TestWriteAllFansOut(t *testing.T) {
+	tempDir := t.TempDir()
+	remote := &fakeSink{name: "remote", written: make(map[string][]byte)}
+	sinks := []Sink{NewLocalSink(tempDir), remote}
+
+	results, err := WriteAll(sinks, "id_ed25519.enc", []byte("data"))
+	if err != nil {
+		t.Fatalf("WriteAll failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "id_ed25519.enc"))
+	if err != nil {
+		t.Fatalf("Local sink did not write file: %v", err)
+	}
+	if string(content) != "data" {
+		t.Errorf("Local sink wrote %q, want %q", content, "data")
+	}
+
+	if string(remote.written["id_ed25519.enc"]) != "data" {
+		t.Error("Remote sink did not receive data")
+	}
+}
+
+func TestWriteAllAggregatesErrors(t *testing.T) {
+	errFirst := errors.New("first failed")
+	errSecond := errors.New("second failed")
+	healthy := &fakeSink{name: "healthy", written: make(map[string][]byte)}
+
+	sinks := []Sink{
+		&fakeSink{name: "first", err: errFirst},
+		healthy,
+		&fakeSink{name: "second", err: errSecond},
+	}
+
+	results, err := WriteAll(sinks, "backup.enc", []byte("data"))
+	if err == nil {
+		t.Fatal("WriteAll should report failures")
+	}
+
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("Error should wrap every sink failure: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "first") || !strings.Contains(err.Error(), "second") {
+		t.Errorf("Error should name failing sinks: %v", err)
+	}
+
+	if _, ok := healthy.written["backup.enc"]; !ok {
+		t.Error("A failing sink should not stop later sinks")
+	}
+
+	if results[1].Err != nil {
+		t.Errorf("Healthy sink reported error: %v", results[1].Err)
+	}
+}
+
+func TestLocalSinkChecksContext(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := NewLocalSink(dir)
+	sink.Context = ctx
+
+	if err := sink.Write("a.enc", []byte("data")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	cancel()
+	if err := sink.Write("b.enc", []byte("data")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Write() after the context ended = %v, want context.Canceled", err)
+	}
+	if FileExists(filepath.Join(dir, "b.enc")) {
+		t.Error("Write() after the context ended still wrote the file")
+	}
+}
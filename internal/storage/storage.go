@@ -1,46 +1,89 @@
 package storage
 
 import (
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strings"
 
 	"github.com/sshhades/sshhades/pkg/format"
 )
 
+// MaxEncryptedFileSize bounds how much of a file LoadEncryptedFile reads.
+// Even a 16384-bit RSA key encrypts to well under this; large bundles can
+// raise it with SSHHADES_MAX_FILE_SIZE.
+var MaxEncryptedFileSize int64 = 1 << 20
+
+// ErrFileTooLarge is returned by ReadFileLimited for files over the limit
+var ErrFileTooLarge = errors.New("file too large")
+
 // SaveEncryptedFile saves an encrypted file to disk
 func SaveEncryptedFile(path string, encFile *format.EncryptedFile) error {
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
 	// Convert to JSON
 	data, err := encFile.ToJSON()
 	if err != nil {
 		return fmt.Errorf("failed to serialize encrypted file: %w", err)
 	}
 
-	// Write to file with restrictive permissions
-	err = os.WriteFile(path, data, 0600)
+	return writeFile(path, data)
+}
+
+// WriteFileAtomic writes data to path with exactly perm by way of a
+// temporary file in the same directory that is synced and then renamed over
+// path, so an interrupted write never leaves a truncated file: path holds
+// either its old contents or all of data. An existing symlink at path is
+// followed and its target replaced, as a plain write would.
+func WriteFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
 	if err != nil {
-		return fmt.Errorf("failed to write encrypted file: %w", err)
+		return err
 	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	renamed = true
 	return nil
 }
 
 // LoadEncryptedFile loads an encrypted file from disk
 func LoadEncryptedFile(path string) (*format.EncryptedFile, error) {
 	// Read file
-	data, err := os.ReadFile(path)
+	data, err := ReadFileLimited(path, MaxEncryptedFileSize, "encrypted file")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read encrypted file: %w", err)
 	}
 
 	// Parse JSON
-	encFile, err := format.FromJSON(data)
+	encFile, err := format.Parse(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse encrypted file: %w", err)
 	}
@@ -48,6 +91,48 @@ func LoadEncryptedFile(path string) (*format.EncryptedFile, error) {
 	return encFile, nil
 }
 
+// ReadFileLimited reads a file of at most limit bytes. kind names what the
+// file should be, for the error returned when it is larger.
+func ReadFileLimited(path string, limit int64, kind string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Stat catches the common case before reading anything
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > limit {
+		return nil, tooLargeError(path, limit, kind)
+	}
+
+	// The limited read covers files that grow or report no size (pipes, /proc)
+	return ReadLimited(file, path, limit, kind)
+}
+
+// ReadLimited reads at most limit bytes from r, such as stdin. name and kind
+// describe the input for the error returned when it is larger; the bytes
+// read so far are then wiped, as the input may be a private key.
+func ReadLimited(r io.Reader, name string, limit int64, kind string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		clear(data)
+		return nil, tooLargeError(name, limit, kind)
+	}
+
+	return data, nil
+}
+
+func tooLargeError(name string, limit int64, kind string) error {
+	return fmt.Errorf("%w to be a valid %s: %s (limit %d bytes)", ErrFileTooLarge, kind, name, limit)
+}
+
 // FileExists checks if a file exists
 func FileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -80,4 +165,132 @@ func ValidatePath(path string) error {
 	
 	// Additional security checks can be added here
 	return nil
-}
\ No newline at end of file
+}
+
+// ShredFile overwrites a regular file with random bytes, syncs it and removes it.
+// This is best-effort: SSDs, copy-on-write and journaling filesystems may keep
+// earlier copies of the data. Read-only files, such as keys kept at 0400, are
+// made writable by their owner first, and get their mode back if the
+// overwrite fails.
+func ShredFile(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("refusing to shred non-regular file: %s", path)
+	}
+
+	mode := info.Mode().Perm()
+	restoreMode := func() {}
+	if mode&0200 == 0 {
+		if err := os.Chmod(path, mode|0200); err != nil {
+			return fmt.Errorf("%s is read-only and could not be made writable to shred it: %w", path, err)
+		}
+		restoreMode = func() { os.Chmod(path, mode) }
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		restoreMode()
+		return fmt.Errorf("failed to open file for shredding: %w", err)
+	}
+
+	_, copyErr := io.CopyN(file, rand.Reader, info.Size())
+	syncErr := file.Sync()
+	closeErr := file.Close()
+	if err := errors.Join(copyErr, syncErr, closeErr); err != nil {
+		restoreMode()
+		return fmt.Errorf("failed to overwrite file: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+
+	return nil
+}
+
+// ExpandPath expands a leading ~ or ~user to a home directory and
+// $VAR/${VAR} references to their environment values, so paths behave the
+// same whether or not a shell expanded them first. A reference to a
+// variable that is not set is an error.
+func ExpandPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		name, rest, _ := strings.Cut(path[1:], "/")
+
+		var homeDir string
+		if name == "" {
+			var err error
+			homeDir, err = os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to get home directory: %w", err)
+			}
+		} else {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", fmt.Errorf("cannot expand ~%s: %w", name, err)
+			}
+			homeDir = u.HomeDir
+		}
+		path = filepath.Join(homeDir, rest)
+	}
+
+	return expandVars(path)
+}
+
+// expandVars replaces $NAME and ${NAME} in path with the value of the
+// environment variable NAME. Unlike os.ExpandEnv it fails for a variable
+// that is not set instead of dropping it, and keeps a $ that does not start
+// a reference, as in "a$", "$1" or "$$", as it is.
+func expandVars(path string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '$' {
+			b.WriteByte(path[i])
+			continue
+		}
+
+		name, width := varName(path[i+1:])
+		if name == "" {
+			b.WriteByte('$')
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("cannot expand $%s in %s: the variable is not set", name, path)
+		}
+		b.WriteString(value)
+		i += width
+	}
+	return b.String(), nil
+}
+
+// varName returns the variable name a $ followed by s refers to and how
+// many bytes of s the reference takes, or "" when s starts with neither
+// NAME nor {NAME}
+func varName(s string) (string, int) {
+	braced := strings.HasPrefix(s, "{")
+	if braced {
+		s = s[1:]
+	}
+
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (n == 0 || c < '0' || c > '9') {
+			break
+		}
+		n++
+	}
+
+	switch {
+	case n == 0:
+		return "", 0
+	case !braced:
+		return s[:n], n
+	case n < len(s) && s[n] == '}':
+		return s[:n], n + 2
+	}
+	return "", 0
+}
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sshhades/sshhades/pkg/format"
+)
+
+func TestReadFileLimited(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data")
+	if err := os.WriteFile(path, []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ReadFileLimited(path, 10, "test file")
+	if err != nil {
+		t.Fatalf("ReadFileLimited() at the limit error = %v", err)
+	}
+	if string(data) != "0123456789" {
+		t.Errorf("ReadFileLimited() = %q", data)
+	}
+
+	_, err = ReadFileLimited(path, 9, "test file")
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("ReadFileLimited() over the limit error = %v, want ErrFileTooLarge", err)
+	}
+	if !strings.Contains(err.Error(), "valid test file") {
+		t.Errorf("error %q does not name the file kind", err)
+	}
+
+	if _, err := ReadFileLimited(filepath.Join(dir, "missing"), 10, "test file"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFileLimited() missing file error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	data, err := ReadLimited(strings.NewReader("0123456789"), "stdin", 10, "key file")
+	if err != nil || string(data) != "0123456789" {
+		t.Fatalf("ReadLimited() at the limit = %q, %v", data, err)
+	}
+
+	_, err = ReadLimited(strings.NewReader("0123456789"), "stdin", 9, "key file")
+	if !errors.Is(err, ErrFileTooLarge) || !strings.Contains(err.Error(), "stdin") {
+		t.Errorf("ReadLimited() over the limit error = %v, want ErrFileTooLarge naming stdin", err)
+	}
+}
+
+func TestLoadEncryptedFileTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.enc")
+	if err := os.WriteFile(path, make([]byte, 64), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	old := MaxEncryptedFileSize
+	MaxEncryptedFileSize = 32
+	defer func() { MaxEncryptedFileSize = old }()
+
+	if _, err := LoadEncryptedFile(path); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("LoadEncryptedFile() error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestSaveEncryptedFileCreatesPrivateDirs(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "backups", "host", "id_ed25519.enc")
+
+	encFile := &format.EncryptedFile{}
+	if err := SaveEncryptedFile(path, encFile); err != nil {
+		t.Fatalf("SaveEncryptedFile() error = %v", err)
+	}
+
+	for _, dir := range []string{filepath.Join(root, "backups"), filepath.Dir(path)} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0700 {
+			t.Errorf("%s created with mode %o, want 0700", dir, perm)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(path, []byte("old key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("new key"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new key" {
+		t.Fatalf("file holds %q, %v, want the new key", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode %o, want 0600", info.Mode().Perm())
+	}
+
+	// Nothing is left next to the file
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the written file", len(entries))
+	}
+
+	// A symlink is followed, not replaced
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := WriteFileAtomic(link, []byte("through link"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic() through a symlink error = %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("WriteFileAtomic() replaced the symlink")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "through link" {
+		t.Errorf("symlink target holds %q, want the new data", data)
+	}
+}
+
+func TestWriteFileAtomicCleansUp(t *testing.T) {
+	dir := t.TempDir()
+
+	// Renaming a file over a directory fails after the data is written
+	target := filepath.Join(dir, "taken")
+	if err := os.Mkdir(target, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(target, []byte("new key"), 0600); err == nil {
+		t.Fatal("WriteFileAtomic() over a directory succeeded")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want the temporary file removed", len(entries))
+	}
+}
+
+func TestShredFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "id_ed25519")
+	original := strings.Repeat("secret key material\n", 64)
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second link to the same inode shows what was written over the data
+	link := filepath.Join(dir, "link")
+	if err := os.Link(path, link); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	if err := ShredFile(path); err != nil {
+		t.Fatalf("ShredFile() error = %v", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("ShredFile() left the file in place: %v", err)
+	}
+
+	data, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len(original) {
+		t.Errorf("Overwritten file has %d bytes, want %d", len(data), len(original))
+	}
+	if strings.Contains(string(data), "secret key material") {
+		t.Error("ShredFile() did not overwrite the file contents")
+	}
+}
+
+func TestShredFileRefusesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(target, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	symlink := filepath.Join(dir, "symlink")
+	if err := os.Symlink(target, symlink); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := ShredFile(symlink); err == nil {
+		t.Error("ShredFile() should refuse a symlink")
+	}
+	if _, err := os.Lstat(symlink); err != nil {
+		t.Errorf("Refused symlink was removed: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "secret" {
+		t.Errorf("Symlink target changed: %q, %v", data, err)
+	}
+}
+
+func TestShredFileReadOnly(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(target, []byte("secret"), 0400); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ShredFile(target); err != nil {
+		t.Fatalf("ShredFile() of a 0400 key error = %v", err)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("Read-only file was not removed: %v", err)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSHHADES_TEST_DIR", "/srv/keys")
+	t.Setenv("SSHHADES_TEST_EMPTY", "")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~", home},
+		{"~/", home},
+		{"~/.ssh/id_ed25519", filepath.Join(home, ".ssh", "id_ed25519")},
+		{"$SSHHADES_TEST_DIR/id_rsa", "/srv/keys/id_rsa"},
+		{"${SSHHADES_TEST_DIR}/id_rsa", "/srv/keys/id_rsa"},
+		{"backups/id~1.enc", "backups/id~1.enc"},
+		{"-", "-"},
+		{"$SSHHADES_TEST_DIR$", "/srv/keys$"},
+		{"keys/$1/$$.enc", "keys/$1/$$.enc"},
+		{"keys/${SSHHADES_TEST_DIR", "keys/${SSHHADES_TEST_DIR"},
+		{"keys/${}", "keys/${}"},
+		{"${SSHHADES_TEST_EMPTY}id_rsa", "id_rsa"},
+	}
+
+	for _, tt := range tests {
+		got, err := ExpandPath(tt.path)
+		if err != nil {
+			t.Errorf("ExpandPath(%q) error = %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpandPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandPathUnsetVariable(t *testing.T) {
+	for _, path := range []string{"$SSHHADES_TEST_UNSET/id_rsa", "${SSHHADES_TEST_UNSET}/id_rsa"} {
+		_, err := ExpandPath(path)
+		if err == nil || !strings.Contains(err.Error(), "$SSHHADES_TEST_UNSET") {
+			t.Errorf("ExpandPath(%q) error = %v, want it to name the unset variable", path, err)
+		}
+	}
+}
+
+func TestExpandPathUser(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+
+	got, err := ExpandPath("~" + current.Username + "/.ssh")
+	if err != nil {
+		t.Fatalf("ExpandPath() error = %v", err)
+	}
+	if want := filepath.Join(current.HomeDir, ".ssh"); got != want {
+		t.Errorf("ExpandPath() = %q, want %q", got, want)
+	}
+
+	if _, err := ExpandPath("~sshhades-no-such-user/.ssh"); err == nil {
+		t.Error("ExpandPath() should fail for an unknown user")
+	}
+}
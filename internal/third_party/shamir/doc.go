@@ -0,0 +1,6 @@
+// Package shamir is Shamir's secret sharing over GF(2^8) as implemented by
+// HashiCorp Vault, copied unmodified from github.com/hashicorp/vault v1.21.4
+// (shamir/shamir.go and shamir/shamir_test.go) under the MPL-2.0 license in
+// this directory. Update it by copying those files from a newer release;
+// sshhades-specific code belongs in internal/crypto.
+package shamir
@@ -0,0 +1,147 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// bundleMagic starts every encoded bundle
+var bundleMagic = []byte("SSHHBNDL\x01")
+
+// Member is one file inside a bundle
+type Member struct {
+	// Name is the file name the member is restored as
+	Name string
+
+	// Mode holds the permission bits the file had at backup time
+	Mode uint32
+
+	// Type is the detected SSH key type, e.g. "ed25519"
+	Type string
+
+	// Data is the file content
+	Data []byte
+}
+
+// ValidateMemberName checks that name is a plain file name, which restore
+// can safely join to the output directory: not empty, not "." or "..", not
+// absolute and without a path separator of any platform
+func ValidateMemberName(name string) error {
+	if !filepath.IsLocal(name) || strings.ContainsAny(name, `/\`) || name == "." {
+		return fmt.Errorf("invalid bundle member name: %q", name)
+	}
+	return nil
+}
+
+// EncodeBundle serializes members into a single plaintext for encryption.
+// The result is a flat length-prefixed encoding, so the caller can clear it
+// like any other key material.
+func EncodeBundle(members []Member) ([]byte, error) {
+	if len(members) == 0 {
+		return nil, errors.New("bundle has no members")
+	}
+
+	size := len(bundleMagic) + 4
+	for _, m := range members {
+		if err := ValidateMemberName(m.Name); err != nil {
+			return nil, err
+		}
+		if len(m.Name) > 0xffff || len(m.Type) > 0xffff {
+			return nil, fmt.Errorf("invalid bundle member name or type: %q", m.Name)
+		}
+		size += 2 + len(m.Name) + 4 + 2 + len(m.Type) + 4 + len(m.Data)
+	}
+
+	buf := make([]byte, 0, size)
+	buf = append(buf, bundleMagic...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(members)))
+	for _, m := range members {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(m.Name)))
+		buf = append(buf, m.Name...)
+		buf = binary.BigEndian.AppendUint32(buf, m.Mode)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(m.Type)))
+		buf = append(buf, m.Type...)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(m.Data)))
+		buf = append(buf, m.Data...)
+	}
+
+	return buf, nil
+}
+
+// DecodeBundle parses a plaintext produced by EncodeBundle. Member data
+// slices alias data, so clearing data also clears every member. Member
+// names become file names on restore, so any name ValidateMemberName
+// rejects makes the whole bundle invalid.
+func DecodeBundle(data []byte) ([]Member, error) {
+	rest, ok := bytes.CutPrefix(data, bundleMagic)
+	if !ok {
+		return nil, errors.New("not a bundle")
+	}
+
+	r := bundleReader{rest: rest}
+	count := r.uint32()
+
+	var members []Member
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		var m Member
+		m.Name = string(r.bytes(int(r.uint16())))
+		m.Mode = r.uint32()
+		m.Type = string(r.bytes(int(r.uint16())))
+		m.Data = r.bytes(int(r.uint32()))
+		members = append(members, m)
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	for _, m := range members {
+		if err := ValidateMemberName(m.Name); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.rest) != 0 {
+		return nil, errors.New("trailing data after bundle members")
+	}
+	if len(members) == 0 {
+		return nil, errors.New("bundle has no members")
+	}
+
+	return members, nil
+}
+
+// bundleReader consumes a bundle encoding, remembering the first error
+type bundleReader struct {
+	rest []byte
+	err  error
+}
+
+func (r *bundleReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.rest) {
+		r.err = errors.New("truncated bundle")
+		return nil
+	}
+	b := r.rest[:n:n]
+	r.rest = r.rest[n:]
+	return b
+}
+
+func (r *bundleReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *bundleReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertificateInfo describes an OpenSSH certificate that was backed up,
+// either as the key file itself or bundled next to the private key
+type CertificateInfo struct {
+	// Type is "user" or "host"
+	Type string `json:"type"`
+
+	// KeyID is the identity the certificate was signed with (-I)
+	KeyID string `json:"key_id,omitempty"`
+
+	// Serial is the certificate serial number (-z)
+	Serial uint64 `json:"serial,omitempty"`
+
+	// Principals are the user or host names the certificate is valid
+	// for; empty means any
+	Principals []string `json:"principals,omitempty"`
+
+	// ValidAfter and ValidBefore bound the validity period; nil means
+	// no bound on that side
+	ValidAfter  *time.Time `json:"valid_after,omitempty"`
+	ValidBefore *time.Time `json:"valid_before,omitempty"`
+
+	// CA is the SHA256 fingerprint of the certificate authority key that
+	// signed the certificate
+	CA string `json:"ca,omitempty"`
+}
+
+// Expired reports whether the certificate is no longer valid at now
+func (c *CertificateInfo) Expired(now time.Time) bool {
+	return c.ValidBefore != nil && !now.Before(*c.ValidBefore)
+}
+
+// Validity describes the validity period, e.g. "2024-01-01 to 2025-01-01"
+func (c *CertificateInfo) Validity() string {
+	const layout = "2006-01-02 15:04:05 UTC"
+	switch {
+	case c.ValidAfter == nil && c.ValidBefore == nil:
+		return "forever"
+	case c.ValidBefore == nil:
+		return "from " + c.ValidAfter.UTC().Format(layout) + ", forever"
+	case c.ValidAfter == nil:
+		return "until " + c.ValidBefore.UTC().Format(layout)
+	}
+	return c.ValidAfter.UTC().Format(layout) + " to " + c.ValidBefore.UTC().Format(layout)
+}
+
+// PrincipalList returns the principals for display, or "any" when the
+// certificate is not restricted to any
+func (c *CertificateInfo) PrincipalList() string {
+	if len(c.Principals) == 0 {
+		return "any"
+	}
+	return strings.Join(c.Principals, ", ")
+}
+
+// String summarizes the certificate on one line
+func (c *CertificateInfo) String() string {
+	return fmt.Sprintf("%s certificate %q for %s, valid %s", c.Type, c.KeyID, c.PrincipalList(), c.Validity())
+}
@@ -0,0 +1,85 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxCommentLength is the longest Header.Comment, in bytes, a backup may
+// be created with
+const MaxCommentLength = 1024
+
+// ValidateComment rejects comments that are too long or contain control
+// or invisible formatting characters, which would bloat the header and
+// garble terminals when the comment is shown
+func ValidateComment(comment string) error {
+	if len(comment) > MaxCommentLength {
+		return fmt.Errorf("comment is %d bytes long; the limit is %d", len(comment), MaxCommentLength)
+	}
+	if !utf8.ValidString(comment) {
+		return fmt.Errorf("comment is not valid UTF-8")
+	}
+	for _, r := range comment {
+		if unsafeCommentRune(r) {
+			return fmt.Errorf("comment contains the control character %U", r)
+		}
+	}
+	return nil
+}
+
+// SanitizeComment makes a comment read from a header safe to print: control
+// and formatting characters and invalid UTF-8 are escaped, so a crafted
+// header cannot move the cursor or reorder text, and comments longer than
+// MaxCommentLength are cut with an ellipsis
+func SanitizeComment(comment string) string {
+	var b strings.Builder
+	for i := 0; i < len(comment); {
+		if b.Len() >= MaxCommentLength {
+			b.WriteString("…")
+			break
+		}
+
+		r, size := utf8.DecodeRuneInString(comment[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&b, `\x%02x`, comment[i])
+		case unsafeCommentRune(r) && r < utf8.RuneSelf:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case unsafeCommentRune(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteString(comment[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// MaxHintLength is the longest Header.Hint, in bytes, a backup may be
+// created with
+const MaxHintLength = 128
+
+// ValidateHint rejects passphrase hints that are too long or contain
+// characters ValidateComment rejects
+func ValidateHint(hint string) error {
+	if len(hint) > MaxHintLength {
+		return fmt.Errorf("hint is %d bytes long; the limit is %d", len(hint), MaxHintLength)
+	}
+	if !utf8.ValidString(hint) {
+		return fmt.Errorf("hint is not valid UTF-8")
+	}
+	for _, r := range hint {
+		if unsafeCommentRune(r) {
+			return fmt.Errorf("hint contains the control character %U", r)
+		}
+	}
+	return nil
+}
+
+// unsafeCommentRune reports control characters, including C1 controls, and
+// invisible format characters such as bidirectional overrides
+func unsafeCommentRune(r rune) bool {
+	return unicode.IsControl(r) || unicode.Is(unicode.Cf, r)
+}
@@ -0,0 +1,91 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidateComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"plain", "laptop key", false},
+		{"multi-byte", "clé de Zoë 🔑 ключ", false},
+		{"at the limit", strings.Repeat("é", MaxCommentLength/2), false},
+		{"over the limit", strings.Repeat("é", MaxCommentLength/2) + "x", true},
+		{"newline", "first\nsecond", true},
+		{"escape sequence", "key \x1b[2J", true},
+		{"tab", "a\tb", true},
+		{"C1 control", "a\u0085b", true},
+		{"bidi override", "evil\u202egnp.exe", true},
+		{"invalid UTF-8", "bad \xff byte", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateComment(tt.comment); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateComment(%q) error = %v, wantErr %v", tt.comment, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSanitizeComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+	}{
+		{"plain", "laptop key", "laptop key"},
+		{"multi-byte", "clé 🔑 ключ", "clé 🔑 ключ"},
+		{"escape sequence", "key \x1b[2J\r\n", `key \x1b[2J\x0d\x0a`},
+		{"bidi override", "evil\u202egnp", `evil\u202egnp`},
+		{"C1 control", "a\u0085b", `a\u0085b`},
+		{"invalid UTF-8", "bad \xff\xfe", `bad \xff\xfe`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeComment(tt.comment); got != tt.want {
+				t.Errorf("SanitizeComment(%q) = %q, want %q", tt.comment, got, tt.want)
+			}
+		})
+	}
+
+	// Oversized comments are cut on a rune boundary
+	long := SanitizeComment(strings.Repeat("ключ", MaxCommentLength))
+	if !utf8.ValidString(long) || !strings.HasSuffix(long, "…") {
+		t.Errorf("SanitizeComment() of a long comment = %q...", long[:20])
+	}
+	if len(long) > MaxCommentLength+len("…")+utf8.UTFMax {
+		t.Errorf("SanitizeComment() kept %d bytes, limit %d", len(long), MaxCommentLength)
+	}
+}
+
+func TestValidateHint(t *testing.T) {
+	tests := []struct {
+		name    string
+		hint    string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"plain", "usual one + year of the laptop", false},
+		{"at the limit", strings.Repeat("x", MaxHintLength), false},
+		{"over the limit", strings.Repeat("x", MaxHintLength+1), true},
+		{"newline", "first\nsecond", true},
+		{"bidi override", "evil\u202egnp", true},
+		{"invalid UTF-8", "bad \xff byte", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateHint(tt.hint); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHint(%q) error = %v, wantErr %v", tt.hint, err, tt.wantErr)
+			}
+		})
+	}
+}
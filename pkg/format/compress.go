@@ -0,0 +1,88 @@
+package format
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CompressionGzip is the only compression method, recorded in
+// Header.Compression. An empty method means the plaintext is stored as is.
+const CompressionGzip = "gzip"
+
+// CompressionVersion is written by files whose plaintext is compressed.
+// Older readers would restore the compressed bytes as the key, so they
+// must reject such files as too new.
+const CompressionVersion = "1.2"
+
+// MaxDecompressedSize bounds the plaintext Decompress produces, so a
+// crafted file cannot expand without limit
+const MaxDecompressedSize = 16 << 20
+
+// compressionHeaderCost approximates, in plaintext bytes, what the
+// "compression" header field adds to a file once the ciphertext is base64
+// encoded. CompressAuto only keeps savings larger than this.
+const compressionHeaderCost = 24
+
+// Compress gzips data at the best compression level. The buffer is sized
+// up front so no partial copies of the plaintext are left behind by growth.
+func Compress(data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+64))
+	w, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CompressAuto compresses data only when that makes the file smaller. It
+// returns the bytes to encrypt and the method to record, which is empty
+// when data is returned unchanged.
+func CompressAuto(data []byte) ([]byte, string, error) {
+	compressed, err := Compress(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(compressed)+compressionHeaderCost >= len(data) {
+		clear(compressed)
+		return data, "", nil
+	}
+	return compressed, CompressionGzip, nil
+}
+
+// Decompress reverses the compression named by method. An empty method
+// returns data unchanged.
+func Decompress(data []byte, method string) ([]byte, error) {
+	switch method {
+	case "":
+		return data, nil
+	case CompressionGzip:
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", method)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed data: %w", err)
+	}
+	defer r.Close()
+
+	out, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+	if err != nil {
+		clear(out)
+		return nil, fmt.Errorf("invalid compressed data: %w", err)
+	}
+	if len(out) > MaxDecompressedSize {
+		clear(out)
+		return nil, errors.New("compressed data expands beyond the size limit")
+	}
+	return out, nil
+}
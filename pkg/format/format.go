@@ -1,19 +1,48 @@
 package format
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Version represents the encrypted file format version
 const Version = "1.0"
 
+// BundleVersion is written by files whose plaintext is a bundle of several
+// members. Older readers would mistake a bundle for a single key, so they
+// must reject it as too new.
+const BundleVersion = "1.1"
+
+// LatestVersion is the newest format version this build can read
+const LatestVersion = ShareVersion
+
+// ErrNewerVersion is returned for files written in a format version newer
+// than this build understands
+var ErrNewerVersion = errors.New("file written by a newer sshhades; please upgrade")
+
+// MaxClockSkew is how far in the future a header timestamp may lie before
+// the clock of the machine that wrote it is considered wrong
+const MaxClockSkew = time.Hour
+
 // Supported algorithms
 const (
 	AlgorithmAESGCM     = "AES-256-GCM"
 	AlgorithmChaCha20   = "ChaCha20-Poly1305"
 )
 
+// Key derivation functions, recorded in Header.KDF. Both are Argon2
+// variants taking the same parameters.
+const (
+	KDFArgon2id = "Argon2id"
+	KDFArgon2i  = "Argon2i"
+)
+
 // EncryptedFile represents the structure of an encrypted SSH key file
 type EncryptedFile struct {
 	// Header contains metadata about the encrypted file
@@ -30,6 +59,38 @@ type EncryptedFile struct {
 	
 	// Tag is the AES-GCM authentication tag
 	Tag []byte `json:"tag"`
+
+	// Metadata holds the descriptive header fields when they were encrypted
+	// with --encrypt-metadata; the header then carries none of them
+	Metadata *SealedMetadata `json:"metadata,omitempty"`
+
+	// Share is this file's share of the data key of a split backup,
+	// described by Header.Share
+	Share []byte `json:"share,omitempty"`
+}
+
+// SealedMetadata is a Metadata value encrypted with the file's key
+type SealedMetadata struct {
+	// Nonce is the AEAD nonce, distinct from the key data nonce
+	Nonce []byte `json:"nonce"`
+
+	// Ciphertext is the encrypted JSON metadata with the tag appended
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Metadata is the optional descriptive part of a header: everything that
+// is not needed to decrypt the file
+type Metadata struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Comment      string    `json:"comment,omitempty"`
+	KeyType      string    `json:"key_type,omitempty"`
+	KeyRole      string    `json:"key_role,omitempty"`
+	Fingerprint  string    `json:"fingerprint,omitempty"`
+	OriginalName string    `json:"original_name,omitempty"`
+	Hostname     string    `json:"hostname,omitempty"`
+	Username     string    `json:"username,omitempty"`
+
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
 }
 
 // Header contains metadata about the encryption
@@ -40,7 +101,7 @@ type Header struct {
 	// Algorithm used for encryption (e.g., "AES-256-GCM")
 	Algorithm string `json:"algorithm"`
 	
-	// KDF is the key derivation function used (e.g., "Argon2id")
+	// KDF is the key derivation function used: KDFArgon2id or KDFArgon2i
 	KDF string `json:"kdf"`
 	
 	// Iterations for the KDF
@@ -57,6 +118,59 @@ type Header struct {
 	
 	// Comment is a user-provided description
 	Comment string `json:"comment,omitempty"`
+
+	// Hint is a user-provided reminder of the passphrase. Unlike the
+	// comment it is never encrypted or stripped, as it is needed before
+	// the passphrase is: anyone with the file can read it.
+	Hint string `json:"hint,omitempty"`
+
+	// KeyType is the detected SSH key type (e.g., "ed25519")
+	KeyType string `json:"key_type,omitempty"`
+
+	// KeyRole is what the key is used for: "user", "host" or "ca"
+	KeyRole string `json:"key_role,omitempty"`
+
+	// Fingerprint is the SHA256 fingerprint of the encrypted key
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// OriginalName is the filename of the key at backup time
+	OriginalName string `json:"original_name,omitempty"`
+
+	// Hostname is the machine the key was backed up from (opt-in)
+	Hostname string `json:"hostname,omitempty"`
+
+	// Username is the OS account the key was backed up from (opt-in)
+	Username string `json:"username,omitempty"`
+
+	// Certificate describes the OpenSSH certificate backed up as the key
+	// or bundled with it, if any
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+
+	// Members is the number of files in a bundle (see EncodeBundle), or 0
+	// when the plaintext is a single key
+	Members int `json:"members,omitempty"`
+
+	// Compression is the method the plaintext was compressed with before
+	// encryption (CompressionGzip), or empty when it was stored as is
+	Compression string `json:"compression,omitempty"`
+
+	// Strength is the named strength level (e.g. "moderate") the KDF
+	// parameters were chosen with, if any. It is only a hint: the raw
+	// parameters above are what decryption uses.
+	Strength string `json:"strength,omitempty"`
+
+	// FastMode marks files encrypted with the weak development KDF
+	// parameters, so verify and info can flag them
+	FastMode bool `json:"fast_mode,omitempty"`
+
+	// ExpiresAt is when the backup is due for rotation and restore should
+	// warn or refuse. It is advisory: the header is not authenticated, so
+	// anyone who can write the file can change or remove it.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Share is set on the files of a backup split with --split, which are
+	// restored by combining shares instead of with a passphrase
+	Share *ShareParams `json:"share,omitempty"`
 }
 
 // DefaultHeader returns a header with secure default values
@@ -64,7 +178,7 @@ func DefaultHeader() Header {
 	return Header{
 		Version:    Version,
 		Algorithm:  AlgorithmAESGCM,
-		KDF:        "Argon2id",
+		KDF:        KDFArgon2id,
 		Iterations: 100000,  // 100k iterations
 		Memory:     64,      // 64 MB
 		Threads:    4,       // 4 threads
@@ -77,12 +191,110 @@ func FastHeader() Header {
 	return Header{
 		Version:    Version,
 		Algorithm:  AlgorithmAESGCM,
-		KDF:        "Argon2id",
+		KDF:        KDFArgon2id,
 		Iterations: 1000,    // Much faster
 		Memory:     8,       // Lower memory
 		Threads:    1,       // Single thread
 		Timestamp:  time.Now().UTC(),
+		FastMode:   true,
+	}
+}
+
+// Metadata returns the optional descriptive fields of the header
+func (h *Header) Metadata() Metadata {
+	return Metadata{
+		Timestamp:    h.Timestamp,
+		Comment:      h.Comment,
+		KeyType:      h.KeyType,
+		KeyRole:      h.KeyRole,
+		Fingerprint:  h.Fingerprint,
+		OriginalName: h.OriginalName,
+		Hostname:     h.Hostname,
+		Username:     h.Username,
+		Certificate:  h.Certificate,
+	}
+}
+
+// ApplyMetadata restores descriptive fields, e.g. after decrypting them
+func (h *Header) ApplyMetadata(m Metadata) {
+	h.Timestamp = m.Timestamp
+	h.Comment = m.Comment
+	h.KeyType = m.KeyType
+	h.KeyRole = m.KeyRole
+	h.Fingerprint = m.Fingerprint
+	h.OriginalName = m.OriginalName
+	h.Hostname = m.Hostname
+	h.Username = m.Username
+	h.Certificate = m.Certificate
+}
+
+// StripMetadata clears every optional descriptive field, keeping only what
+// is needed to decrypt the file. The timestamp is reset to the zero time,
+// which still parses in readers that expect the field to be present.
+func (h *Header) StripMetadata() {
+	h.Timestamp = time.Time{}
+	h.Comment = ""
+	h.KeyType = ""
+	h.KeyRole = ""
+	h.Fingerprint = ""
+	h.OriginalName = ""
+	h.Hostname = ""
+	h.Username = ""
+	h.Certificate = nil
+}
+
+// TimestampInFuture reports whether the header timestamp is implausibly
+// far ahead of now, which means the backup was made on a machine with a
+// wrong clock and its timestamp should not be trusted
+func (h *Header) TimestampInFuture(now time.Time) bool {
+	return !h.Timestamp.IsZero() && h.Timestamp.Sub(now) > MaxClockSkew
+}
+
+// Expired reports whether the backup has an expiry time that has passed
+func (h *Header) Expired(now time.Time) bool {
+	return h.ExpiresAt != nil && !now.Before(*h.ExpiresAt)
+}
+
+// CheckVersion reports whether a file format version can be read by this
+// build. Versions newer than LatestVersion wrap ErrNewerVersion so callers
+// can tell "upgrade sshhades" apart from a damaged or foreign file.
+func CheckVersion(version string) error {
+	fileMajor, fileMinor, err := parseVersion(version)
+	if err != nil {
+		return fmt.Errorf("unsupported file version: %q", version)
+	}
+
+	// LatestVersion is a constant, so it always parses
+	major, minor, _ := parseVersion(LatestVersion)
+
+	switch {
+	case fileMajor > major || (fileMajor == major && fileMinor > minor):
+		return fmt.Errorf("%w (file format %s, this build reads up to %s)", ErrNewerVersion, version, LatestVersion)
+	case fileMajor != major:
+		return fmt.Errorf("unsupported file version: %s", version)
 	}
+
+	return nil
+}
+
+// parseVersion splits a "major.minor" version string
+func parseVersion(version string) (int, int, error) {
+	majorStr, minorStr, ok := strings.Cut(version, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed version: %q", version)
+	}
+
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("malformed version: %q", version)
+	}
+
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil || minor < 0 {
+		return 0, 0, fmt.Errorf("malformed version: %q", version)
+	}
+
+	return major, minor, nil
 }
 
 // ToJSON serializes the encrypted file to JSON format
@@ -95,4 +307,57 @@ func FromJSON(data []byte) (*EncryptedFile, error) {
 	var ef EncryptedFile
 	err := json.Unmarshal(data, &ef)
 	return &ef, err
+}
+
+// Armor markers around a base64-wrapped encrypted file
+const (
+	ArmorHeader = "-----BEGIN SSHHADES BACKUP-----"
+	ArmorFooter = "-----END SSHHADES BACKUP-----"
+)
+
+// Armor wraps serialized file data in a single base64 line between
+// ArmorHeader and ArmorFooter, for pasting into text fields and secrets
+func Armor(data []byte) []byte {
+	return []byte(ArmorHeader + "\n" + base64.StdEncoding.EncodeToString(data) + "\n" + ArmorFooter + "\n")
+}
+
+// IsArmored reports whether data looks like the output of Armor
+func IsArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(ArmorHeader))
+}
+
+// Dearmor reverses Armor. Whitespace inside the base64 body is ignored, so
+// blobs that were re-wrapped when pasted still decode.
+func Dearmor(data []byte) ([]byte, error) {
+	body, ok := bytes.CutPrefix(bytes.TrimSpace(data), []byte(ArmorHeader))
+	if !ok {
+		return nil, fmt.Errorf("missing %s line", ArmorHeader)
+	}
+
+	body, ok = bytes.CutSuffix(body, []byte(ArmorFooter))
+	if !ok {
+		return nil, fmt.Errorf("missing %s line", ArmorFooter)
+	}
+
+	encoded := strings.Join(strings.Fields(string(body)), "")
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 in armored file: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// Parse deserializes an encrypted file, accepting both plain JSON and the
+// base64 armor produced by Armor
+func Parse(data []byte) (*EncryptedFile, error) {
+	if IsArmored(data) {
+		decoded, err := Dearmor(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	return FromJSON(data)
 }
\ No newline at end of file
@@ -0,0 +1,160 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampInFuture(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		want      bool
+	}{
+		{"not recorded", time.Time{}, false},
+		{"in the past", now.Add(-48 * time.Hour), false},
+		{"within skew", now.Add(MaxClockSkew), false},
+		{"beyond skew", now.Add(MaxClockSkew + time.Minute), true},
+		{"next year", now.AddDate(1, 0, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Header{Timestamp: tt.timestamp}
+			if got := h.TimestampInFuture(now); got != tt.want {
+				t.Errorf("TimestampInFuture() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		want      bool
+	}{
+		{"no expiry", nil, false},
+		{"expired", &past, true},
+		{"at expiry", &now, true},
+		{"not yet", &future, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Header{ExpiresAt: tt.expiresAt}
+			if got := h.Expired(now); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArmorRoundTrip(t *testing.T) {
+	data := []byte("{\"header\":{}}\x00\xff binary-safe")
+
+	armored := Armor(data)
+	if !IsArmored(armored) {
+		t.Fatal("IsArmored() = false for Armor output")
+	}
+	if IsArmored(data) {
+		t.Error("IsArmored() = true for unarmored data")
+	}
+
+	decoded, err := Dearmor(armored)
+	if err != nil {
+		t.Fatalf("Dearmor() error = %v", err)
+	}
+	if string(decoded) != string(data) {
+		t.Errorf("Dearmor() = %q, want %q", decoded, data)
+	}
+}
+
+func TestDearmorWhitespace(t *testing.T) {
+	data := []byte(strings.Repeat("sshhades armored backup ", 20))
+	armored := string(Armor(data))
+
+	// Re-wrap the body at 64 columns with CRLF line endings and indentation,
+	// as a mail client or a paste into a terminal might
+	lines := strings.Split(strings.TrimSpace(armored), "\n")
+	body := lines[1]
+	var wrapped []string
+	for len(body) > 64 {
+		wrapped = append(wrapped, "  "+body[:64])
+		body = body[64:]
+	}
+	wrapped = append(wrapped, "  "+body)
+	rewrapped := "\n\n" + lines[0] + "\r\n" + strings.Join(wrapped, "\r\n") + "\r\n" + lines[2] + "\r\n\n"
+
+	decoded, err := Dearmor([]byte(rewrapped))
+	if err != nil {
+		t.Fatalf("Dearmor() error = %v", err)
+	}
+	if string(decoded) != string(data) {
+		t.Errorf("Dearmor() = %q, want %q", decoded, data)
+	}
+}
+
+func TestDearmorCorrupted(t *testing.T) {
+	armored := string(Armor([]byte("backup data")))
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing header", strings.Replace(armored, ArmorHeader, "", 1)},
+		{"missing footer", strings.Replace(armored, ArmorFooter, "", 1)},
+		{"invalid base64", strings.Replace(armored, "YmFja3Vw", "Ym!ja3Vw", 1)},
+		{"truncated body", strings.Replace(armored, "IGRhdGE=", "IGRhd", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.data == armored {
+				t.Fatal("test case did not change the armor")
+			}
+			if _, err := Dearmor([]byte(tt.data)); err == nil {
+				t.Error("Dearmor() should fail")
+			}
+		})
+	}
+}
+
+func TestParseArmored(t *testing.T) {
+	encFile := &EncryptedFile{
+		Header:     DefaultHeader(),
+		Salt:       make([]byte, 32),
+		Nonce:      make([]byte, 12),
+		Ciphertext: []byte("ciphertext"),
+		Tag:        make([]byte, 16),
+	}
+	data, err := encFile.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, input := range map[string][]byte{"plain": data, "armored": Armor(data)} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := Parse(input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if string(parsed.Ciphertext) != "ciphertext" || parsed.Header.Algorithm != encFile.Header.Algorithm {
+				t.Errorf("Parse() = %+v, want the original file", parsed)
+			}
+		})
+	}
+
+	// Armor that does not decode is an error, not a fallback to JSON
+	corrupted := strings.Replace(string(Armor(data)), ArmorFooter, "", 1)
+	if _, err := Parse([]byte(corrupted)); err == nil {
+		t.Error("Parse() should fail for corrupted armor")
+	}
+}
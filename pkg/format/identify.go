@@ -0,0 +1,211 @@
+package format
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of file Identify recognizes
+const (
+	KindSSHHades      = "sshhades"
+	KindAge           = "age"
+	KindSSHPrivateKey = "ssh-private-key"
+	KindUnknown       = "unknown"
+)
+
+// Encodings Identify reports
+const (
+	EncodingJSON   = "json"
+	EncodingArmor  = "armor"
+	EncodingBinary = "binary"
+	EncodingPEM    = "pem"
+	EncodingText   = "text"
+)
+
+// Markers of files other tools write
+const (
+	ageHeader      = "age-encryption.org/v1\n"
+	ageArmorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorFooter = "-----END AGE ENCRYPTED FILE-----"
+)
+
+// Identification is what Identify could tell about a file without
+// decrypting it. Fields it could not determine are empty.
+type Identification struct {
+	Kind       string   `json:"kind"`
+	Encoding   string   `json:"encoding,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Algorithm  string   `json:"algorithm,omitempty"`
+	KDF        string   `json:"kdf,omitempty"`
+	Recipients []string `json:"recipients,omitempty"`
+	Truncated  bool     `json:"truncated,omitempty"`
+	Detail     string   `json:"detail,omitempty"`
+}
+
+// Identify sniffs data and reports which format it is in. Unlike Parse it
+// never fails: truncated or damaged files are described as far as they can
+// be, so it is safe to use for triage of unknown files.
+func Identify(data []byte) Identification {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case len(trimmed) == 0:
+		return Identification{Kind: KindUnknown, Detail: "empty file"}
+	case IsArmored(trimmed):
+		return identifyArmored(trimmed)
+	case bytes.HasPrefix(data, []byte(ageHeader)):
+		return identifyAge(data, EncodingBinary)
+	case bytes.HasPrefix(trimmed, []byte(ageArmorHeader)):
+		return identifyAgeArmored(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN ")) && bytes.Contains(firstLine(trimmed), []byte("PRIVATE KEY-----")):
+		return identifyPrivateKey(trimmed)
+	case trimmed[0] == '{':
+		return identifyJSON(trimmed, EncodingJSON)
+	}
+
+	id := Identification{Kind: KindUnknown, Encoding: EncodingBinary, Detail: "not a recognized format"}
+	if utf8.Valid(trimmed) {
+		id.Encoding = EncodingText
+	}
+	return id
+}
+
+// identifyArmored decodes as much of an sshhades armor block as is there
+func identifyArmored(data []byte) Identification {
+	body, _ := bytes.CutPrefix(data, []byte(ArmorHeader))
+	body, complete := bytes.CutSuffix(body, []byte(ArmorFooter))
+
+	encoded := strings.Join(strings.Fields(string(body)), "")
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Keep whole base64 quanta of a cut-off body
+		encoded = strings.TrimRight(encoded, "=")
+		decoded, _ = base64.RawStdEncoding.DecodeString(encoded[:len(encoded)/4*4])
+		complete = false
+	}
+
+	id := identifyJSON(bytes.TrimSpace(decoded), EncodingArmor)
+	if id.Kind == KindUnknown {
+		// The armor lines alone identify the file
+		id = Identification{Kind: KindSSHHades, Encoding: EncodingArmor, Detail: "armored body could not be read"}
+	}
+	if !complete {
+		id.Truncated = true
+	}
+	return id
+}
+
+// jsonField matches a string field of a JSON object, for salvaging
+// header fields from files cut off before the JSON is complete
+func jsonField(name string) *regexp.Regexp {
+	return regexp.MustCompile(`"` + name + `"\s*:\s*"([^"]*)"`)
+}
+
+var (
+	versionField   = jsonField("version")
+	algorithmField = jsonField("algorithm")
+	kdfField       = jsonField("kdf")
+)
+
+// identifyJSON recognizes an sshhades envelope, whole or truncated
+func identifyJSON(data []byte, encoding string) Identification {
+	id := Identification{Kind: KindUnknown, Encoding: encoding}
+
+	var ef EncryptedFile
+	err := json.Unmarshal(data, &ef)
+	if err == nil {
+		if ef.Header.Version == "" || ef.Header.Algorithm == "" {
+			id.Detail = "JSON, but not an sshhades backup"
+			return id
+		}
+		id.Kind = KindSSHHades
+		id.Version = ef.Header.Version
+		id.Algorithm = ef.Header.Algorithm
+		id.KDF = ef.Header.KDF
+		if len(ef.Ciphertext) == 0 || len(ef.Tag) == 0 {
+			id.Detail = "header present but ciphertext or tag missing"
+		}
+		return id
+	}
+
+	var syntaxErr *json.SyntaxError
+	ended := errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(data))
+	if !bytes.Contains(data, []byte(`"header"`)) {
+		id.Detail = "JSON, but not an sshhades backup"
+		if ended {
+			id.Truncated = true
+		}
+		return id
+	}
+
+	id.Kind = KindSSHHades
+	id.Version = submatch(versionField, data)
+	id.Algorithm = submatch(algorithmField, data)
+	id.KDF = submatch(kdfField, data)
+	if ended {
+		id.Truncated = true
+	} else {
+		id.Detail = "damaged JSON: " + err.Error()
+	}
+	return id
+}
+
+// identifyAge reads the recipient stanzas of an age header
+func identifyAge(data []byte, encoding string) Identification {
+	id := Identification{Kind: KindAge, Encoding: encoding, Version: "v1"}
+
+	header, _, complete := bytes.Cut(data, []byte("\n---"))
+	for _, line := range strings.Split(string(header), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "->" {
+			id.Recipients = append(id.Recipients, fields[1])
+		}
+	}
+	if !complete {
+		id.Truncated = true
+	}
+	return id
+}
+
+// identifyAgeArmored decodes an ASCII-armored age file far enough to read
+// its header
+func identifyAgeArmored(data []byte) Identification {
+	body, _ := bytes.CutPrefix(data, []byte(ageArmorHeader))
+	body, complete := bytes.CutSuffix(body, []byte(ageArmorFooter))
+
+	encoded := strings.TrimRight(strings.Join(strings.Fields(string(body)), ""), "=")
+	decoded, _ := base64.RawStdEncoding.DecodeString(encoded[:len(encoded)/4*4])
+
+	id := identifyAge(decoded, EncodingArmor)
+	if !complete {
+		id.Truncated = true
+	}
+	return id
+}
+
+// identifyPrivateKey recognizes a key file that is not a backup at all
+func identifyPrivateKey(data []byte) Identification {
+	id := Identification{Kind: KindSSHPrivateKey, Encoding: EncodingPEM, Detail: "a private key, not an encrypted backup"}
+	if !bytes.Contains(data, []byte("-----END ")) {
+		id.Truncated = true
+	}
+	return id
+}
+
+// firstLine returns data up to its first newline
+func firstLine(data []byte) []byte {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return line
+}
+
+// submatch returns the first capture of re in data, or ""
+func submatch(re *regexp.Regexp, data []byte) string {
+	if m := re.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package format
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// ShareVersion is written by files that hold one share of a split backup.
+// Older readers would ask for a passphrase that does not exist, so they
+// must reject such files as too new.
+const ShareVersion = "1.3"
+
+// ShareParams describes one share of a backup split with backup --split.
+// Every share file carries the whole ciphertext; the key it was encrypted
+// with is only recovered by combining Threshold shares of the same set.
+type ShareParams struct {
+	// SetID identifies the split; only shares with the same SetID combine
+	SetID string `json:"set_id"`
+
+	// Threshold is how many shares restore needs
+	Threshold int `json:"threshold"`
+
+	// Total is how many shares were made
+	Total int `json:"total"`
+
+	// Index is this share's number, from 1 to Total
+	Index int `json:"index"`
+
+	// KeyCheck is a hex MAC of the split data key, so restore can tell that
+	// shares combined to the wrong key; empty in shares written before it
+	// was added
+	KeyCheck string `json:"key_check,omitempty"`
+}
+
+// Validate checks that the share numbers are consistent
+func (p *ShareParams) Validate() error {
+	switch {
+	case p.SetID == "":
+		return fmt.Errorf("share has no set ID")
+	case p.Threshold < 2 || p.Total < p.Threshold:
+		return fmt.Errorf("invalid share threshold %d of %d", p.Threshold, p.Total)
+	case p.Index < 1 || p.Index > p.Total:
+		return fmt.Errorf("invalid share index %d of %d", p.Index, p.Total)
+	case p.KeyCheck != "" && !isHex(p.KeyCheck):
+		return fmt.Errorf("invalid share key check %q", p.KeyCheck)
+	}
+	return nil
+}
+
+// String describes the share, e.g. "share 1 of 3 (any 2 restore the key)"
+func (p *ShareParams) String() string {
+	return fmt.Sprintf("share %d of %d (any %d restore the key)", p.Index, p.Total, p.Threshold)
+}
+
+// isHex reports whether s is valid hexadecimal
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}